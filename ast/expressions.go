@@ -2,8 +2,11 @@ package ast
 
 import (
 	"bytes"
+	"fmt"
 	"gorilla/token"
+	"math/big"
 	"strconv"
+	"unicode"
 )
 
 type Literal interface {
@@ -24,6 +27,10 @@ func (in *IdentifierExpression) GetTokenType() token.TokenType {
 func (in *IdentifierExpression) GetTokenLiteral() string {
 	return in.Token.Literal
 }
+func (in *IdentifierExpression) GetSpan() token.Span {
+	return in.Token.Span
+}
+
 func (in *IdentifierExpression) GetName() string {
 	return in.GetTokenLiteral()
 }
@@ -49,6 +56,10 @@ func (boolLit *BoolLiteral) GetTokenLiteral() string {
 	return boolLit.Token.Literal
 }
 
+func (boolLit *BoolLiteral) GetSpan() token.Span {
+	return boolLit.Token.Span
+}
+
 func (boolLit *BoolLiteral) GetValue() bool {
 	return boolLit.Token.Type == token.TRUE
 }
@@ -57,18 +68,27 @@ func (boolLit *BoolLiteral) ToString() string {
 	return boolLit.GetTokenLiteral()
 }
 
+// IntegerLiteral holds its value as an int64, or as a big.Int if the
+// literal does not fit one.
 type IntegerLiteral struct {
-	token token.Token
-	value int64
+	token    token.Token
+	value    int64
+	bigValue *big.Int
 }
 
 func NewIntegerLiteral(token token.Token) (*IntegerLiteral, error) {
 	value, err := strconv.ParseInt(token.Literal, 0, 64)
+	if numErr, ok := err.(*strconv.NumError); ok && numErr.Err == strconv.ErrRange {
+		bigValue, ok := new(big.Int).SetString(token.Literal, 0)
+		if ok {
+			return &IntegerLiteral{token: token, bigValue: bigValue}, nil
+		}
+	}
 	if err != nil {
 		return nil, err
 		// return nil, fmt.Errorf("Could not parse integer literal: " + token.Literal)
 	}
-	return &IntegerLiteral{token, value}, nil
+	return &IntegerLiteral{token: token, value: value}, nil
 }
 
 func (intLit *IntegerLiteral) expressionNode() {}
@@ -81,14 +101,190 @@ func (intLit *IntegerLiteral) GetTokenLiteral() string {
 	return intLit.token.Literal
 }
 
+func (intLit *IntegerLiteral) GetSpan() token.Span {
+	return intLit.token.Span
+}
+
 func (intLit *IntegerLiteral) GetValue() int64 {
 	return intLit.value
 }
 
+// GetBigValue returns the value of a literal that does not fit an int64,
+// or nil.
+func (intLit *IntegerLiteral) GetBigValue() *big.Int {
+	return intLit.bigValue
+}
+
 func (intLit *IntegerLiteral) ToString() string {
 	return intLit.GetTokenLiteral()
 }
 
+type FloatLiteral struct {
+	token token.Token
+	value float64
+}
+
+func NewFloatLiteral(token token.Token) (*FloatLiteral, error) {
+	value, err := strconv.ParseFloat(token.Literal, 64)
+	if err != nil {
+		return nil, err
+	}
+	return &FloatLiteral{token, value}, nil
+}
+
+func (floatLit *FloatLiteral) expressionNode() {}
+
+func (floatLit *FloatLiteral) GetTokenType() token.TokenType {
+	return token.FLOAT
+}
+
+func (floatLit *FloatLiteral) GetTokenLiteral() string {
+	return floatLit.token.Literal
+}
+
+func (floatLit *FloatLiteral) GetSpan() token.Span {
+	return floatLit.token.Span
+}
+
+func (floatLit *FloatLiteral) GetValue() float64 {
+	return floatLit.value
+}
+
+func (floatLit *FloatLiteral) ToString() string {
+	return floatLit.GetTokenLiteral()
+}
+
+// StringLiteral holds the unescaped value of a string in its token literal.
+type StringLiteral struct {
+	Token token.Token
+}
+
+func (strLit *StringLiteral) expressionNode() {}
+
+func (strLit *StringLiteral) GetTokenType() token.TokenType {
+	return token.STRING
+}
+
+func (strLit *StringLiteral) GetTokenLiteral() string {
+	return strLit.Token.Literal
+}
+
+func (strLit *StringLiteral) GetSpan() token.Span {
+	return strLit.Token.Span
+}
+
+func (strLit *StringLiteral) GetValue() string {
+	return strLit.Token.Literal
+}
+
+func (strLit *StringLiteral) ToString() string {
+	return QuoteString(strLit.GetValue())
+}
+
+// QuoteString returns value as a Gorilla string literal, escaping what the
+// lexer would otherwise not read back verbatim.
+func QuoteString(value string) string {
+	var out bytes.Buffer
+	out.WriteByte('"')
+	for _, r := range value {
+		switch r {
+		case '"', '\\':
+			out.WriteByte('\\')
+			out.WriteRune(r)
+		case '\n':
+			out.WriteString("\\n")
+		case '\t':
+			out.WriteString("\\t")
+		case '\r':
+			out.WriteString("\\r")
+		default:
+			if unicode.IsPrint(r) {
+				out.WriteRune(r)
+			} else {
+				fmt.Fprintf(&out, "\\u{%X}", r)
+			}
+		}
+	}
+	out.WriteByte('"')
+	return out.String()
+}
+
+type ArrayLiteral struct {
+	Token    token.Token // the '[' token
+	Elements []ExpressionNode
+	Rbracket token.Pos
+}
+
+func (arrLit *ArrayLiteral) expressionNode() {}
+
+func (arrLit *ArrayLiteral) GetTokenType() token.TokenType {
+	return token.LBRACKET
+}
+
+func (arrLit *ArrayLiteral) GetTokenLiteral() string {
+	return "["
+}
+
+func (arrLit *ArrayLiteral) GetSpan() token.Span {
+	return token.Span{Start: arrLit.Token.Span.Start, End: arrLit.Rbracket + 1}
+}
+
+func (arrLit *ArrayLiteral) ToString() string {
+	var out bytes.Buffer
+	out.WriteString("[")
+	for i, element := range arrLit.Elements {
+		out.WriteString(element.ToString())
+		if i < len(arrLit.Elements)-1 {
+			out.WriteString(", ")
+		}
+	}
+	out.WriteString("]")
+	return out.String()
+}
+
+// HashLiteral is a `{key: value, ...}` literal. At the start of a statement
+// '{' opens a BlockStatement unless it is followed by '}' or by a single
+// token and ':'.
+type HashLiteral struct {
+	Token  token.Token // the '{' token
+	Pairs  []HashPair  // in source order
+	Rbrace token.Pos
+}
+
+type HashPair struct {
+	Key   ExpressionNode
+	Value ExpressionNode
+}
+
+func (hashLit *HashLiteral) expressionNode() {}
+
+func (hashLit *HashLiteral) GetTokenType() token.TokenType {
+	return token.LBRACE
+}
+
+func (hashLit *HashLiteral) GetTokenLiteral() string {
+	return "{"
+}
+
+func (hashLit *HashLiteral) GetSpan() token.Span {
+	return token.Span{Start: hashLit.Token.Span.Start, End: hashLit.Rbrace + 1}
+}
+
+func (hashLit *HashLiteral) ToString() string {
+	var out bytes.Buffer
+	out.WriteString("{")
+	for i, pair := range hashLit.Pairs {
+		out.WriteString(pair.Key.ToString())
+		out.WriteString(": ")
+		out.WriteString(pair.Value.ToString())
+		if i < len(hashLit.Pairs)-1 {
+			out.WriteString(", ")
+		}
+	}
+	out.WriteString("}")
+	return out.String()
+}
+
 // func NewIfElseExpression(
 // 	condition ExpressionNode,
 // 	ifBlock ExpressionNode,
@@ -98,6 +294,7 @@ func (intLit *IntegerLiteral) ToString() string {
 // }
 
 type FunctionLiteral struct {
+	Token     token.Token // the 'fn' token
 	Signiture []*IdentifierExpression
 	Body      *BlockStatement
 }
@@ -112,6 +309,13 @@ func (f *FunctionLiteral) GetTokenLiteral() string {
 	return "fn"
 }
 
+func (f *FunctionLiteral) GetSpan() token.Span {
+	if f.Body == nil {
+		return f.Token.Span
+	}
+	return token.Span{Start: f.Token.Span.Start, End: f.Body.GetSpan().End}
+}
+
 func (f *FunctionLiteral) ToString() string {
 	var out bytes.Buffer
 	out.WriteString("fn ")
@@ -136,24 +340,33 @@ func (f *FunctionLiteral) ToString() string {
 	return out.String()
 }
 
+// FunctionCall is `callee(args...)`. The callee is any expression that
+// evaluates to a function, eg. `add(1)`, `makeAdder(1)(2)` or
+// `fn(x) { return x; }(5)`. Token is the '(' of the call.
 type FunctionCall struct {
-	FunctionName IdentifierExpression
-	Arguments    []ExpressionNode
+	Token     token.Token
+	Function  ExpressionNode
+	Arguments []ExpressionNode
+	Rparen    token.Pos
 }
 
 func (f *FunctionCall) expressionNode() {}
 
 func (f *FunctionCall) GetTokenType() token.TokenType {
-	return token.IDENT
+	return f.Token.Type
 }
 
 func (f *FunctionCall) GetTokenLiteral() string {
-	return f.FunctionName.GetTokenLiteral()
+	return f.Token.Literal
+}
+
+func (f *FunctionCall) GetSpan() token.Span {
+	return token.Span{Start: f.Function.GetSpan().Start, End: f.Rparen + 1}
 }
 
 func (f *FunctionCall) ToString() string {
 	var out bytes.Buffer
-	out.WriteString(f.FunctionName.GetTokenLiteral())
+	out.WriteString(f.Function.ToString())
 	out.WriteString("(")
 	for i, arg := range f.Arguments {
 		out.WriteString(arg.ToString())
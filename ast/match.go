@@ -0,0 +1,264 @@
+package ast
+
+import (
+	"bytes"
+	"gorilla/token"
+)
+
+// Match evaluates the body of the first arm whose pattern matches Value and
+// whose guard, if any, holds:
+//
+//	match (value) { [x, _] if x > 0 => x, _ => 0 }
+type Match struct {
+	Token  token.Token // the 'match' token
+	Value  ExpressionNode
+	Arms   []*MatchArm
+	Rbrace token.Pos
+}
+
+func (match *Match) expressionNode() {}
+
+func (match *Match) GetTokenType() token.TokenType {
+	return token.MATCH
+}
+
+func (match *Match) GetTokenLiteral() string {
+	return "match"
+}
+
+func (match *Match) GetSpan() token.Span {
+	return token.Span{Start: match.Token.Span.Start, End: match.Rbrace + 1}
+}
+
+func (match *Match) ToString() string {
+	var out bytes.Buffer
+	out.WriteString("match (" + match.Value.ToString() + ") { ")
+	for i, arm := range match.Arms {
+		out.WriteString(arm.ToString())
+		if i < len(match.Arms)-1 {
+			out.WriteString(", ")
+		}
+	}
+	out.WriteString(" }")
+	return out.String()
+}
+
+// IsExhaustive reports whether the match has an arm matching any value: a
+// wildcard or a binding without a guard.
+func (match *Match) IsExhaustive() bool {
+	for _, arm := range match.Arms {
+		if arm.Guard != nil {
+			continue
+		}
+		switch arm.Pattern.(type) {
+		case *WildcardPattern, *BindingPattern:
+			return true
+		}
+	}
+	return false
+}
+
+// MatchArm is `pattern => body` or `pattern if guard => body`. The names
+// bound by the pattern are visible in the guard and the body.
+type MatchArm struct {
+	Pattern Pattern
+	Guard   ExpressionNode // nullable
+	Body    ExpressionNode
+}
+
+func (arm *MatchArm) GetTokenType() token.TokenType {
+	return token.ARROW
+}
+
+func (arm *MatchArm) GetTokenLiteral() string {
+	return "=>"
+}
+
+func (arm *MatchArm) GetSpan() token.Span {
+	return token.Span{Start: arm.Pattern.GetSpan().Start, End: arm.Body.GetSpan().End}
+}
+
+func (arm *MatchArm) ToString() string {
+	out := arm.Pattern.ToString()
+	if arm.Guard != nil {
+		out += " if " + arm.Guard.ToString()
+	}
+	return out + " => " + arm.Body.ToString()
+}
+
+// Pattern is the part of a match arm tested against the value.
+type Pattern interface {
+	Node
+	patternNode()
+}
+
+// WildcardPattern is '_', which matches anything and binds nothing.
+type WildcardPattern struct {
+	Token token.Token // the '_' token
+}
+
+func (wildcard *WildcardPattern) patternNode() {}
+
+func (wildcard *WildcardPattern) GetTokenType() token.TokenType {
+	return token.IDENT
+}
+
+func (wildcard *WildcardPattern) GetTokenLiteral() string {
+	return "_"
+}
+
+func (wildcard *WildcardPattern) GetSpan() token.Span {
+	return wildcard.Token.Span
+}
+
+func (wildcard *WildcardPattern) ToString() string {
+	return "_"
+}
+
+// LiteralPattern matches values equal to a number, string or bool literal.
+type LiteralPattern struct {
+	Value ExpressionNode
+}
+
+func (literal *LiteralPattern) patternNode() {}
+
+func (literal *LiteralPattern) GetTokenType() token.TokenType {
+	return literal.Value.GetTokenType()
+}
+
+func (literal *LiteralPattern) GetTokenLiteral() string {
+	return literal.Value.GetTokenLiteral()
+}
+
+func (literal *LiteralPattern) GetSpan() token.Span {
+	return literal.Value.GetSpan()
+}
+
+func (literal *LiteralPattern) ToString() string {
+	return literal.Value.ToString()
+}
+
+// BindingPattern matches anything and binds it to a name.
+type BindingPattern struct {
+	Identifier *IdentifierExpression
+}
+
+func (binding *BindingPattern) patternNode() {}
+
+func (binding *BindingPattern) GetTokenType() token.TokenType {
+	return token.IDENT
+}
+
+func (binding *BindingPattern) GetTokenLiteral() string {
+	return binding.Identifier.GetName()
+}
+
+func (binding *BindingPattern) GetSpan() token.Span {
+	return binding.Identifier.GetSpan()
+}
+
+func (binding *BindingPattern) ToString() string {
+	return binding.Identifier.GetName()
+}
+
+// ArrayPattern matches an array of the same length whose elements match
+// the element patterns.
+type ArrayPattern struct {
+	Token    token.Token // the '[' token
+	Elements []Pattern
+	Rbracket token.Pos
+}
+
+func (arrPattern *ArrayPattern) patternNode() {}
+
+func (arrPattern *ArrayPattern) GetTokenType() token.TokenType {
+	return token.LBRACKET
+}
+
+func (arrPattern *ArrayPattern) GetTokenLiteral() string {
+	return "["
+}
+
+func (arrPattern *ArrayPattern) GetSpan() token.Span {
+	return token.Span{Start: arrPattern.Token.Span.Start, End: arrPattern.Rbracket + 1}
+}
+
+func (arrPattern *ArrayPattern) ToString() string {
+	var out bytes.Buffer
+	out.WriteString("[")
+	for i, element := range arrPattern.Elements {
+		out.WriteString(element.ToString())
+		if i < len(arrPattern.Elements)-1 {
+			out.WriteString(", ")
+		}
+	}
+	out.WriteString("]")
+	return out.String()
+}
+
+// HashPattern matches a hash that has every key of the pattern, with a
+// value matching the pattern of that key. Other keys are ignored.
+type HashPattern struct {
+	Token  token.Token // the '{' token
+	Pairs  []HashPatternPair
+	Rbrace token.Pos
+}
+
+// HashPatternPair is `key: pattern`, where key is a literal.
+type HashPatternPair struct {
+	Key   ExpressionNode
+	Value Pattern
+}
+
+func (hashPattern *HashPattern) patternNode() {}
+
+func (hashPattern *HashPattern) GetTokenType() token.TokenType {
+	return token.LBRACE
+}
+
+func (hashPattern *HashPattern) GetTokenLiteral() string {
+	return "{"
+}
+
+func (hashPattern *HashPattern) GetSpan() token.Span {
+	return token.Span{Start: hashPattern.Token.Span.Start, End: hashPattern.Rbrace + 1}
+}
+
+func (hashPattern *HashPattern) ToString() string {
+	var out bytes.Buffer
+	out.WriteString("{")
+	for i, pair := range hashPattern.Pairs {
+		out.WriteString(pair.Key.ToString() + ": " + pair.Value.ToString())
+		if i < len(hashPattern.Pairs)-1 {
+			out.WriteString(", ")
+		}
+	}
+	out.WriteString("}")
+	return out.String()
+}
+
+// PatternBindings returns the names bound by pattern, in the order they
+// appear.
+func PatternBindings(pattern Pattern) []*IdentifierExpression {
+	switch pattern := pattern.(type) {
+	case *BindingPattern:
+		return []*IdentifierExpression{pattern.Identifier}
+
+	case *ArrayPattern:
+		var bindings []*IdentifierExpression
+		for _, element := range pattern.Elements {
+			bindings = append(bindings, PatternBindings(element)...)
+		}
+		return bindings
+
+	case *HashPattern:
+		var bindings []*IdentifierExpression
+		for _, pair := range pattern.Pairs {
+			bindings = append(bindings, PatternBindings(pair.Value)...)
+		}
+		return bindings
+
+	default:
+		return nil
+	}
+}
@@ -5,6 +5,7 @@ import "gorilla/token"
 type Node interface {
 	GetTokenLiteral() string
 	GetTokenType() token.TokenType
+	GetSpan() token.Span
 	ToString() string
 }
 
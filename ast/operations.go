@@ -27,6 +27,13 @@ func (prefixOp *Prefix) GetTokenLiteral() string {
 	return prefixOp.Operator.Literal
 }
 
+func (prefixOp *Prefix) GetSpan() token.Span {
+	return token.Span{
+		Start: prefixOp.Operator.Span.Start,
+		End:   prefixOp.Operand.GetSpan().End,
+	}
+}
+
 func (prefixOp *Prefix) GetOperatorType() token.TokenType {
 	return prefixOp.GetTokenType()
 }
@@ -61,6 +68,13 @@ func (inFix *Infix) GetTokenLiteral() string {
 	return inFix.Operator.Literal
 }
 
+func (inFix *Infix) GetSpan() token.Span {
+	return token.Span{
+		Start: inFix.Left.GetSpan().Start,
+		End:   inFix.Right.GetSpan().End,
+	}
+}
+
 func (inFix *Infix) GetOperatorType() token.TokenType {
 	return inFix.GetTokenType()
 }
@@ -83,6 +97,103 @@ func (inFix *Infix) ToString() string {
 	return out.String()
 }
 
+// Logical is `left && right` or `left || right`. It is not an Infix since
+// the right operand is only evaluated if the left one does not decide the
+// result.
+type Logical struct {
+	Operator token.Token
+	Left     ExpressionNode
+	Right    ExpressionNode
+}
+
+func (logical *Logical) expressionNode() {}
+
+func (logical *Logical) GetTokenType() token.TokenType {
+	return logical.Operator.Type
+}
+
+func (logical *Logical) GetTokenLiteral() string {
+	return logical.Operator.Literal
+}
+
+func (logical *Logical) GetSpan() token.Span {
+	return token.Span{
+		Start: logical.Left.GetSpan().Start,
+		End:   logical.Right.GetSpan().End,
+	}
+}
+
+func (logical *Logical) GetOperatorType() token.TokenType {
+	return logical.GetTokenType()
+}
+
+func (logical *Logical) GetOperands() []ExpressionNode {
+	return []ExpressionNode{logical.Left, logical.Right}
+}
+
+func (logical *Logical) ToString() string {
+	var out bytes.Buffer
+
+	out.WriteString("(")
+	out.WriteString(logical.Left.ToString())
+	out.WriteString(" ")
+	out.WriteString(logical.Operator.Literal)
+	out.WriteString(" ")
+	out.WriteString(logical.Right.ToString())
+	out.WriteString(")")
+
+	return out.String()
+}
+
+// Comparison is a chain of comparisons like `0 <= i < n`, which means
+// `0 <= i && i < n` except that i is evaluated once. Operators[i] compares
+// Operands[i] with Operands[i+1]. A single comparison is an Infix.
+type Comparison struct {
+	Operands  []ExpressionNode
+	Operators []token.Token
+}
+
+func (comparison *Comparison) expressionNode() {}
+
+func (comparison *Comparison) GetTokenType() token.TokenType {
+	return comparison.Operators[0].Type
+}
+
+func (comparison *Comparison) GetTokenLiteral() string {
+	return comparison.Operators[0].Literal
+}
+
+func (comparison *Comparison) GetSpan() token.Span {
+	return token.Span{
+		Start: comparison.Operands[0].GetSpan().Start,
+		End:   comparison.Operands[len(comparison.Operands)-1].GetSpan().End,
+	}
+}
+
+func (comparison *Comparison) GetOperatorType() token.TokenType {
+	return comparison.GetTokenType()
+}
+
+func (comparison *Comparison) GetOperands() []ExpressionNode {
+	return comparison.Operands
+}
+
+func (comparison *Comparison) ToString() string {
+	var out bytes.Buffer
+
+	out.WriteString("(")
+	out.WriteString(comparison.Operands[0].ToString())
+	for i, operator := range comparison.Operators {
+		out.WriteString(" ")
+		out.WriteString(operator.Literal)
+		out.WriteString(" ")
+		out.WriteString(comparison.Operands[i+1].ToString())
+	}
+	out.WriteString(")")
+
+	return out.String()
+}
+
 type Trinary struct {
 	// Operator token.Token
 	Left   ExpressionNode
@@ -100,6 +211,13 @@ func (trinary *Trinary) GetTokenLiteral() string {
 	return ""
 }
 
+func (trinary *Trinary) GetSpan() token.Span {
+	return token.Span{
+		Start: trinary.Left.GetSpan().Start,
+		End:   trinary.Right.GetSpan().End,
+	}
+}
+
 func (trinary *Trinary) GetOperatorType() token.TokenType {
 	return trinary.GetTokenType()
 }
@@ -121,3 +239,96 @@ func (trinary *Trinary) ToString() string {
 
 	return out.String()
 }
+
+// IndexExpression is the postfix operation Left[Index].
+type IndexExpression struct {
+	Left     ExpressionNode
+	Index    ExpressionNode
+	Rbracket token.Pos
+}
+
+func (indexOp *IndexExpression) expressionNode() {}
+
+func (indexOp *IndexExpression) GetTokenType() token.TokenType {
+	return token.LBRACKET
+}
+
+func (indexOp *IndexExpression) GetTokenLiteral() string {
+	return "["
+}
+
+func (indexOp *IndexExpression) GetSpan() token.Span {
+	return token.Span{Start: indexOp.Left.GetSpan().Start, End: indexOp.Rbracket + 1}
+}
+
+func (indexOp *IndexExpression) GetOperatorType() token.TokenType {
+	return indexOp.GetTokenType()
+}
+
+func (indexOp *IndexExpression) GetOperands() []ExpressionNode {
+	return []ExpressionNode{indexOp.Left, indexOp.Index}
+}
+
+func (indexOp *IndexExpression) ToString() string {
+	var out bytes.Buffer
+	out.WriteString("(")
+	out.WriteString(indexOp.Left.ToString())
+	out.WriteString("[")
+	out.WriteString(indexOp.Index.ToString())
+	out.WriteString("])")
+	return out.String()
+}
+
+// SliceExpression is the postfix operation Left[Start:End]. Start and End
+// are nullable and default to the bounds of Left.
+type SliceExpression struct {
+	Left     ExpressionNode
+	Start    ExpressionNode // nullable
+	End      ExpressionNode // nullable
+	Rbracket token.Pos
+}
+
+func (sliceOp *SliceExpression) expressionNode() {}
+
+func (sliceOp *SliceExpression) GetTokenType() token.TokenType {
+	return token.COLON
+}
+
+func (sliceOp *SliceExpression) GetTokenLiteral() string {
+	return ":"
+}
+
+func (sliceOp *SliceExpression) GetSpan() token.Span {
+	return token.Span{Start: sliceOp.Left.GetSpan().Start, End: sliceOp.Rbracket + 1}
+}
+
+func (sliceOp *SliceExpression) GetOperatorType() token.TokenType {
+	return sliceOp.GetTokenType()
+}
+
+func (sliceOp *SliceExpression) GetOperands() []ExpressionNode {
+	operands := []ExpressionNode{sliceOp.Left}
+	if sliceOp.Start != nil {
+		operands = append(operands, sliceOp.Start)
+	}
+	if sliceOp.End != nil {
+		operands = append(operands, sliceOp.End)
+	}
+	return operands
+}
+
+func (sliceOp *SliceExpression) ToString() string {
+	var out bytes.Buffer
+	out.WriteString("(")
+	out.WriteString(sliceOp.Left.ToString())
+	out.WriteString("[")
+	if sliceOp.Start != nil {
+		out.WriteString(sliceOp.Start.ToString())
+	}
+	out.WriteString(":")
+	if sliceOp.End != nil {
+		out.WriteString(sliceOp.End.ToString())
+	}
+	out.WriteString("])")
+	return out.String()
+}
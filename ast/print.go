@@ -0,0 +1,210 @@
+package ast
+
+import (
+	"fmt"
+	"gorilla/token"
+	"io"
+	"strings"
+)
+
+// field is a child of a node, with the role it plays in its parent.
+type field struct {
+	name string
+	node Node
+}
+
+// Fprint writes node to out as an indented tree, one node per line with
+// its position in file. file may be nil to leave out the positions.
+func Fprint(out io.Writer, file *token.File, node Node) {
+	printNode(out, file, "", node, 0)
+}
+
+func printNode(out io.Writer, file *token.File, name string, node Node, depth int) {
+	io.WriteString(out, strings.Repeat("  ", depth))
+	if name != "" {
+		io.WriteString(out, name+": ")
+	}
+	if node == nil {
+		io.WriteString(out, "nil\n")
+		return
+	}
+
+	label, fields := describe(node)
+	io.WriteString(out, label)
+	if file != nil {
+		io.WriteString(out, " "+file.Position(node.GetSpan().Start).String())
+	}
+	io.WriteString(out, "\n")
+
+	for _, field := range fields {
+		printNode(out, file, field.name, field.node, depth+1)
+	}
+}
+
+// describe returns the label of node and its children.
+func describe(node Node) (string, []field) {
+	switch node := node.(type) {
+	// statements
+	case *LetStatement:
+		return "LetStatement " + node.Identifier.GetName(), []field{{"value", node.Expression}}
+
+	case *ReturnStatement:
+		if node.ReturnValue == nil {
+			return "ReturnStatement", nil
+		}
+		return "ReturnStatement", []field{{"value", node.ReturnValue}}
+
+	case *ExpressionStatement:
+		return "ExpressionStatement", []field{{"", node.Expression}}
+
+	case *BlockStatement:
+		fields := make([]field, len(node.Statements))
+		for i, stmt := range node.Statements {
+			fields[i] = field{"", stmt}
+		}
+		return "BlockStatement", fields
+
+	case *IfStatement:
+		fields := []field{{"condition", node.Condition}, {"then", node.Statement}}
+		if node.Else != nil {
+			fields = append(fields, field{"else", node.Else})
+		}
+		return "IfStatement", fields
+
+	case *ElseStatement:
+		return "ElseStatement", []field{{"", node.Statement}}
+
+	case *WhileStatement:
+		return "WhileStatement", []field{{"condition", node.Condition}, {"body", node.Body}}
+
+	case *ForStatement:
+		return "ForStatement " + node.Variable.GetName(), []field{{"iterable", node.Iterable}, {"body", node.Body}}
+
+	case *Assignment:
+		return "Assignment " + node.Operator.Literal, []field{{"target", node.Target}, {"value", node.Value}}
+
+	case *BreakStatement:
+		return "BreakStatement", nil
+
+	case *ContinueStatement:
+		return "ContinueStatement", nil
+
+	// expressions
+	case *IdentifierExpression:
+		return "Identifier " + node.GetName(), nil
+
+	case *BoolLiteral, *IntegerLiteral, *FloatLiteral:
+		return fmt.Sprintf("%s %s", typeName(node), node.GetTokenLiteral()), nil
+
+	case *StringLiteral:
+		return "StringLiteral " + QuoteString(node.GetValue()), nil
+
+	case *ArrayLiteral:
+		fields := make([]field, len(node.Elements))
+		for i, element := range node.Elements {
+			fields[i] = field{"", element}
+		}
+		return "ArrayLiteral", fields
+
+	case *HashLiteral:
+		fields := make([]field, 0, 2*len(node.Pairs))
+		for _, pair := range node.Pairs {
+			fields = append(fields, field{"key", pair.Key}, field{"value", pair.Value})
+		}
+		return "HashLiteral", fields
+
+	case *FunctionLiteral:
+		params := make([]string, len(node.Signiture))
+		for i, param := range node.Signiture {
+			params[i] = param.GetName()
+		}
+		return "FunctionLiteral (" + strings.Join(params, ", ") + ")", []field{{"body", node.Body}}
+
+	case *FunctionCall:
+		fields := []field{{"function", node.Function}}
+		for _, arg := range node.Arguments {
+			fields = append(fields, field{"arg", arg})
+		}
+		return "FunctionCall", fields
+
+	case *Prefix:
+		return "Prefix " + string(node.GetOperatorType()), []field{{"", node.Operand}}
+
+	case *Infix:
+		return "Infix " + string(node.GetOperatorType()), []field{{"", node.Left}, {"", node.Right}}
+
+	case *Logical:
+		return "Logical " + string(node.GetOperatorType()), []field{{"", node.Left}, {"", node.Right}}
+
+	case *Comparison:
+		operators := make([]string, len(node.Operators))
+		for i, operator := range node.Operators {
+			operators[i] = operator.Literal
+		}
+		fields := make([]field, len(node.Operands))
+		for i, operand := range node.Operands {
+			fields[i] = field{"", operand}
+		}
+		return "Comparison " + strings.Join(operators, " "), fields
+
+	case *Trinary:
+		return "Trinary", []field{{"then", node.Left}, {"condition", node.Middle}, {"else", node.Right}}
+
+	case *Match:
+		fields := []field{{"value", node.Value}}
+		for _, arm := range node.Arms {
+			fields = append(fields, field{"arm", arm})
+		}
+		return "Match", fields
+
+	case *MatchArm:
+		fields := []field{{"pattern", node.Pattern}}
+		if node.Guard != nil {
+			fields = append(fields, field{"guard", node.Guard})
+		}
+		return "MatchArm", append(fields, field{"body", node.Body})
+
+	case *WildcardPattern:
+		return "WildcardPattern", nil
+
+	case *LiteralPattern:
+		return "LiteralPattern", []field{{"", node.Value}}
+
+	case *BindingPattern:
+		return "BindingPattern " + node.Identifier.GetName(), nil
+
+	case *ArrayPattern:
+		fields := make([]field, len(node.Elements))
+		for i, element := range node.Elements {
+			fields[i] = field{"", element}
+		}
+		return "ArrayPattern", fields
+
+	case *HashPattern:
+		fields := make([]field, 0, 2*len(node.Pairs))
+		for _, pair := range node.Pairs {
+			fields = append(fields, field{"key", pair.Key}, field{"value", pair.Value})
+		}
+		return "HashPattern", fields
+
+	case *IndexExpression:
+		return "IndexExpression", []field{{"", node.Left}, {"index", node.Index}}
+
+	case *SliceExpression:
+		fields := []field{{"", node.Left}}
+		if node.Start != nil {
+			fields = append(fields, field{"start", node.Start})
+		}
+		if node.End != nil {
+			fields = append(fields, field{"end", node.End})
+		}
+		return "SliceExpression", fields
+
+	default:
+		return typeName(node) + " " + node.ToString(), nil
+	}
+}
+
+func typeName(node Node) string {
+	return strings.TrimPrefix(fmt.Sprintf("%T", node), "*ast.")
+}
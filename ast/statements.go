@@ -6,7 +6,7 @@ import (
 )
 
 type LetStatement struct {
-	// tok        token.Token
+	Token      token.Token // the 'let' token
 	Identifier *IdentifierExpression
 	Expression ExpressionNode
 }
@@ -31,8 +31,16 @@ func (letStmt *LetStatement) GetTokenLiteral() string {
 	return "let"
 }
 
+func (letStmt *LetStatement) GetSpan() token.Span {
+	return token.Span{
+		Start: letStmt.Token.Span.Start,
+		End:   letStmt.Expression.GetSpan().End,
+	}
+}
+
 type ReturnStatement struct {
-	ReturnValue ExpressionNode
+	Token       token.Token    // the 'return' token
+	ReturnValue ExpressionNode // nullable
 }
 
 func (returnStmt *ReturnStatement) statementNode() {}
@@ -45,7 +53,20 @@ func (returnStmt *ReturnStatement) GetTokenLiteral() string {
 	return "return"
 }
 
+func (returnStmt *ReturnStatement) GetSpan() token.Span {
+	if returnStmt.ReturnValue == nil {
+		return returnStmt.Token.Span
+	}
+	return token.Span{
+		Start: returnStmt.Token.Span.Start,
+		End:   returnStmt.ReturnValue.GetSpan().End,
+	}
+}
+
 func (returnStmt *ReturnStatement) ToString() string {
+	if returnStmt.ReturnValue == nil {
+		return "return;"
+	}
 	var out bytes.Buffer
 	out.WriteString("return ")
 	out.WriteString(returnStmt.ReturnValue.ToString())
@@ -53,8 +74,34 @@ func (returnStmt *ReturnStatement) ToString() string {
 	return out.String()
 }
 
+// ExpressionStatement is an expression evaluated for its value or side
+// effects, e.g. `print(x);` or `x + 1;`.
+type ExpressionStatement struct {
+	Expression ExpressionNode
+}
+
+func (exprStmt *ExpressionStatement) statementNode() {}
+
+func (exprStmt *ExpressionStatement) GetTokenType() token.TokenType {
+	return exprStmt.Expression.GetTokenType()
+}
+
+func (exprStmt *ExpressionStatement) GetTokenLiteral() string {
+	return exprStmt.Expression.GetTokenLiteral()
+}
+
+func (exprStmt *ExpressionStatement) GetSpan() token.Span {
+	return exprStmt.Expression.GetSpan()
+}
+
+func (exprStmt *ExpressionStatement) ToString() string {
+	return exprStmt.Expression.ToString() + ";"
+}
+
 type BlockStatement struct {
+	Token      token.Token // the '{' token
 	Statements []StatementNode
+	Rbrace     token.Pos
 }
 
 func (blockStmt *BlockStatement) statementNode() {}
@@ -67,6 +114,10 @@ func (blockStmt *BlockStatement) GetTokenLiteral() string {
 	return "{"
 }
 
+func (blockStmt *BlockStatement) GetSpan() token.Span {
+	return token.Span{Start: blockStmt.Token.Span.Start, End: blockStmt.Rbrace + 1}
+}
+
 func (blockStmt *BlockStatement) AppendStatement(statement StatementNode) {
 	blockStmt.Statements = append(blockStmt.Statements, statement)
 }
@@ -85,18 +136,19 @@ func (blockStmt *BlockStatement) ToString() string {
 	return out.String()
 }
 
-func NewIfStatement(condition ExpressionNode, block *BlockStatement) *IfStatement {
-	return &IfStatement{condition, block, nil}
+func NewIfStatement(ifToken token.Token, condition ExpressionNode, block *BlockStatement) *IfStatement {
+	return &IfStatement{ifToken, condition, block, nil}
 }
 
-func NewIfElseStatement(condition ExpressionNode, block *BlockStatement, elseBlock StatementNode) *IfStatement {
-	return &IfStatement{condition, block, &ElseStatement{elseBlock}}
+func NewIfElseStatement(
+	ifToken token.Token, condition ExpressionNode, block *BlockStatement,
+	elseToken token.Token, elseBlock StatementNode,
+) *IfStatement {
+	return &IfStatement{ifToken, condition, block, &ElseStatement{elseToken, elseBlock}}
 }
 
 type IfStatement struct {
-	// IfToken token.Token
-	// Conditions []ExpressionNode
-	// Statements []StatementNode
+	Token     token.Token // the 'if' token
 	Condition ExpressionNode
 	Statement *BlockStatement
 	Else      *ElseStatement // nullable
@@ -112,6 +164,14 @@ func (ifStmt *IfStatement) GetTokenLiteral() string {
 	return "if"
 }
 
+func (ifStmt *IfStatement) GetSpan() token.Span {
+	end := ifStmt.Statement.GetSpan().End
+	if ifStmt.Else != nil {
+		end = ifStmt.Else.GetSpan().End
+	}
+	return token.Span{Start: ifStmt.Token.Span.Start, End: end}
+}
+
 func (ifStmt *IfStatement) ToString() string {
 	var out bytes.Buffer
 	out.WriteString("if " + ifStmt.Condition.ToString() + " ")
@@ -127,7 +187,7 @@ func (ifStmt *IfStatement) ToString() string {
 }
 
 type ElseStatement struct {
-	// ElseToken token.Token
+	Token     token.Token   // the 'else' token
 	Statement StatementNode // IfStatement or BlockStatement
 }
 
@@ -141,6 +201,13 @@ func (elseStmt *ElseStatement) GetTokenLiteral() string {
 	return "else"
 }
 
+func (elseStmt *ElseStatement) GetSpan() token.Span {
+	return token.Span{
+		Start: elseStmt.Token.Span.Start,
+		End:   elseStmt.Statement.GetSpan().End,
+	}
+}
+
 func (elseStmt *ElseStatement) ToString() string {
 	var out bytes.Buffer
 	out.WriteString(" else ")
@@ -156,3 +223,151 @@ func (elseStmt *ElseStatement) ToString() string {
 
 	return out.String()
 }
+
+// WhileStatement runs Body for as long as Condition is truthy.
+type WhileStatement struct {
+	Token     token.Token // the 'while' token
+	Condition ExpressionNode
+	Body      *BlockStatement
+}
+
+func (whileStmt *WhileStatement) statementNode() {}
+
+func (whileStmt *WhileStatement) GetTokenType() token.TokenType {
+	return token.WHILE
+}
+
+func (whileStmt *WhileStatement) GetTokenLiteral() string {
+	return "while"
+}
+
+func (whileStmt *WhileStatement) GetSpan() token.Span {
+	return token.Span{Start: whileStmt.Token.Span.Start, End: whileStmt.Body.GetSpan().End}
+}
+
+func (whileStmt *WhileStatement) ToString() string {
+	return "while " + whileStmt.Condition.ToString() + " " + whileStmt.Body.ToString()
+}
+
+// ForStatement runs Body once for each element of Iterable, with Variable
+// bound to the element.
+type ForStatement struct {
+	Token    token.Token // the 'for' token
+	Variable *IdentifierExpression
+	Iterable ExpressionNode
+	Body     *BlockStatement
+}
+
+func (forStmt *ForStatement) statementNode() {}
+
+func (forStmt *ForStatement) GetTokenType() token.TokenType {
+	return token.FOR
+}
+
+func (forStmt *ForStatement) GetTokenLiteral() string {
+	return "for"
+}
+
+func (forStmt *ForStatement) GetSpan() token.Span {
+	return token.Span{Start: forStmt.Token.Span.Start, End: forStmt.Body.GetSpan().End}
+}
+
+func (forStmt *ForStatement) ToString() string {
+	var out bytes.Buffer
+	out.WriteString("for (")
+	out.WriteString(forStmt.Variable.GetName())
+	out.WriteString(" in ")
+	out.WriteString(forStmt.Iterable.ToString())
+	out.WriteString(") ")
+	out.WriteString(forStmt.Body.ToString())
+	return out.String()
+}
+
+// BreakStatement leaves the innermost enclosing loop.
+type BreakStatement struct {
+	Token token.Token // the 'break' token
+}
+
+func (breakStmt *BreakStatement) statementNode() {}
+
+func (breakStmt *BreakStatement) GetTokenType() token.TokenType {
+	return token.BREAK
+}
+
+func (breakStmt *BreakStatement) GetTokenLiteral() string {
+	return "break"
+}
+
+func (breakStmt *BreakStatement) GetSpan() token.Span {
+	return breakStmt.Token.Span
+}
+
+func (breakStmt *BreakStatement) ToString() string {
+	return "break;"
+}
+
+// ContinueStatement skips to the next iteration of the innermost enclosing
+// loop.
+type ContinueStatement struct {
+	Token token.Token // the 'continue' token
+}
+
+func (continueStmt *ContinueStatement) statementNode() {}
+
+func (continueStmt *ContinueStatement) GetTokenType() token.TokenType {
+	return token.CONTINUE
+}
+
+func (continueStmt *ContinueStatement) GetTokenLiteral() string {
+	return "continue"
+}
+
+func (continueStmt *ContinueStatement) GetSpan() token.Span {
+	return continueStmt.Token.Span
+}
+
+func (continueStmt *ContinueStatement) ToString() string {
+	return "continue;"
+}
+
+// Assignment rebinds an existing name or replaces an element of an array or
+// hash: `x = 1;`, `x += 1;` or `xs[0] = 1;`. Target is an
+// IdentifierExpression or an IndexExpression.
+type Assignment struct {
+	Target   ExpressionNode
+	Operator token.Token // '=' or a compound operator such as '+='
+	Value    ExpressionNode
+}
+
+func (assign *Assignment) statementNode() {}
+
+func (assign *Assignment) GetTokenType() token.TokenType {
+	return assign.Operator.Type
+}
+
+func (assign *Assignment) GetTokenLiteral() string {
+	return assign.Operator.Literal
+}
+
+// IsCompound reports whether the operator combines the old value with the
+// new one, as in `x += 1`.
+func (assign *Assignment) IsCompound() bool {
+	return assign.Operator.Type != token.ASSIGN
+}
+
+// GetInfixOperator returns the infix operator applied by a compound
+// assignment, e.g. '+' for '+='.
+func (assign *Assignment) GetInfixOperator() token.TokenType {
+	return token.AssignOperators[assign.Operator.Type]
+}
+
+func (assign *Assignment) GetSpan() token.Span {
+	return token.Span{
+		Start: assign.Target.GetSpan().Start,
+		End:   assign.Value.GetSpan().End,
+	}
+}
+
+func (assign *Assignment) ToString() string {
+	return assign.Target.ToString() + " " + assign.Operator.Literal + " " + assign.Value.ToString() + ";"
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"gorilla/ast"
+	"gorilla/compiler"
+	"gorilla/eval"
+	"gorilla/lexer"
+	"gorilla/object"
+	"gorilla/parser"
+	"gorilla/repl"
+	"gorilla/token"
+	"gorilla/vm"
+	"io"
+	"os"
+	"runtime"
+)
+
+// Exit codes, so scripts can be used in pipelines.
+const (
+	ExitOK           = 0
+	ExitRuntimeError = 1 // the program failed while running
+	ExitSyntaxError  = 2 // the program could not be lexed or parsed
+	ExitUsage        = 64
+	ExitNoInput      = 66 // the source file could not be read
+)
+
+const usage = `Usage:
+	gorilla [repl]                    start the interactive interpreter
+	gorilla run [-vm] [file] [args]   run a program; without file, or with -, read stdin
+	gorilla tokens [file]             print the tokens of a program
+	gorilla ast [file]                print the parsed program
+	gorilla check [file]              report syntax errors without running
+
+The arguments after the file are available to the program as the array of
+strings args, where args[0] is the file.
+`
+
+type command func(args []string, stdin io.Reader, stdout, stderr io.Writer) int
+
+var commands = map[string]command{
+	"repl":   runRepl,
+	"run":    runFile,
+	"tokens": runTokens,
+	"ast":    runAst,
+	"check":  runCheck,
+}
+
+// run executes the command line args (without the program name) and
+// returns the exit code.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		return runRepl(args, stdin, stdout, stderr)
+	}
+
+	switch args[0] {
+	case "help", "-h", "-help", "--help":
+		io.WriteString(stdout, usage)
+		return ExitOK
+	}
+
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(stderr, "gorilla: unknown command %q\n\n%s", args[0], usage)
+		return ExitUsage
+	}
+	return cmd(args[1:], stdin, stdout, stderr)
+}
+
+func runRepl(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) > 0 {
+		fmt.Fprintf(stderr, "gorilla repl: unexpected argument %q\n", args[0])
+		return ExitUsage
+	}
+
+	fmt.Fprintf(stdout,
+		"Gorilla Programming Lanugage version 0.1 [%s]\n",
+		runtime.GOOS,
+	)
+	repl.Start(stdin, stdout)
+	return ExitOK
+}
+
+func runFile(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("gorilla run", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	useVM := flags.Bool("vm", false, "run on the bytecode vm instead of the evaluator")
+	if err := flags.Parse(args); err != nil {
+		return ExitUsage
+	}
+
+	src, code := readSource(flags.Args(), stdin, stderr)
+	if code != ExitOK {
+		return code
+	}
+
+	prog, ok := parseSource(src, stderr)
+	if !ok {
+		return ExitSyntaxError
+	}
+
+	scriptArgs := &object.Array{}
+	for _, arg := range flags.Args() {
+		scriptArgs.Elements = append(scriptArgs.Elements, &object.String{Value: arg})
+	}
+	if len(scriptArgs.Elements) == 0 {
+		scriptArgs.Elements = append(scriptArgs.Elements, &object.String{Value: src.name})
+	}
+
+	var result object.Object
+	if *useVM {
+		symbolTable := compiler.NewSymbolTable()
+		argsSymbol := symbolTable.Define("args")
+
+		comp := compiler.NewWithState(symbolTable, []object.Object{})
+		if err := comp.Compile(prog); err != nil {
+			fmt.Fprintf(stderr, "%s: %s\n", src.name, err)
+			return ExitSyntaxError
+		}
+
+		globals := make([]object.Object, argsSymbol.Index+1)
+		globals[argsSymbol.Index] = scriptArgs
+		result = vm.NewWithGlobals(comp.Bytecode(), globals).Run()
+	} else {
+		env := object.NewEnvironment()
+		env.Set("args", scriptArgs)
+		result = eval.EvalProgram(prog, env)
+	}
+
+	if errObj, ok := result.(*object.Error); ok {
+		position := src.file.Position(errObj.Span.Start)
+		fmt.Fprintf(stderr, "%s: %s\n", position, errObj.Inspect())
+		return ExitRuntimeError
+	}
+	return ExitOK
+}
+
+func runTokens(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	src, code := readSource(args, stdin, stderr)
+	if code != ExitOK {
+		return code
+	}
+
+	code = ExitOK
+	lx := lexer.NewFileLexer(src.file, src.text)
+	for {
+		tok := lx.GetNextToken()
+		position := src.file.Position(tok.Span.Start)
+		fmt.Fprintf(stdout, "%d:%d\t%s\t%q\n", position.Line, position.Column, tok.Type, tok.Literal)
+
+		if tok.Type == token.ILLEGAL {
+			code = ExitSyntaxError
+		}
+		if tok.Type == token.EOF {
+			for _, err := range lx.Errors {
+				fmt.Fprintln(stderr, err)
+			}
+			return code
+		}
+	}
+}
+
+func runAst(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	src, code := readSource(args, stdin, stderr)
+	if code != ExitOK {
+		return code
+	}
+
+	prog, ok := parseSource(src, stderr)
+	if !ok {
+		return ExitSyntaxError
+	}
+	for _, stmt := range prog.Statements {
+		io.WriteString(stdout, stmt.ToString()+"\n")
+	}
+	return ExitOK
+}
+
+func runCheck(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	src, code := readSource(args, stdin, stderr)
+	if code != ExitOK {
+		return code
+	}
+
+	if _, ok := parseSource(src, stderr); !ok {
+		return ExitSyntaxError
+	}
+	return ExitOK
+}
+
+// source is a program read from a file or stdin.
+type source struct {
+	name string
+	text string
+	file *token.File
+}
+
+// readSource reads the file named by args[0], or stdin if there is none or
+// it is "-".
+func readSource(args []string, stdin io.Reader, stderr io.Writer) (*source, int) {
+	name := "-"
+	if len(args) > 0 {
+		name = args[0]
+	}
+
+	var data []byte
+	var err error
+	if name == "-" {
+		name = "<stdin>"
+		data, err = io.ReadAll(stdin)
+	} else {
+		data, err = os.ReadFile(name)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "gorilla: %s\n", err)
+		return nil, ExitNoInput
+	}
+
+	text := string(data)
+	file := token.NewFileSet().AddFile(name, len(text))
+	return &source{name, text, file}, ExitOK
+}
+
+// parseSource parses src, reporting every warning and syntax error to
+// stderr.
+func parseSource(src *source, stderr io.Writer) (*ast.Program, bool) {
+	p := parser.NewParser(lexer.NewFileLexer(src.file, src.text))
+	prog, ok := p.ParseProgram()
+	for _, warning := range p.Warnings {
+		fmt.Fprintln(stderr, "warning: "+warning.Error())
+		if warning.Hint != "" {
+			fmt.Fprintf(stderr, "\thint: %s\n", warning.Hint)
+		}
+	}
+	if !ok {
+		for _, err := range p.Errors {
+			fmt.Fprintln(stderr, err.Error())
+			if err.Hint != "" {
+				fmt.Fprintf(stderr, "\thint: %s\n", err.Hint)
+			}
+		}
+	}
+	return prog, ok
+}
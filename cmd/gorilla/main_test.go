@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunExitCodes(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	ok := write("ok.gor", "let add = fn(a, b) { return a + b; };\nadd(1, 2);\n")
+	syntaxError := write("syntax.gor", "let x = 5;\nlet y 10;\n")
+	runtimeError := write("runtime.gor", "let f = fn(x) {\n\treturn x / 0;\n};\nf(1);\n")
+	usesArgs := write("args.gor", `if (len(args) != 3 || args[2] != "b") { 1 / 0; }`)
+
+	tests := []struct {
+		args   []string
+		stdin  string
+		code   int
+		stderr string
+		stdout string
+	}{
+		{[]string{"run", ok}, "", ExitOK, "", ""},
+		{[]string{"run", "-vm", ok}, "", ExitOK, "", ""},
+		{[]string{"run", syntaxError}, "", ExitSyntaxError, syntaxError + ":2:7: ", ""},
+		{[]string{"run", runtimeError}, "", ExitRuntimeError, runtimeError + ":2:9: Runtime error: division by zero", ""},
+		{[]string{"run", "-vm", runtimeError}, "", ExitRuntimeError, runtimeError + ":2:9: Runtime error: division by zero", ""},
+		{[]string{"run", usesArgs, "a", "b"}, "", ExitOK, "", ""},
+		{[]string{"run", "-vm", usesArgs, "a", "b"}, "", ExitOK, "", ""},
+		{[]string{"run", usesArgs, "a"}, "", ExitRuntimeError, "", ""},
+		{[]string{"run"}, "1 +;", ExitSyntaxError, "<stdin>:1:4: ", ""},
+		{[]string{"run", "-"}, "foo;", ExitRuntimeError, "<stdin>:1:1: Runtime error: identifier not found: foo", ""},
+		{[]string{"run", filepath.Join(dir, "missing.gor")}, "", ExitNoInput, "gorilla: ", ""},
+		{[]string{"check", ok}, "", ExitOK, "", ""},
+		{[]string{"check", syntaxError}, "", ExitSyntaxError, syntaxError + ":2:7: ", ""},
+		{[]string{"check", "-"}, "foo(;", ExitSyntaxError, "<stdin>:1:5: ", ""},
+		{[]string{"check", "-"}, "match (1) { 1 => 2 };", ExitOK, "warning: <stdin>:1:1: match has no wildcard arm", ""},
+		{[]string{"ast"}, "let x = 1 + 2 * 3;", ExitOK, "", "let x = (1 + (2 * 3));\n"},
+		{[]string{"tokens"}, "x;", ExitOK, "", "1:1\tIDENT\t\"x\"\n1:2\t;\t\";\"\n1:3\tEOF\t\"\"\n"},
+		{[]string{"tokens"}, "@", ExitSyntaxError, "", ""},
+		{[]string{"tokens"}, "x /* open", ExitSyntaxError, "<stdin>:1:3: Unterminated block comment", ""},
+		{[]string{"run"}, "// comment\nlet x = /* 1 */ 2;", ExitOK, "", ""},
+		{[]string{"frobnicate"}, "", ExitUsage, `gorilla: unknown command "frobnicate"`, ""},
+		{[]string{"run", "-nope"}, "", ExitUsage, "flag provided but not defined", ""},
+		{[]string{"help"}, "", ExitOK, "", "Usage:"},
+	}
+
+	for _, tt := range tests {
+		var stdout, stderr bytes.Buffer
+		code := run(tt.args, strings.NewReader(tt.stdin), &stdout, &stderr)
+
+		if code != tt.code {
+			t.Errorf("%v: expected exit code %d. got %d (stderr %q)", tt.args, tt.code, code, stderr.String())
+		}
+		if !strings.Contains(stderr.String(), tt.stderr) || (tt.stderr == "" && tt.code == ExitOK && stderr.Len() > 0) {
+			t.Errorf("%v: expected stderr to contain %q. got %q", tt.args, tt.stderr, stderr.String())
+		}
+		if !strings.HasPrefix(stdout.String(), tt.stdout) {
+			t.Errorf("%v: expected stdout to start with %q. got %q", tt.args, tt.stdout, stdout.String())
+		}
+	}
+}
+
+func TestRunRepl(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := run([]string{}, strings.NewReader("1 + 1;\n"), &stdout, &stderr)
+
+	if code != ExitOK {
+		t.Errorf("Expected exit code %d. got %d", ExitOK, code)
+	}
+	if !strings.Contains(stdout.String(), "Gorilla") || !strings.Contains(stdout.String(), "2\n") {
+		t.Errorf("Unexpected REPL output %q", stdout.String())
+	}
+}
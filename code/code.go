@@ -0,0 +1,289 @@
+package code
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"gorilla/token"
+	"sort"
+)
+
+// Instructions is a flat sequence of encoded instructions: a one byte
+// Opcode followed by its operands, big endian.
+type Instructions []byte
+
+type Opcode byte
+
+const (
+	OpConstant Opcode = iota // push constants[u16]
+	OpPop                    // discard the top of the stack
+	OpDup                    // push a copy of the top of the stack
+	OpDupTwo                 // push a copy of the top two values of the stack
+	OpRotThree               // move the top of the stack below the two values under it
+
+	OpNone
+	OpTrue
+	OpFalse
+
+	// binary operators, pop right then left and push the result
+	OpAdd
+	OpSub
+	OpMul
+	OpDiv
+	OpMod
+	OpPow
+	OpBitAnd
+	OpBitOr
+	OpBitXor
+	OpShiftLeft
+	OpShiftRight
+	OpEqual
+	OpNotEqual
+	OpLess
+	OpGreater
+	OpLessEqual
+	OpGreaterEqual
+
+	// prefix operators
+	OpMinus
+	OpBang
+	OpBitNot
+
+	OpJump          // jump to u16
+	OpJumpNotTruthy // pop the condition, jump to u16 if it is falsy
+	OpIter          // pop an iterable and push an iterator over its elements
+	OpIterNext      // push the next element of the iterator on top, or jump to u16 when there is none
+	OpMatch         // pop a value and test the pattern at constants[u16]; push its bindings and True, or False
+	OpNoMatch       // pop the value that no match arm matched and fail
+
+	OpGetGlobal // push globals[u16]
+	OpSetGlobal // pop into globals[u16]
+	OpGetLocal  // push locals[u8]
+	OpSetLocal  // pop into locals[u8]
+	OpGetFree   // push the captured variable u8 of the current closure
+
+	// give the slots from the first operand to the second, inclusive, new
+	// storage; the closures that captured them keep the old
+	OpFreshGlobals // globals[u16] to globals[u16]
+	OpFreshLocals  // locals[u8] to locals[u8]
+
+	// pop into a slot that must already be set
+	OpAssignGlobal // globals[u16]
+	OpAssignLocal  // locals[u8]
+	OpAssignFree   // the captured variable u8
+
+	OpArray    // pop u16 elements into an array
+	OpHashKey  // fail unless the top of the stack can be a hash key
+	OpHash     // pop u16 key, value pairs into a hash
+	OpIndex    // pop index and left, push left[index]
+	OpSetIndex // pop value, index and left, set left[index] to value
+	OpSlice    // pop end, start and left; the u8 flags tell which bounds exist
+
+	OpCall        // call the function below its u8 arguments
+	OpReturnValue // return the top of the stack
+	OpReturn      // return None
+	OpClosure     // push a closure over the function at constants[u16]
+)
+
+// Slice flags tell which of the optional bounds of OpSlice were pushed.
+const (
+	SliceStart = 1 << iota
+	SliceEnd
+)
+
+// Definition names an Opcode and gives the width in bytes of each operand.
+type Definition struct {
+	Name          string
+	OperandWidths []int
+}
+
+var definitions = map[Opcode]*Definition{
+	OpConstant: {"OpConstant", []int{2}},
+	OpPop:      {"OpPop", []int{}},
+	OpDup:      {"OpDup", []int{}},
+	OpDupTwo:   {"OpDupTwo", []int{}},
+	OpRotThree: {"OpRotThree", []int{}},
+
+	OpNone:  {"OpNone", []int{}},
+	OpTrue:  {"OpTrue", []int{}},
+	OpFalse: {"OpFalse", []int{}},
+
+	OpAdd:          {"OpAdd", []int{}},
+	OpSub:          {"OpSub", []int{}},
+	OpMul:          {"OpMul", []int{}},
+	OpDiv:          {"OpDiv", []int{}},
+	OpMod:          {"OpMod", []int{}},
+	OpPow:          {"OpPow", []int{}},
+	OpBitAnd:       {"OpBitAnd", []int{}},
+	OpBitOr:        {"OpBitOr", []int{}},
+	OpBitXor:       {"OpBitXor", []int{}},
+	OpShiftLeft:    {"OpShiftLeft", []int{}},
+	OpShiftRight:   {"OpShiftRight", []int{}},
+	OpEqual:        {"OpEqual", []int{}},
+	OpNotEqual:     {"OpNotEqual", []int{}},
+	OpLess:         {"OpLess", []int{}},
+	OpGreater:      {"OpGreater", []int{}},
+	OpLessEqual:    {"OpLessEqual", []int{}},
+	OpGreaterEqual: {"OpGreaterEqual", []int{}},
+
+	OpMinus:  {"OpMinus", []int{}},
+	OpBang:   {"OpBang", []int{}},
+	OpBitNot: {"OpBitNot", []int{}},
+
+	OpJump:          {"OpJump", []int{2}},
+	OpJumpNotTruthy: {"OpJumpNotTruthy", []int{2}},
+	OpIter:          {"OpIter", []int{}},
+	OpIterNext:      {"OpIterNext", []int{2}},
+	OpMatch:         {"OpMatch", []int{2}},
+	OpNoMatch:       {"OpNoMatch", []int{}},
+
+	OpGetGlobal: {"OpGetGlobal", []int{2}},
+	OpSetGlobal: {"OpSetGlobal", []int{2}},
+	OpGetLocal:  {"OpGetLocal", []int{1}},
+	OpSetLocal:  {"OpSetLocal", []int{1}},
+	OpGetFree:   {"OpGetFree", []int{1}},
+
+	OpFreshGlobals: {"OpFreshGlobals", []int{2, 2}},
+	OpFreshLocals:  {"OpFreshLocals", []int{1, 1}},
+
+	OpAssignGlobal: {"OpAssignGlobal", []int{2}},
+	OpAssignLocal:  {"OpAssignLocal", []int{1}},
+	OpAssignFree:   {"OpAssignFree", []int{1}},
+
+	OpArray:    {"OpArray", []int{2}},
+	OpHashKey:  {"OpHashKey", []int{}},
+	OpHash:     {"OpHash", []int{2}},
+	OpIndex:    {"OpIndex", []int{}},
+	OpSetIndex: {"OpSetIndex", []int{}},
+	OpSlice:    {"OpSlice", []int{1}},
+
+	OpCall:        {"OpCall", []int{1}},
+	OpReturnValue: {"OpReturnValue", []int{}},
+	OpReturn:      {"OpReturn", []int{}},
+	OpClosure:     {"OpClosure", []int{2}},
+}
+
+func Lookup(op Opcode) (*Definition, error) {
+	def, ok := definitions[op]
+	if !ok {
+		return nil, fmt.Errorf("opcode %d undefined", op)
+	}
+	return def, nil
+}
+
+// Make encodes op and its operands. It returns nil for an unknown Opcode.
+func Make(op Opcode, operands ...int) Instructions {
+	def, ok := definitions[op]
+	if !ok {
+		return nil
+	}
+
+	length := 1
+	for _, width := range def.OperandWidths {
+		length += width
+	}
+
+	instruction := make(Instructions, length)
+	instruction[0] = byte(op)
+
+	offset := 1
+	for i, operand := range operands {
+		width := def.OperandWidths[i]
+		switch width {
+		case 2:
+			binary.BigEndian.PutUint16(instruction[offset:], uint16(operand))
+		case 1:
+			instruction[offset] = byte(operand)
+		}
+		offset += width
+	}
+	return instruction
+}
+
+// ReadOperands decodes the operands of def from ins, which starts right
+// after the Opcode, and returns them with the number of bytes read.
+func ReadOperands(def *Definition, ins Instructions) ([]int, int) {
+	operands := make([]int, len(def.OperandWidths))
+	offset := 0
+
+	for i, width := range def.OperandWidths {
+		switch width {
+		case 2:
+			operands[i] = int(ReadUint16(ins[offset:]))
+		case 1:
+			operands[i] = int(ReadUint8(ins[offset:]))
+		}
+		offset += width
+	}
+	return operands, offset
+}
+
+func ReadUint16(ins Instructions) uint16 {
+	return binary.BigEndian.Uint16(ins)
+}
+
+func ReadUint8(ins Instructions) uint8 {
+	return uint8(ins[0])
+}
+
+// String disassembles ins, one instruction per line prefixed by its offset.
+func (ins Instructions) String() string {
+	var out bytes.Buffer
+
+	i := 0
+	for i < len(ins) {
+		def, err := Lookup(Opcode(ins[i]))
+		if err != nil {
+			fmt.Fprintf(&out, "ERROR: %s\n", err)
+			i++
+			continue
+		}
+
+		operands, read := ReadOperands(def, ins[i+1:])
+		fmt.Fprintf(&out, "%04d %s\n", i, ins.formatInstruction(def, operands))
+		i += 1 + read
+	}
+	return out.String()
+}
+
+func (ins Instructions) formatInstruction(def *Definition, operands []int) string {
+	if len(operands) != len(def.OperandWidths) {
+		return fmt.Sprintf("ERROR: operand len %d does not match defined %d\n",
+			len(operands), len(def.OperandWidths),
+		)
+	}
+
+	switch len(operands) {
+	case 0:
+		return def.Name
+	case 1:
+		return fmt.Sprintf("%s %d", def.Name, operands[0])
+	case 2:
+		return fmt.Sprintf("%s %d %d", def.Name, operands[0], operands[1])
+	default:
+		return fmt.Sprintf("ERROR: unhandled operand count for %s\n", def.Name)
+	}
+}
+
+// SourceMap maps the offset of an instruction to the span of the node it
+// was compiled from, so runtime errors can be located. Offsets are added in
+// increasing order.
+type SourceMap struct {
+	Offsets []int
+	Spans   []token.Span
+}
+
+func (sm *SourceMap) Add(offset int, span token.Span) {
+	sm.Offsets = append(sm.Offsets, offset)
+	sm.Spans = append(sm.Spans, span)
+}
+
+// Lookup returns the span recorded for the instruction at offset, or the
+// zero Span if there is none.
+func (sm *SourceMap) Lookup(offset int) token.Span {
+	i := sort.SearchInts(sm.Offsets, offset)
+	if i < len(sm.Offsets) && sm.Offsets[i] == offset {
+		return sm.Spans[i]
+	}
+	return token.Span{}
+}
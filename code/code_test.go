@@ -0,0 +1,93 @@
+package code
+
+import (
+	"gorilla/token"
+	"testing"
+)
+
+func TestMake(t *testing.T) {
+	tests := []struct {
+		op       Opcode
+		operands []int
+		expected []byte
+	}{
+		{OpConstant, []int{65534}, []byte{byte(OpConstant), 255, 254}},
+		{OpAdd, []int{}, []byte{byte(OpAdd)}},
+		{OpGetLocal, []int{255}, []byte{byte(OpGetLocal), 255}},
+		{OpSlice, []int{SliceStart | SliceEnd}, []byte{byte(OpSlice), 3}},
+		{OpFreshGlobals, []int{1, 258}, []byte{byte(OpFreshGlobals), 0, 1, 1, 2}},
+	}
+
+	for _, tt := range tests {
+		instruction := Make(tt.op, tt.operands...)
+		if string(instruction) != string(tt.expected) {
+			t.Errorf("Make(%d, %v): expected %v. got %v", tt.op, tt.operands, tt.expected, instruction)
+		}
+	}
+}
+
+func TestReadOperands(t *testing.T) {
+	tests := []struct {
+		op        Opcode
+		operands  []int
+		bytesRead int
+	}{
+		{OpConstant, []int{65535}, 2},
+		{OpCall, []int{255}, 1},
+		{OpPop, []int{}, 0},
+	}
+
+	for _, tt := range tests {
+		instruction := Make(tt.op, tt.operands...)
+		def, err := Lookup(tt.op)
+		if err != nil {
+			t.Fatalf("definition not found: %s", err)
+		}
+
+		operands, n := ReadOperands(def, instruction[1:])
+		if n != tt.bytesRead {
+			t.Fatalf("%s: expected %d bytes read. got %d", def.Name, tt.bytesRead, n)
+		}
+		for i, operand := range tt.operands {
+			if operands[i] != operand {
+				t.Errorf("%s: operand %d - expected %d. got %d", def.Name, i, operand, operands[i])
+			}
+		}
+	}
+}
+
+func TestInstructionsString(t *testing.T) {
+	instructions := Instructions{}
+	for _, ins := range []Instructions{
+		Make(OpAdd),
+		Make(OpGetLocal, 1),
+		Make(OpConstant, 2),
+		Make(OpJumpNotTruthy, 65535),
+		Make(OpFreshLocals, 2, 4),
+	} {
+		instructions = append(instructions, ins...)
+	}
+
+	expected := `0000 OpAdd
+0001 OpGetLocal 1
+0003 OpConstant 2
+0006 OpJumpNotTruthy 65535
+0009 OpFreshLocals 2 4
+`
+	if instructions.String() != expected {
+		t.Errorf("Wrong disassembly.\nexpected:\n%s\ngot:\n%s", expected, instructions.String())
+	}
+}
+
+func TestSourceMap(t *testing.T) {
+	sourceMap := SourceMap{}
+	sourceMap.Add(0, token.Span{Start: 1, End: 4})
+	sourceMap.Add(7, token.Span{Start: 5, End: 9})
+
+	if span := sourceMap.Lookup(7); span.Start != 5 {
+		t.Errorf("Expected span starting at 5. got %d", span.Start)
+	}
+	if span := sourceMap.Lookup(3); span.Start.IsValid() {
+		t.Errorf("Expected no span at offset 3. got %v", span)
+	}
+}
@@ -0,0 +1,881 @@
+package compiler
+
+import (
+	"fmt"
+	"gorilla/ast"
+	"gorilla/code"
+	"gorilla/eval"
+	"gorilla/object"
+	"gorilla/token"
+	"math"
+)
+
+var infixOpcodes = map[token.TokenType]code.Opcode{
+	token.PLUS:     code.OpAdd,
+	token.MINUS:    code.OpSub,
+	token.ASTERISK: code.OpMul,
+	token.SLASH:    code.OpDiv,
+	token.PERCENT:  code.OpMod,
+	token.POWER:    code.OpPow,
+
+	token.BIT_AND:     code.OpBitAnd,
+	token.BIT_OR:      code.OpBitOr,
+	token.BIT_XOR:     code.OpBitXor,
+	token.SHIFT_LEFT:  code.OpShiftLeft,
+	token.SHIFT_RIGHT: code.OpShiftRight,
+
+	token.EQ:     code.OpEqual,
+	token.NOT_EQ: code.OpNotEqual,
+	token.LT:     code.OpLess,
+	token.GT:     code.OpGreater,
+	token.LE:     code.OpLessEqual,
+	token.GE:     code.OpGreaterEqual,
+}
+
+var prefixOpcodes = map[token.TokenType]code.Opcode{
+	token.MINUS:   code.OpMinus,
+	token.BANG:    code.OpBang,
+	token.BIT_NOT: code.OpBitNot,
+}
+
+// Bytecode is a compiled program, ready to be run by the vm.
+type Bytecode struct {
+	Instructions code.Instructions
+	SourceMap    code.SourceMap
+	Constants    []object.Object
+
+	// Globals names the global slots, by index.
+	Globals []string
+}
+
+// compilationScope collects the instructions of the function being compiled.
+type compilationScope struct {
+	instructions code.Instructions
+	sourceMap    code.SourceMap
+	loops        []*loop // the loops around the statement being compiled, innermost last
+}
+
+// loop is where break and continue jump to in a loop being compiled. The
+// end is not known until the body is compiled, so the offsets of the
+// break and continue jumps are kept to be patched.
+type loop struct {
+	start     int
+	breaks    []int
+	continues []int
+}
+
+// Compiler lowers an ast.Program to Bytecode with the same semantics as
+// eval.EvalProgram: every statement produces a value, the program evaluates
+// to the value of its last statement, and a function without a return
+// statement returns None.
+type Compiler struct {
+	constants   []object.Object
+	builtins    map[string]int
+	symbolTable *SymbolTable
+
+	scopes     []compilationScope
+	scopeIndex int
+
+	// err is the first operand found too large for its instruction. The
+	// operands are only known deep in the compilation, so it is kept here
+	// and returned by Compile.
+	err error
+}
+
+func New() *Compiler {
+	return NewWithState(NewSymbolTable(), []object.Object{})
+}
+
+// NewWithState creates a compiler that continues from the globals of
+// symbolTable and the constants of an earlier compilation, so predefined or
+// previously compiled globals can be used.
+func NewWithState(symbolTable *SymbolTable, constants []object.Object) *Compiler {
+	return &Compiler{
+		constants:   constants,
+		builtins:    map[string]int{},
+		symbolTable: symbolTable,
+		scopes:      []compilationScope{{}},
+	}
+}
+
+func (c *Compiler) Bytecode() *Bytecode {
+	return &Bytecode{
+		Instructions: c.currentInstructions(),
+		SourceMap:    c.scopes[c.scopeIndex].sourceMap,
+		Constants:    c.constants,
+		Globals:      c.symbolTable.Global().Names(),
+	}
+}
+
+// Compile compiles a whole program. Undefined names are not reported here:
+// like the evaluator, the vm reports them when they are read, so functions
+// can refer to globals defined after them.
+func (c *Compiler) Compile(prog *ast.Program) error {
+	if err := c.compileStatements(prog.Statements); err != nil {
+		return err
+	}
+	c.emit(code.OpReturnValue)
+	return c.err
+}
+
+// compileStatements compiles a program or block, leaving the value of its
+// last statement, or None, on the stack.
+func (c *Compiler) compileStatements(statements []ast.StatementNode) error {
+	if len(statements) == 0 {
+		c.emit(code.OpNone)
+		return nil
+	}
+	c.defineFunctions(statements)
+
+	for i, stmt := range statements {
+		pushed, err := c.compileStatement(stmt)
+		if err != nil {
+			return err
+		}
+
+		isLast := i == len(statements)-1
+		if pushed && !isLast {
+			c.emit(code.OpPop)
+		} else if !pushed && isLast {
+			c.emit(code.OpNone)
+		}
+	}
+	return nil
+}
+
+// defineFunctions defines the names bound to function literals before the
+// statements are compiled, so functions of one scope can call each other
+// whatever their order, as they can in the evaluator.
+func (c *Compiler) defineFunctions(statements []ast.StatementNode) {
+	for _, stmt := range statements {
+		letStmt, ok := stmt.(*ast.LetStatement)
+		if !ok {
+			continue
+		}
+		if _, ok := letStmt.Expression.(*ast.FunctionLiteral); ok {
+			c.symbolTable.Define(letStmt.Identifier.GetName())
+		}
+	}
+}
+
+// compileStatement reports whether the statement left its value on the
+// stack. A let statement leaves nothing, its value is None; neither does a
+// return statement, which never completes.
+func (c *Compiler) compileStatement(stmt ast.StatementNode) (bool, error) {
+	switch stmt := stmt.(type) {
+	case *ast.LetStatement:
+		return false, c.compileLetStatement(stmt)
+
+	case *ast.ReturnStatement:
+		if stmt.ReturnValue == nil {
+			c.emit(code.OpNone)
+		} else if err := c.compileExpression(stmt.ReturnValue); err != nil {
+			return false, err
+		}
+		c.emit(code.OpReturnValue)
+		return false, nil
+
+	case *ast.ExpressionStatement:
+		return true, c.compileExpression(stmt.Expression)
+
+	case *ast.BlockStatement:
+		c.symbolTable = NewBlockSymbolTable(c.symbolTable)
+		err := c.compileStatements(stmt.Statements)
+		c.symbolTable = c.symbolTable.Outer
+		return true, err
+
+	case *ast.IfStatement:
+		return true, c.compileIfStatement(stmt)
+
+	case *ast.ElseStatement:
+		return c.compileStatement(stmt.Statement)
+
+	case *ast.WhileStatement:
+		return false, c.compileWhileStatement(stmt)
+
+	case *ast.ForStatement:
+		return false, c.compileForStatement(stmt)
+
+	case *ast.Assignment:
+		return false, c.compileAssignment(stmt)
+
+	case *ast.BreakStatement:
+		loop := c.currentLoop()
+		loop.breaks = append(loop.breaks, c.emit(code.OpJump, 9999))
+		return false, nil
+
+	case *ast.ContinueStatement:
+		loop := c.currentLoop()
+		loop.continues = append(loop.continues, c.emit(code.OpJump, 9999))
+		return false, nil
+
+	default:
+		return false, fmt.Errorf("cannot compile %T", stmt)
+	}
+}
+
+func (c *Compiler) compileLetStatement(letStmt *ast.LetStatement) error {
+	name := letStmt.Identifier.GetName()
+
+	// A function literal sees its own name so it can recurse. Any other
+	// value is compiled first, so `let x = x + 1` reads the old binding.
+	var symbol Symbol
+	_, isFunction := letStmt.Expression.(*ast.FunctionLiteral)
+	if isFunction {
+		symbol = c.symbolTable.Define(name)
+	}
+
+	if err := c.compileExpression(letStmt.Expression); err != nil {
+		return err
+	}
+
+	if !isFunction {
+		symbol = c.symbolTable.Define(name)
+	}
+	return c.storeSymbol(symbol)
+}
+
+func (c *Compiler) compileIfStatement(ifStmt *ast.IfStatement) error {
+	if err := c.compileExpression(ifStmt.Condition); err != nil {
+		return err
+	}
+	jumpNotTruthyPos := c.emit(code.OpJumpNotTruthy, 9999)
+
+	if err := c.compileStatementValue(ifStmt.Statement); err != nil {
+		return err
+	}
+	jumpPos := c.emit(code.OpJump, 9999)
+	c.changeOperand(jumpNotTruthyPos, len(c.currentInstructions()))
+
+	if ifStmt.Else == nil {
+		c.emit(code.OpNone)
+	} else if err := c.compileStatementValue(ifStmt.Else); err != nil {
+		return err
+	}
+	c.changeOperand(jumpPos, len(c.currentInstructions()))
+	return nil
+}
+
+// compileAssignment evaluates the target before the value, as the evaluator
+// does. A compound assignment reads the old value first: an index target
+// keeps a copy of its left side and index for OpSetIndex.
+func (c *Compiler) compileAssignment(assign *ast.Assignment) error {
+	switch target := assign.Target.(type) {
+	case *ast.IdentifierExpression:
+		if assign.IsCompound() {
+			if err := c.compileIdentifier(target); err != nil {
+				return err
+			}
+		}
+		if err := c.compileAssignedValue(assign); err != nil {
+			return err
+		}
+
+		symbol, ok := c.symbolTable.Resolve(target.GetName())
+		if !ok {
+			// a global defined later, or never
+			symbol = c.symbolTable.Global().Define(target.GetName())
+		}
+		switch symbol.Scope {
+		case GlobalScope:
+			c.emitAt(target, code.OpAssignGlobal, symbol.Index)
+		case LocalScope:
+			c.emitAt(target, code.OpAssignLocal, symbol.Index)
+		case FreeScope:
+			c.emitAt(target, code.OpAssignFree, symbol.Index)
+		}
+
+	case *ast.IndexExpression:
+		if err := c.compileExpression(target.Left); err != nil {
+			return err
+		}
+		if err := c.compileExpression(target.Index); err != nil {
+			return err
+		}
+		if assign.IsCompound() {
+			c.emit(code.OpDupTwo)
+			c.emitAt(target, code.OpIndex)
+		}
+		if err := c.compileAssignedValue(assign); err != nil {
+			return err
+		}
+		c.emitAt(target, code.OpSetIndex)
+
+	default:
+		return fmt.Errorf("cannot assign to %s", assign.Target.ToString())
+	}
+	return nil
+}
+
+// compileAssignedValue compiles the right-hand side of assign, combined
+// with the old value on the stack for a compound assignment.
+func (c *Compiler) compileAssignedValue(assign *ast.Assignment) error {
+	if err := c.compileExpression(assign.Value); err != nil {
+		return err
+	}
+	if assign.IsCompound() {
+		c.emitAt(assign, infixOpcodes[assign.GetInfixOperator()])
+	}
+	return nil
+}
+
+// compileWhileStatement tests the condition before each iteration. A loop
+// leaves no value, like a let statement.
+func (c *Compiler) compileWhileStatement(whileStmt *ast.WhileStatement) error {
+	start := len(c.currentInstructions())
+	firstSlot := c.symbolTable.NumDefinitions()
+	if err := c.compileExpression(whileStmt.Condition); err != nil {
+		return err
+	}
+	jumpNotTruthyPos := c.emit(code.OpJumpNotTruthy, 9999)
+
+	if err := c.compileLoopBody(start, firstSlot, whileStmt.Body); err != nil {
+		return err
+	}
+	c.changeOperand(jumpNotTruthyPos, len(c.currentInstructions()))
+	return nil
+}
+
+// compileForStatement keeps an iterator on the stack for the whole loop.
+// OpIterNext jumps past the body once it is exhausted, to the OpPop that
+// drops the iterator; break jumps there as well.
+func (c *Compiler) compileForStatement(forStmt *ast.ForStatement) error {
+	if err := c.compileExpression(forStmt.Iterable); err != nil {
+		return err
+	}
+	c.emitAt(forStmt.Iterable, code.OpIter)
+
+	// the loop variable is scoped to the loop
+	c.symbolTable = NewBlockSymbolTable(c.symbolTable)
+	defer func() { c.symbolTable = c.symbolTable.Outer }()
+
+	start := c.emit(code.OpIterNext, 9999)
+	if err := c.storeSymbol(c.symbolTable.Define(forStmt.Variable.GetName())); err != nil {
+		return err
+	}
+
+	if err := c.compileLoopBody(start, c.symbolTable.NumDefinitions(), forStmt.Body); err != nil {
+		return err
+	}
+	c.changeOperand(start, len(c.currentInstructions()))
+	c.emit(code.OpPop)
+	return nil
+}
+
+// compileLoopBody compiles the body of a loop starting at start, the jump
+// back to it, and patches the breaks of the body to the end of the loop.
+//
+// As in the evaluator, every iteration runs in new scopes, so the variables
+// defined from firstSlot on that a function captures are given new storage
+// before the next iteration, and continue jumps there too.
+func (c *Compiler) compileLoopBody(start int, firstSlot int, body *ast.BlockStatement) error {
+	// c.scopes grows when the body has a function literal, so it is indexed
+	// again rather than kept as a pointer
+	current := &loop{start: start}
+	c.scopes[c.scopeIndex].loops = append(c.scopes[c.scopeIndex].loops, current)
+
+	if _, err := c.compileStatement(body); err != nil {
+		return err
+	}
+	c.emit(code.OpPop)
+
+	next := start
+	if c.symbolTable.capturedSince(firstSlot) {
+		next = c.emitFresh(firstSlot)
+	}
+	c.emit(code.OpJump, c.jumpTarget(start))
+	for _, pos := range current.continues {
+		c.changeOperand(pos, next)
+	}
+
+	loops := c.scopes[c.scopeIndex].loops
+	c.scopes[c.scopeIndex].loops = loops[:len(loops)-1]
+	for _, pos := range current.breaks {
+		c.changeOperand(pos, len(c.currentInstructions()))
+	}
+	return nil
+}
+
+// emitFresh emits the instruction that gives the slots from firstSlot on new
+// storage.
+func (c *Compiler) emitFresh(firstSlot int) int {
+	lastSlot := c.symbolTable.NumDefinitions() - 1
+	if c.symbolTable.function.Outer == nil {
+		return c.emit(code.OpFreshGlobals, firstSlot, lastSlot)
+	}
+	return c.emit(code.OpFreshLocals, firstSlot, lastSlot)
+}
+
+// currentLoop returns the innermost loop. The parser only accepts break and
+// continue inside a loop of the same function.
+func (c *Compiler) currentLoop() *loop {
+	loops := c.scopes[c.scopeIndex].loops
+	return loops[len(loops)-1]
+}
+
+// compileStatementValue compiles stmt so that it always leaves a value.
+func (c *Compiler) compileStatementValue(stmt ast.StatementNode) error {
+	pushed, err := c.compileStatement(stmt)
+	if err != nil {
+		return err
+	}
+	if !pushed {
+		c.emit(code.OpNone)
+	}
+	return nil
+}
+
+func (c *Compiler) compileExpression(expr ast.ExpressionNode) error {
+	switch expr := expr.(type) {
+	case *ast.IdentifierExpression:
+		return c.compileIdentifier(expr)
+
+	case *ast.BoolLiteral:
+		if expr.GetValue() {
+			c.emit(code.OpTrue)
+		} else {
+			c.emit(code.OpFalse)
+		}
+
+	case *ast.IntegerLiteral:
+		if bigValue := expr.GetBigValue(); bigValue != nil {
+			c.emit(code.OpConstant, c.addConstant(&object.BigInt{Value: bigValue}))
+			break
+		}
+		c.emit(code.OpConstant, c.addConstant(&object.Int{Value: expr.GetValue()}))
+
+	case *ast.FloatLiteral:
+		c.emit(code.OpConstant, c.addConstant(&object.Float{Value: expr.GetValue()}))
+
+	case *ast.StringLiteral:
+		c.emit(code.OpConstant, c.addConstant(&object.String{Value: expr.GetValue()}))
+
+	case *ast.ArrayLiteral:
+		if err := c.compileExpressions(expr.Elements); err != nil {
+			return err
+		}
+		c.emit(code.OpArray, len(expr.Elements))
+
+	case *ast.HashLiteral:
+		for _, pair := range expr.Pairs {
+			if err := c.compileExpression(pair.Key); err != nil {
+				return err
+			}
+			// checked before the value is evaluated, as the evaluator does
+			c.emitAt(pair.Key, code.OpHashKey)
+			if err := c.compileExpression(pair.Value); err != nil {
+				return err
+			}
+		}
+		c.emitAt(expr, code.OpHash, len(expr.Pairs))
+
+	case *ast.IndexExpression:
+		if err := c.compileExpression(expr.Left); err != nil {
+			return err
+		}
+		if err := c.compileExpression(expr.Index); err != nil {
+			return err
+		}
+		c.emitAt(expr, code.OpIndex)
+
+	case *ast.SliceExpression:
+		return c.compileSliceExpression(expr)
+
+	case *ast.Prefix:
+		opcode, ok := prefixOpcodes[expr.GetOperatorType()]
+		if !ok {
+			return fmt.Errorf("unknown prefix operator %s", expr.GetOperatorType())
+		}
+		if err := c.compileExpression(expr.Operand); err != nil {
+			return err
+		}
+		c.emitAt(expr, opcode)
+
+	case *ast.Infix:
+		opcode, ok := infixOpcodes[expr.GetOperatorType()]
+		if !ok {
+			return fmt.Errorf("unknown infix operator %s", expr.GetOperatorType())
+		}
+		if err := c.compileExpression(expr.Left); err != nil {
+			return err
+		}
+		if err := c.compileExpression(expr.Right); err != nil {
+			return err
+		}
+		c.emitAt(expr, opcode)
+
+	case *ast.Logical:
+		return c.compileLogical(expr)
+
+	case *ast.Comparison:
+		return c.compileComparison(expr)
+
+	case *ast.Trinary:
+		return c.compileTrinary(expr)
+
+	case *ast.Match:
+		return c.compileMatch(expr)
+
+	case *ast.FunctionLiteral:
+		return c.compileFunctionLiteral(expr)
+
+	case *ast.FunctionCall:
+		if err := c.compileExpression(expr.Function); err != nil {
+			return err
+		}
+		if len(expr.Arguments) > math.MaxUint8 {
+			return fmt.Errorf("too many arguments: %d", len(expr.Arguments))
+		}
+		if err := c.compileExpressions(expr.Arguments); err != nil {
+			return err
+		}
+		c.emitAt(expr, code.OpCall, len(expr.Arguments))
+
+	default:
+		return fmt.Errorf("cannot compile %T", expr)
+	}
+	return nil
+}
+
+func (c *Compiler) compileExpressions(exprs []ast.ExpressionNode) error {
+	for _, expr := range exprs {
+		if err := c.compileExpression(expr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// compileIdentifier loads a variable or builtin. A name that is not defined
+// yet is assumed to be a global defined later; reading it before that is a
+// runtime error.
+func (c *Compiler) compileIdentifier(identifier *ast.IdentifierExpression) error {
+	name := identifier.GetName()
+	symbol, ok := c.symbolTable.Resolve(name)
+	if !ok {
+		if builtin, ok := eval.LookupBuiltin(name); ok {
+			c.emit(code.OpConstant, c.addBuiltin(builtin))
+			return nil
+		}
+		symbol = c.symbolTable.Global().Define(name)
+	}
+
+	switch symbol.Scope {
+	case GlobalScope:
+		c.emitAt(identifier, code.OpGetGlobal, symbol.Index)
+	case LocalScope:
+		c.emit(code.OpGetLocal, symbol.Index)
+	case FreeScope:
+		c.emit(code.OpGetFree, symbol.Index)
+	}
+	return nil
+}
+
+func (c *Compiler) storeSymbol(symbol Symbol) error {
+	switch symbol.Scope {
+	case GlobalScope:
+		if symbol.Index > math.MaxUint16 {
+			return fmt.Errorf("too many global variables")
+		}
+		c.emit(code.OpSetGlobal, symbol.Index)
+	case LocalScope:
+		if symbol.Index > math.MaxUint8 {
+			return fmt.Errorf("too many local variables")
+		}
+		c.emit(code.OpSetLocal, symbol.Index)
+	}
+	return nil
+}
+
+func (c *Compiler) compileSliceExpression(sliceOp *ast.SliceExpression) error {
+	if err := c.compileExpression(sliceOp.Left); err != nil {
+		return err
+	}
+
+	flags := 0
+	if sliceOp.Start != nil {
+		if err := c.compileExpression(sliceOp.Start); err != nil {
+			return err
+		}
+		flags |= code.SliceStart
+	}
+	if sliceOp.End != nil {
+		if err := c.compileExpression(sliceOp.End); err != nil {
+			return err
+		}
+		flags |= code.SliceEnd
+	}
+	c.emitAt(sliceOp, code.OpSlice, flags)
+	return nil
+}
+
+// compileLogical jumps over the right operand when the left one decides
+// the result. The right operand is converted to a Bool with two OpBangs.
+func (c *Compiler) compileLogical(logical *ast.Logical) error {
+	if err := c.compileExpression(logical.Left); err != nil {
+		return err
+	}
+	jumpNotTruthyPos := c.emit(code.OpJumpNotTruthy, 9999)
+
+	var jumpPos int
+	if logical.GetOperatorType() == token.OR {
+		// a truthy left operand is the result
+		c.emit(code.OpTrue)
+		jumpPos = c.emit(code.OpJump, 9999)
+		c.changeOperand(jumpNotTruthyPos, len(c.currentInstructions()))
+	}
+
+	if err := c.compileExpression(logical.Right); err != nil {
+		return err
+	}
+	c.emit(code.OpBang)
+	c.emit(code.OpBang)
+
+	if logical.GetOperatorType() == token.AND {
+		// a falsy left operand is the result
+		jumpPos = c.emit(code.OpJump, 9999)
+		c.changeOperand(jumpNotTruthyPos, len(c.currentInstructions()))
+		c.emit(code.OpFalse)
+	}
+	c.changeOperand(jumpPos, len(c.currentInstructions()))
+	return nil
+}
+
+// compileComparison keeps a copy of each middle operand under the result
+// of its comparison, so it is evaluated once and can be compared with the
+// next operand. A false comparison jumps to the end, dropping that copy.
+func (c *Compiler) compileComparison(comparison *ast.Comparison) error {
+	if err := c.compileExpression(comparison.Operands[0]); err != nil {
+		return err
+	}
+
+	jumpNotTruthyPositions := []int{}
+	for i, operator := range comparison.Operators {
+		if err := c.compileExpression(comparison.Operands[i+1]); err != nil {
+			return err
+		}
+
+		isLast := i == len(comparison.Operators)-1
+		if !isLast {
+			// [left right] becomes [right left right]
+			c.emit(code.OpDup)
+			c.emit(code.OpRotThree)
+		}
+		c.emitAt(comparison, infixOpcodes[operator.Type])
+		if !isLast {
+			jumpNotTruthyPositions = append(jumpNotTruthyPositions, c.emit(code.OpJumpNotTruthy, 9999))
+		}
+	}
+	jumpPos := c.emit(code.OpJump, 9999)
+
+	for _, pos := range jumpNotTruthyPositions {
+		c.changeOperand(pos, len(c.currentInstructions()))
+	}
+	c.emit(code.OpPop)
+	c.emit(code.OpFalse)
+
+	c.changeOperand(jumpPos, len(c.currentInstructions()))
+	return nil
+}
+
+func (c *Compiler) compileTrinary(trinary *ast.Trinary) error {
+	if err := c.compileExpression(trinary.Middle); err != nil {
+		return err
+	}
+	jumpNotTruthyPos := c.emit(code.OpJumpNotTruthy, 9999)
+
+	if err := c.compileExpression(trinary.Left); err != nil {
+		return err
+	}
+	jumpPos := c.emit(code.OpJump, 9999)
+	c.changeOperand(jumpNotTruthyPos, len(c.currentInstructions()))
+
+	if err := c.compileExpression(trinary.Right); err != nil {
+		return err
+	}
+	c.changeOperand(jumpPos, len(c.currentInstructions()))
+	return nil
+}
+
+// compileMatch keeps the value on the stack while the arms are tried. Each
+// arm tests a copy of it with OpMatch, stores the bindings in a scope of the
+// arm, checks the guard, and only pops the value before running the body.
+func (c *Compiler) compileMatch(match *ast.Match) error {
+	if err := c.compileExpression(match.Value); err != nil {
+		return err
+	}
+
+	var endJumps []int
+	for _, arm := range match.Arms {
+		var err error
+		endJumps, err = c.compileMatchArm(arm, endJumps)
+		if err != nil {
+			return err
+		}
+	}
+	c.emitAt(match, code.OpNoMatch)
+
+	for _, pos := range endJumps {
+		c.changeOperand(pos, len(c.currentInstructions()))
+	}
+	return nil
+}
+
+// compileMatchArm compiles arm and appends its jump to the end of the match
+// to endJumps.
+func (c *Compiler) compileMatchArm(arm *ast.MatchArm, endJumps []int) ([]int, error) {
+	c.symbolTable = NewBlockSymbolTable(c.symbolTable)
+	defer func() { c.symbolTable = c.symbolTable.Outer }()
+
+	c.emit(code.OpDup)
+	c.emit(code.OpMatch, c.addConstant(&object.Pattern{Node: arm.Pattern}))
+	nextArmJumps := []int{c.emit(code.OpJumpNotTruthy, 9999)}
+
+	// OpMatch pushes the bound values in order, so they are stored from last
+	// to first
+	bindings := ast.PatternBindings(arm.Pattern)
+	for i := len(bindings) - 1; i >= 0; i-- {
+		if err := c.storeSymbol(c.symbolTable.Define(bindings[i].GetName())); err != nil {
+			return nil, err
+		}
+	}
+
+	if arm.Guard != nil {
+		if err := c.compileExpression(arm.Guard); err != nil {
+			return nil, err
+		}
+		nextArmJumps = append(nextArmJumps, c.emit(code.OpJumpNotTruthy, 9999))
+	}
+
+	c.emit(code.OpPop)
+	if err := c.compileExpression(arm.Body); err != nil {
+		return nil, err
+	}
+	endJumps = append(endJumps, c.emit(code.OpJump, 9999))
+
+	for _, pos := range nextArmJumps {
+		c.changeOperand(pos, len(c.currentInstructions()))
+	}
+	return endJumps, nil
+}
+
+// compileFunctionLiteral compiles the body into a CompiledFunction constant
+// and emits the OpClosure that captures its free variables. As in the
+// evaluator, the body shares one scope with the parameters.
+func (c *Compiler) compileFunctionLiteral(fnLit *ast.FunctionLiteral) error {
+	c.enterScope()
+
+	for _, param := range fnLit.Signiture {
+		c.symbolTable.Define(param.GetName())
+	}
+	c.defineFunctions(fnLit.Body.Statements)
+
+	for _, stmt := range fnLit.Body.Statements {
+		pushed, err := c.compileStatement(stmt)
+		if err != nil {
+			return err
+		}
+		if pushed {
+			c.emit(code.OpPop)
+		}
+	}
+	c.emit(code.OpReturn)
+
+	symbolTable := c.symbolTable
+	if symbolTable.NumDefinitions() > math.MaxUint8+1 {
+		return fmt.Errorf("too many local variables in function")
+	}
+	scope := c.leaveScope()
+
+	captures := make([]object.Capture, len(symbolTable.FreeSymbols))
+	for i, symbol := range symbolTable.FreeSymbols {
+		captures[i] = object.Capture{
+			Name:   symbol.Name,
+			Local:  symbol.Scope == LocalScope,
+			Global: symbol.Scope == GlobalScope,
+			Index:  symbol.Index,
+		}
+	}
+
+	compiledFn := &object.CompiledFunction{
+		Instructions:   scope.instructions,
+		SourceMap:      scope.sourceMap,
+		NumLocals:      symbolTable.NumDefinitions(),
+		NumParameters:  len(fnLit.Signiture),
+		Captures:       captures,
+		LocalNames:     symbolTable.Names(),
+		LocalsCaptured: symbolTable.localsCaptured,
+		Literal:        fnLit,
+	}
+	c.emit(code.OpClosure, c.addConstant(compiledFn))
+	return nil
+}
+
+func (c *Compiler) addConstant(obj object.Object) int {
+	c.constants = append(c.constants, obj)
+	index := len(c.constants) - 1
+	if index > math.MaxUint16 && c.err == nil {
+		c.err = fmt.Errorf("too many constants")
+	}
+	return index
+}
+
+// addBuiltin adds builtin to the constant pool once.
+func (c *Compiler) addBuiltin(builtin *object.Builtin) int {
+	if index, ok := c.builtins[builtin.Name]; ok {
+		return index
+	}
+	index := c.addConstant(builtin)
+	c.builtins[builtin.Name] = index
+	return index
+}
+
+func (c *Compiler) currentInstructions() code.Instructions {
+	return c.scopes[c.scopeIndex].instructions
+}
+
+// emit appends an instruction and returns its offset.
+func (c *Compiler) emit(op code.Opcode, operands ...int) int {
+	scope := &c.scopes[c.scopeIndex]
+	pos := len(scope.instructions)
+	scope.instructions = append(scope.instructions, code.Make(op, operands...)...)
+	return pos
+}
+
+// emitAt emits an instruction that can fail at runtime, recording node as
+// the location of its errors.
+func (c *Compiler) emitAt(node ast.Node, op code.Opcode, operands ...int) int {
+	pos := c.emit(op, operands...)
+	c.scopes[c.scopeIndex].sourceMap.Add(pos, node.GetSpan())
+	return pos
+}
+
+// changeOperand rewrites the operand of the instruction at pos, used to
+// patch jumps once their target is known.
+func (c *Compiler) changeOperand(pos int, operand int) {
+	instructions := c.currentInstructions()
+	op := code.Opcode(instructions[pos])
+	copy(instructions[pos:], code.Make(op, c.jumpTarget(operand)))
+}
+
+// jumpTarget checks that target fits in the operand of a jump.
+func (c *Compiler) jumpTarget(target int) int {
+	if target > math.MaxUint16 && c.err == nil {
+		c.err = fmt.Errorf("jump target too far")
+	}
+	return target
+}
+
+func (c *Compiler) enterScope() {
+	c.scopes = append(c.scopes, compilationScope{})
+	c.scopeIndex++
+	c.symbolTable = NewEnclosedSymbolTable(c.symbolTable)
+}
+
+func (c *Compiler) leaveScope() compilationScope {
+	scope := c.scopes[c.scopeIndex]
+	c.scopes = c.scopes[:c.scopeIndex]
+	c.scopeIndex--
+	c.symbolTable = c.symbolTable.Outer
+	return scope
+}
@@ -0,0 +1,339 @@
+package compiler
+
+import (
+	"fmt"
+	"gorilla/code"
+	"gorilla/lexer"
+	"gorilla/object"
+	"gorilla/parser"
+	"strings"
+	"testing"
+)
+
+func TestCompileStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []code.Instructions
+	}{
+		{"", []code.Instructions{
+			code.Make(code.OpNone),
+			code.Make(code.OpReturnValue),
+		}},
+		{"1 + 2; True;", []code.Instructions{
+			code.Make(code.OpConstant, 0),
+			code.Make(code.OpConstant, 1),
+			code.Make(code.OpAdd),
+			code.Make(code.OpPop),
+			code.Make(code.OpTrue),
+			code.Make(code.OpReturnValue),
+		}},
+		// a let statement is worth None
+		{"let x = 1; let y = x;", []code.Instructions{
+			code.Make(code.OpConstant, 0),
+			code.Make(code.OpSetGlobal, 0),
+			code.Make(code.OpGetGlobal, 0),
+			code.Make(code.OpSetGlobal, 1),
+			code.Make(code.OpNone),
+			code.Make(code.OpReturnValue),
+		}},
+		{"if (True) { 10; }", []code.Instructions{
+			code.Make(code.OpTrue),
+			code.Make(code.OpJumpNotTruthy, 10),
+			code.Make(code.OpConstant, 0),
+			code.Make(code.OpJump, 11),
+			code.Make(code.OpNone),
+			code.Make(code.OpReturnValue),
+		}},
+		// the block gets its own slot for x
+		{"let x = 1; { let x = 2; }", []code.Instructions{
+			code.Make(code.OpConstant, 0),
+			code.Make(code.OpSetGlobal, 0),
+			code.Make(code.OpConstant, 1),
+			code.Make(code.OpSetGlobal, 1),
+			code.Make(code.OpNone),
+			code.Make(code.OpReturnValue),
+		}},
+		// the right operand is jumped over once the left one decides
+		{"True && False;", []code.Instructions{
+			code.Make(code.OpTrue),
+			code.Make(code.OpJumpNotTruthy, 10),
+			code.Make(code.OpFalse),
+			code.Make(code.OpBang),
+			code.Make(code.OpBang),
+			code.Make(code.OpJump, 11),
+			code.Make(code.OpFalse),
+			code.Make(code.OpReturnValue),
+		}},
+		{"True || False;", []code.Instructions{
+			code.Make(code.OpTrue),
+			code.Make(code.OpJumpNotTruthy, 8),
+			code.Make(code.OpTrue),
+			code.Make(code.OpJump, 11),
+			code.Make(code.OpFalse),
+			code.Make(code.OpBang),
+			code.Make(code.OpBang),
+			code.Make(code.OpReturnValue),
+		}},
+		// the middle operand is kept under the first result
+		{"1 < 2 < 3;", []code.Instructions{
+			code.Make(code.OpConstant, 0),
+			code.Make(code.OpConstant, 1),
+			code.Make(code.OpDup),
+			code.Make(code.OpRotThree),
+			code.Make(code.OpLess),
+			code.Make(code.OpJumpNotTruthy, 19),
+			code.Make(code.OpConstant, 2),
+			code.Make(code.OpLess),
+			code.Make(code.OpJump, 21),
+			code.Make(code.OpPop),
+			code.Make(code.OpFalse),
+			code.Make(code.OpReturnValue),
+		}},
+		// a loop leaves no value, break jumps past the jump back
+		{"while (True) { break; }", []code.Instructions{
+			code.Make(code.OpTrue),
+			code.Make(code.OpJumpNotTruthy, 12),
+			code.Make(code.OpJump, 12),
+			code.Make(code.OpNone),
+			code.Make(code.OpPop),
+			code.Make(code.OpJump, 0),
+			code.Make(code.OpNone),
+			code.Make(code.OpReturnValue),
+		}},
+		// the iterator stays on the stack until the loop ends
+		{"for (x in [1]) { continue; }", []code.Instructions{
+			code.Make(code.OpConstant, 0),
+			code.Make(code.OpArray, 1),
+			code.Make(code.OpIter),
+			code.Make(code.OpIterNext, 21),
+			code.Make(code.OpSetGlobal, 0),
+			code.Make(code.OpJump, 7),
+			code.Make(code.OpNone),
+			code.Make(code.OpPop),
+			code.Make(code.OpJump, 7),
+			code.Make(code.OpPop),
+			code.Make(code.OpNone),
+			code.Make(code.OpReturnValue),
+		}},
+		// a captured variable of the body gets new storage for each
+		// iteration, before continue jumps back
+		{"while (True) { let k = 1; fn() { return k; }; continue; }", []code.Instructions{
+			code.Make(code.OpTrue),
+			code.Make(code.OpJumpNotTruthy, 27),
+			code.Make(code.OpConstant, 0),
+			code.Make(code.OpSetGlobal, 0),
+			code.Make(code.OpClosure, 1),
+			code.Make(code.OpPop),
+			code.Make(code.OpJump, 19),
+			code.Make(code.OpNone),
+			code.Make(code.OpPop),
+			code.Make(code.OpFreshGlobals, 0, 0),
+			code.Make(code.OpJump, 0),
+			code.Make(code.OpNone),
+			code.Make(code.OpReturnValue),
+		}},
+		// an assignment leaves no value and checks the slot is set
+		{"let x = 1; x += 2;", []code.Instructions{
+			code.Make(code.OpConstant, 0),
+			code.Make(code.OpSetGlobal, 0),
+			code.Make(code.OpGetGlobal, 0),
+			code.Make(code.OpConstant, 1),
+			code.Make(code.OpAdd),
+			code.Make(code.OpAssignGlobal, 0),
+			code.Make(code.OpNone),
+			code.Make(code.OpReturnValue),
+		}},
+		// the array and index are kept for OpSetIndex
+		{"[1][0] *= 2;", []code.Instructions{
+			code.Make(code.OpConstant, 0),
+			code.Make(code.OpArray, 1),
+			code.Make(code.OpConstant, 1),
+			code.Make(code.OpDupTwo),
+			code.Make(code.OpIndex),
+			code.Make(code.OpConstant, 2),
+			code.Make(code.OpMul),
+			code.Make(code.OpSetIndex),
+			code.Make(code.OpNone),
+			code.Make(code.OpReturnValue),
+		}},
+		// the value stays on the stack until an arm matches
+		{"match (1) { x if x => x, _ => 2 };", []code.Instructions{
+			code.Make(code.OpConstant, 0),
+			code.Make(code.OpDup),
+			code.Make(code.OpMatch, 1),
+			code.Make(code.OpJumpNotTruthy, 26),
+			code.Make(code.OpSetGlobal, 0),
+			code.Make(code.OpGetGlobal, 0),
+			code.Make(code.OpJumpNotTruthy, 26),
+			code.Make(code.OpPop),
+			code.Make(code.OpGetGlobal, 0),
+			code.Make(code.OpJump, 41),
+			code.Make(code.OpDup),
+			code.Make(code.OpMatch, 2),
+			code.Make(code.OpJumpNotTruthy, 40),
+			code.Make(code.OpPop),
+			code.Make(code.OpConstant, 3),
+			code.Make(code.OpJump, 41),
+			code.Make(code.OpNoMatch),
+			code.Make(code.OpReturnValue),
+		}},
+		{"[1][0:];", []code.Instructions{
+			code.Make(code.OpConstant, 0),
+			code.Make(code.OpArray, 1),
+			code.Make(code.OpConstant, 1),
+			code.Make(code.OpSlice, code.SliceStart),
+			code.Make(code.OpReturnValue),
+		}},
+	}
+
+	for _, tt := range tests {
+		bytecode := compile(t, tt.input)
+		testInstructions(t, tt.input, tt.expected, bytecode.Instructions)
+	}
+}
+
+func TestCompileFunctions(t *testing.T) {
+	bytecode := compile(t, "let f = fn(a) { let b = a; return fn() { return a + b; }; };")
+
+	outer, ok := bytecode.Constants[len(bytecode.Constants)-1].(*object.CompiledFunction)
+	if !ok {
+		t.Fatalf("Expected CompiledFunction constant. got %T", bytecode.Constants[len(bytecode.Constants)-1])
+	}
+	if outer.NumLocals != 2 || outer.NumParameters != 1 || !outer.LocalsCaptured {
+		t.Errorf("Wrong outer function: locals=%d parameters=%d captured=%t",
+			outer.NumLocals, outer.NumParameters, outer.LocalsCaptured,
+		)
+	}
+	testInstructions(t, "outer", []code.Instructions{
+		code.Make(code.OpGetLocal, 0),
+		code.Make(code.OpSetLocal, 1),
+		code.Make(code.OpClosure, 0),
+		code.Make(code.OpReturnValue),
+		code.Make(code.OpReturn),
+	}, outer.Instructions)
+
+	inner := bytecode.Constants[0].(*object.CompiledFunction)
+	expectedCaptures := []object.Capture{{"a", true, false, 0}, {"b", true, false, 1}}
+	if len(inner.Captures) != len(expectedCaptures) {
+		t.Fatalf("Expected %d captures. got %d", len(expectedCaptures), len(inner.Captures))
+	}
+	for i, capture := range expectedCaptures {
+		if inner.Captures[i] != capture {
+			t.Errorf("captures[%d] - expected %v. got %v", i, capture, inner.Captures[i])
+		}
+	}
+	testInstructions(t, "inner", []code.Instructions{
+		code.Make(code.OpGetFree, 0),
+		code.Make(code.OpGetFree, 1),
+		code.Make(code.OpAdd),
+		code.Make(code.OpReturnValue),
+		code.Make(code.OpReturn),
+	}, inner.Instructions)
+}
+
+func TestCompileLimits(t *testing.T) {
+	var constants, loop strings.Builder
+	constants.WriteString("let x = 0;")
+	for i := 1; i <= 70000; i++ {
+		fmt.Fprintf(&constants, "x = %d;", i)
+	}
+	loop.WriteString("let x = 0; while (False) {")
+	for i := 0; i < 20000; i++ {
+		loop.WriteString("x;")
+	}
+	loop.WriteString("}")
+
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"constants", constants.String(), "too many constants"},
+		{"jump", loop.String(), "jump target too far"},
+	}
+
+	for _, tt := range tests {
+		p := parser.NewParser(lexer.NewLexer(tt.input))
+		prog, ok := p.ParseProgram()
+		if !ok {
+			t.Fatalf("%s - could not parse: %s", tt.name, p.Err())
+		}
+		err := New().Compile(prog)
+		if err == nil || err.Error() != tt.expected {
+			t.Errorf("%s - expected error %q. got %v", tt.name, tt.expected, err)
+		}
+	}
+}
+
+func TestSymbolTable(t *testing.T) {
+	global := NewSymbolTable()
+	a := global.Define("a")
+
+	block := NewBlockSymbolTable(global)
+	shadowed := block.Define("a")
+	if shadowed == a || shadowed.Scope != GlobalScope || shadowed.Index != 1 {
+		t.Errorf("Expected a new global slot for the shadowing a. got %v", shadowed)
+	}
+	if again := global.Define("a"); again != a {
+		t.Errorf("Expected redefinition to reuse %v. got %v", a, again)
+	}
+
+	outerFn := NewEnclosedSymbolTable(global)
+	outerFn.Define("x")
+	innerFn := NewEnclosedSymbolTable(NewBlockSymbolTable(outerFn))
+	nested := NewEnclosedSymbolTable(innerFn)
+
+	tests := []struct {
+		table    *SymbolTable
+		name     string
+		expected Symbol
+	}{
+		{nested, "a", Symbol{"a", GlobalScope, 0}},
+		{nested, "x", Symbol{"x", FreeScope, 0}},
+		{innerFn, "x", Symbol{"x", FreeScope, 0}},
+		{outerFn, "x", Symbol{"x", LocalScope, 0}},
+	}
+	for _, tt := range tests {
+		symbol, ok := tt.table.Resolve(tt.name)
+		if !ok || symbol != tt.expected {
+			t.Errorf("Resolve(%q): expected %v. got %v", tt.name, tt.expected, symbol)
+		}
+	}
+
+	if _, ok := nested.Resolve("missing"); ok {
+		t.Errorf("Expected missing to be unresolved")
+	}
+	if !outerFn.localsCaptured || innerFn.localsCaptured {
+		t.Errorf("Expected only outerFn to have captured locals")
+	}
+	if len(nested.FreeSymbols) != 1 || nested.FreeSymbols[0].Scope != FreeScope {
+		t.Errorf("Expected nested to capture the free x of innerFn. got %v", nested.FreeSymbols)
+	}
+}
+
+func compile(t *testing.T, input string) *Bytecode {
+	p := parser.NewParser(lexer.NewLexer(input))
+	prog, ok := p.ParseProgram()
+	if !ok {
+		t.Fatalf("Could not parse %q: %s", input, p.Err())
+	}
+
+	comp := New()
+	if err := comp.Compile(prog); err != nil {
+		t.Fatalf("Could not compile %q: %s", input, err)
+	}
+	return comp.Bytecode()
+}
+
+func testInstructions(t *testing.T, name string, expected []code.Instructions, actual code.Instructions) bool {
+	concatted := code.Instructions{}
+	for _, ins := range expected {
+		concatted = append(concatted, ins...)
+	}
+
+	if actual.String() != concatted.String() {
+		t.Errorf("%s: wrong instructions.\nexpected:\n%s\ngot:\n%s", name, concatted, actual)
+		return false
+	}
+	return true
+}
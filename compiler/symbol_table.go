@@ -0,0 +1,150 @@
+package compiler
+
+type SymbolScope string
+
+const (
+	GlobalScope SymbolScope = "GLOBAL"
+	LocalScope  SymbolScope = "LOCAL"
+	FreeScope   SymbolScope = "FREE"
+)
+
+// Symbol is a resolved name: the kind of slot it lives in and its index.
+type Symbol struct {
+	Name  string
+	Scope SymbolScope
+	Index int
+}
+
+// SymbolTable maps names to slots for one scope. The global table and the
+// table of each function own their slots; a block table only adds names,
+// allocating them from the slots of the function it is in, so shadowing a
+// name in a block never reuses the outer slot.
+type SymbolTable struct {
+	Outer *SymbolTable
+
+	// FreeSymbols are the symbols of the enclosing scopes this function
+	// captures, in the order of its free variable slots.
+	FreeSymbols []Symbol
+
+	store    map[string]Symbol
+	function *SymbolTable
+
+	// set on function and global tables only
+	names          []string
+	localsCaptured bool
+	captured       map[int]bool // slots captured by inner functions
+}
+
+// NewSymbolTable creates the global table.
+func NewSymbolTable() *SymbolTable {
+	table := &SymbolTable{store: map[string]Symbol{}, captured: map[int]bool{}}
+	table.function = table
+	return table
+}
+
+// NewEnclosedSymbolTable creates the table of a function defined in outer.
+func NewEnclosedSymbolTable(outer *SymbolTable) *SymbolTable {
+	table := NewSymbolTable()
+	table.Outer = outer
+	return table
+}
+
+// NewBlockSymbolTable creates the table of a block inside outer.
+func NewBlockSymbolTable(outer *SymbolTable) *SymbolTable {
+	return &SymbolTable{
+		Outer:    outer,
+		store:    map[string]Symbol{},
+		function: outer.function,
+	}
+}
+
+// Define binds name in this scope. Defining a name again in the same scope
+// reuses its slot, as `let` overwrites the binding in the evaluator.
+func (st *SymbolTable) Define(name string) Symbol {
+	if symbol, ok := st.store[name]; ok && symbol.Scope != FreeScope {
+		return symbol
+	}
+
+	function := st.function
+	symbol := Symbol{Name: name, Scope: LocalScope, Index: len(function.names)}
+	if function.Outer == nil {
+		symbol.Scope = GlobalScope
+	}
+	function.names = append(function.names, name)
+
+	st.store[name] = symbol
+	return symbol
+}
+
+// Resolve looks name up through the enclosing scopes. A local of an
+// enclosing function, or a global defined in a block, becomes a free
+// variable of every function in between.
+func (st *SymbolTable) Resolve(name string) (Symbol, bool) {
+	if symbol, ok := st.store[name]; ok {
+		return symbol, true
+	}
+	if st.Outer == nil {
+		return Symbol{}, false
+	}
+
+	symbol, ok := st.Outer.Resolve(name)
+	if !ok || st.function != st || symbol.Scope == GlobalScope && !st.isBlockGlobal(symbol) {
+		return symbol, ok
+	}
+	return st.defineFree(symbol), true
+}
+
+// isBlockGlobal reports whether the global symbol was defined in a block
+// rather than at the top level. Like a local, it gets new storage each time
+// its loop comes around, so functions capture it instead of reading the
+// global slot.
+func (st *SymbolTable) isBlockGlobal(symbol Symbol) bool {
+	topLevel, ok := st.Global().store[symbol.Name]
+	return !ok || topLevel.Index != symbol.Index
+}
+
+func (st *SymbolTable) defineFree(original Symbol) Symbol {
+	switch original.Scope {
+	case LocalScope:
+		st.Outer.function.localsCaptured = true
+		st.Outer.function.captured[original.Index] = true
+	case GlobalScope:
+		st.Outer.function.captured[original.Index] = true
+	}
+	st.FreeSymbols = append(st.FreeSymbols, original)
+
+	symbol := Symbol{Name: original.Name, Scope: FreeScope, Index: len(st.FreeSymbols) - 1}
+	st.store[original.Name] = symbol
+	return symbol
+}
+
+// capturedSince reports whether an inner function captures one of the slots
+// of the function or program this table belongs to, from start on.
+func (st *SymbolTable) capturedSince(start int) bool {
+	for index := range st.function.captured {
+		if index >= start {
+			return true
+		}
+	}
+	return false
+}
+
+// Global returns the outermost table.
+func (st *SymbolTable) Global() *SymbolTable {
+	for st.Outer != nil {
+		st = st.Outer
+	}
+	return st
+}
+
+// NumDefinitions is the number of slots used by the function or program
+// this table belongs to.
+func (st *SymbolTable) NumDefinitions() int {
+	return len(st.function.names)
+}
+
+// Names lists the names of the slots of the function or program this table
+// belongs to, by index.
+func (st *SymbolTable) Names() []string {
+	return st.function.names
+}
@@ -0,0 +1,512 @@
+package gorilla
+
+import (
+	"fmt"
+	"gorilla/eval"
+	"gorilla/object"
+	"math"
+	"math/big"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+var (
+	objectType = reflect.TypeOf((*object.Object)(nil)).Elem()
+	errorType  = reflect.TypeOf((*error)(nil)).Elem()
+	bigIntType = reflect.TypeOf((*big.Int)(nil))
+)
+
+// ToObject converts a Go value to a Gorilla value:
+//
+//   - nil, and nil pointers and interfaces, become None
+//   - bools, integers, floats and strings become BOOL, INT, FLOAT and
+//     STRING; integers that do not fit in an int64, and *big.Int, become
+//     BIGINT
+//   - slices and arrays become arrays
+//   - maps become hashes, in the order of their sorted keys
+//   - structs become hashes from the names of their exported fields; the
+//     tag `gorilla:"name"` renames a field and `gorilla:"-"` leaves it out
+//   - functions become builtins, as with Interpreter.Register
+//   - an object.Object is kept as is
+func ToObject(value any) (object.Object, error) {
+	return toObject(reflect.ValueOf(value), map[visit]bool{})
+}
+
+// visit identifies a pointer, map or slice toObject is inside of, so that
+// a value containing itself is reported rather than converted forever. A
+// slice is told apart from the shorter slices of the same array by its
+// length.
+type visit struct {
+	ptr uintptr
+	typ reflect.Type
+	len int
+}
+
+func toObject(v reflect.Value, visiting map[visit]bool) (object.Object, error) {
+	if !v.IsValid() {
+		return eval.NONE, nil
+	}
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return eval.NONE, nil
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Map, reflect.Slice:
+		key := visit{v.Pointer(), v.Type(), 0}
+		if v.Kind() == reflect.Slice {
+			key.len = v.Len()
+		}
+		if key.ptr != 0 {
+			if visiting[key] {
+				return nil, fmt.Errorf("cannot convert %s that contains itself", v.Type())
+			}
+			visiting[key] = true
+			defer delete(visiting, key)
+		}
+	}
+	if v.Type() == bigIntType {
+		return object.NewInteger(new(big.Int).Set(v.Interface().(*big.Int))), nil
+	}
+	if v.Type().Implements(objectType) && v.CanInterface() {
+		return v.Interface().(object.Object), nil
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			return eval.TRUE, nil
+		}
+		return eval.FALSE, nil
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &object.Int{Value: v.Int()}, nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return object.NewInteger(new(big.Int).SetUint64(v.Uint())), nil
+
+	case reflect.Float32, reflect.Float64:
+		return &object.Float{Value: v.Float()}, nil
+
+	case reflect.String:
+		return &object.String{Value: v.String()}, nil
+
+	case reflect.Slice, reflect.Array:
+		elements := make([]object.Object, v.Len())
+		for i := range elements {
+			element, err := toObject(v.Index(i), visiting)
+			if err != nil {
+				return nil, err
+			}
+			elements[i] = element
+		}
+		return &object.Array{Elements: elements}, nil
+
+	case reflect.Map:
+		hashObj := object.NewHash()
+		for _, key := range sortedKeys(v) {
+			if err := setPair(hashObj, key, v.MapIndex(key), visiting); err != nil {
+				return nil, err
+			}
+		}
+		return hashObj, nil
+
+	case reflect.Struct:
+		hashObj := object.NewHash()
+		for i := 0; i < v.NumField(); i++ {
+			name, ok := fieldName(v.Type().Field(i))
+			if !ok {
+				continue
+			}
+			if err := setPair(hashObj, reflect.ValueOf(name), v.Field(i), visiting); err != nil {
+				return nil, err
+			}
+		}
+		return hashObj, nil
+
+	case reflect.Pointer, reflect.Interface:
+		return toObject(v.Elem(), visiting)
+
+	case reflect.Func:
+		name := "function"
+		if fn := runtime.FuncForPC(v.Pointer()); fn != nil {
+			name = fn.Name()[strings.LastIndex(fn.Name(), "/")+1:]
+		}
+		return hostFunction(name, v)
+
+	default:
+		return nil, fmt.Errorf("cannot convert %s to a Gorilla value", v.Type())
+	}
+}
+
+func setPair(hashObj *object.Hash, key reflect.Value, value reflect.Value, visiting map[visit]bool) error {
+	keyObj, err := toObject(key, visiting)
+	if err != nil {
+		return err
+	}
+	hashKey, ok := keyObj.(object.Hashable)
+	if !ok {
+		return fmt.Errorf("unusable as hash key: %s", keyObj.GetType())
+	}
+
+	valueObj, err := toObject(value, visiting)
+	if err != nil {
+		return err
+	}
+	hashObj.Set(hashKey, valueObj)
+	return nil
+}
+
+// sortedKeys returns the keys of the map v in a stable order, since Go
+// does not keep one.
+func sortedKeys(v reflect.Value) []reflect.Value {
+	keys := v.MapKeys()
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		for a.Kind() == reflect.Interface && !a.IsNil() {
+			a = a.Elem()
+		}
+		for b.Kind() == reflect.Interface && !b.IsNil() {
+			b = b.Elem()
+		}
+		if a.Kind() != b.Kind() {
+			return a.Kind() < b.Kind()
+		}
+
+		switch a.Kind() {
+		case reflect.String:
+			return a.String() < b.String()
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return a.Int() < b.Int()
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			return a.Uint() < b.Uint()
+		case reflect.Float32, reflect.Float64:
+			return a.Float() < b.Float()
+		case reflect.Bool:
+			return !a.Bool() && b.Bool()
+		default:
+			return fmt.Sprint(a) < fmt.Sprint(b)
+		}
+	})
+	return keys
+}
+
+// fieldName returns the key of a struct field in a hash, and false if the
+// field is left out.
+func fieldName(field reflect.StructField) (string, bool) {
+	if !field.IsExported() {
+		return "", false
+	}
+	tag := field.Tag.Get("gorilla")
+	if tag == "-" {
+		return "", false
+	}
+	if tag != "" {
+		return tag, true
+	}
+	return field.Name, true
+}
+
+// FromObject stores the Gorilla value obj in the Go value target points
+// to, converting it as ToObject would in reverse. Numbers may be stored in
+// any numeric type they fit in, None sets pointers, slices and maps to nil,
+// and a hash fills the fields of a struct it has a key for. A target of
+// type object.Object receives obj as is. A target of type any receives
+// nil, bool, int64, *big.Int, float64, string, []any, a map[string]any for
+// hashes with only string keys and a map[any]any for others, or obj as is
+// for functions.
+func FromObject(obj object.Object, target any) error {
+	v := reflect.ValueOf(target)
+	if v.Kind() != reflect.Pointer || v.IsNil() {
+		return fmt.Errorf("target must be a non-nil pointer, got %T", target)
+	}
+	return fromObject(obj, v.Elem())
+}
+
+func fromObject(obj object.Object, v reflect.Value) error {
+	if v.Type() == objectType {
+		v.Set(reflect.ValueOf(obj))
+		return nil
+	}
+	if v.Kind() == reflect.Interface && v.NumMethod() == 0 {
+		if value := toGo(obj); value != nil {
+			v.Set(reflect.ValueOf(value))
+		} else {
+			v.SetZero()
+		}
+		return nil
+	}
+	if reflect.TypeOf(obj).AssignableTo(v.Type()) {
+		v.Set(reflect.ValueOf(obj))
+		return nil
+	}
+
+	_, isNone := obj.(*object.None)
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Slice, reflect.Map:
+		if isNone {
+			v.SetZero()
+			return nil
+		}
+	}
+
+	if v.Type() == bigIntType {
+		switch obj := obj.(type) {
+		case *object.Int:
+			v.Set(reflect.ValueOf(big.NewInt(obj.Value)))
+			return nil
+		case *object.BigInt:
+			v.Set(reflect.ValueOf(new(big.Int).Set(obj.Value)))
+			return nil
+		}
+		return conversionError(obj, v)
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		boolObj, ok := obj.(*object.Bool)
+		if !ok {
+			return conversionError(obj, v)
+		}
+		v.SetBool(boolObj.Value)
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		intObj, ok := obj.(*object.Int)
+		if !ok || v.OverflowInt(intObj.Value) {
+			return conversionError(obj, v)
+		}
+		v.SetInt(intObj.Value)
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		var value *big.Int
+		switch obj := obj.(type) {
+		case *object.Int:
+			value = big.NewInt(obj.Value)
+		case *object.BigInt:
+			value = obj.Value
+		}
+		if value == nil || value.Sign() < 0 || !value.IsUint64() || v.OverflowUint(value.Uint64()) {
+			return conversionError(obj, v)
+		}
+		v.SetUint(value.Uint64())
+
+	case reflect.Float32, reflect.Float64:
+		var value float64
+		switch obj := obj.(type) {
+		case *object.Float:
+			value = obj.Value
+		case *object.Int:
+			value = float64(obj.Value)
+		case *object.BigInt:
+			value, _ = new(big.Float).SetInt(obj.Value).Float64()
+		default:
+			return conversionError(obj, v)
+		}
+		if v.OverflowFloat(value) && !math.IsInf(value, 0) {
+			return conversionError(obj, v)
+		}
+		v.SetFloat(value)
+
+	case reflect.String:
+		strObj, ok := obj.(*object.String)
+		if !ok {
+			return conversionError(obj, v)
+		}
+		v.SetString(strObj.Value)
+
+	case reflect.Slice, reflect.Array:
+		arrObj, ok := obj.(*object.Array)
+		if !ok {
+			return conversionError(obj, v)
+		}
+		if v.Kind() == reflect.Slice {
+			v.Set(reflect.MakeSlice(v.Type(), len(arrObj.Elements), len(arrObj.Elements)))
+		} else if v.Len() != len(arrObj.Elements) {
+			return fmt.Errorf("cannot convert ARRAY of length %d to %s", len(arrObj.Elements), v.Type())
+		}
+		for i, element := range arrObj.Elements {
+			if err := fromObject(element, v.Index(i)); err != nil {
+				return err
+			}
+		}
+
+	case reflect.Map:
+		hashObj, ok := obj.(*object.Hash)
+		if !ok {
+			return conversionError(obj, v)
+		}
+		mapValue := reflect.MakeMapWithSize(v.Type(), hashObj.Len())
+		for _, pair := range hashObj.GetPairs() {
+			key := reflect.New(v.Type().Key()).Elem()
+			if err := fromObject(pair.Key, key); err != nil {
+				return err
+			}
+			value := reflect.New(v.Type().Elem()).Elem()
+			if err := fromObject(pair.Value, value); err != nil {
+				return err
+			}
+			mapValue.SetMapIndex(key, value)
+		}
+		v.Set(mapValue)
+
+	case reflect.Struct:
+		hashObj, ok := obj.(*object.Hash)
+		if !ok {
+			return conversionError(obj, v)
+		}
+		for i := 0; i < v.NumField(); i++ {
+			name, ok := fieldName(v.Type().Field(i))
+			if !ok {
+				continue
+			}
+			value, ok := hashObj.Get(&object.String{Value: name})
+			if !ok {
+				continue
+			}
+			if err := fromObject(value, v.Field(i)); err != nil {
+				return fmt.Errorf("field %s: %w", name, err)
+			}
+		}
+
+	case reflect.Pointer:
+		elem := reflect.New(v.Type().Elem())
+		if err := fromObject(obj, elem.Elem()); err != nil {
+			return err
+		}
+		v.Set(elem)
+
+	default:
+		return conversionError(obj, v)
+	}
+	return nil
+}
+
+func conversionError(obj object.Object, v reflect.Value) error {
+	return fmt.Errorf("cannot convert %s to %s", obj.GetType(), v.Type())
+}
+
+// toGo converts obj to the natural Go type, as documented on FromObject.
+func toGo(obj object.Object) any {
+	switch obj := obj.(type) {
+	case *object.None:
+		return nil
+	case *object.Bool:
+		return obj.Value
+	case *object.Int:
+		return obj.Value
+	case *object.BigInt:
+		return new(big.Int).Set(obj.Value)
+	case *object.Float:
+		return obj.Value
+	case *object.String:
+		return obj.Value
+
+	case *object.Array:
+		elements := make([]any, len(obj.Elements))
+		for i, element := range obj.Elements {
+			elements[i] = toGo(element)
+		}
+		return elements
+
+	case *object.Hash:
+		pairs := obj.GetPairs()
+		onlyStrings := true
+		for _, pair := range pairs {
+			if pair.Key.GetType() != object.STRING {
+				onlyStrings = false
+				break
+			}
+		}
+
+		if onlyStrings {
+			hash := make(map[string]any, len(pairs))
+			for _, pair := range pairs {
+				hash[pair.Key.(*object.String).Value] = toGo(pair.Value)
+			}
+			return hash
+		}
+		hash := make(map[any]any, len(pairs))
+		for _, pair := range pairs {
+			key := toGo(pair.Key)
+			if bigValue, ok := key.(*big.Int); ok {
+				key = bigValue.String() // a pointer would only compare by identity
+			}
+			hash[key] = toGo(pair.Value)
+		}
+		return hash
+
+	default:
+		return obj
+	}
+}
+
+// hostFunction wraps the Go function fn as a builtin named name, as
+// described on Interpreter.Register.
+func hostFunction(name string, fn reflect.Value) (*object.Builtin, error) {
+	fnType := fn.Type()
+	switch {
+	case fnType.NumOut() > 2,
+		fnType.NumOut() == 2 && fnType.Out(1) != errorType:
+		return nil, fmt.Errorf("%s: results must be (), (T), (error) or (T, error), got %s", name, fnType)
+	}
+	returnsError := fnType.NumOut() > 0 && fnType.Out(fnType.NumOut()-1) == errorType
+	numParams := fnType.NumIn()
+
+	call := func(args ...object.Object) (result object.Object) {
+		switch {
+		case fnType.IsVariadic() && len(args) < numParams-1:
+			return &object.Error{Message: fmt.Sprintf(
+				"wrong number of arguments: expected at least %d, got %d", numParams-1, len(args),
+			)}
+		case !fnType.IsVariadic() && len(args) != numParams:
+			return &object.Error{Message: fmt.Sprintf(
+				"wrong number of arguments: expected %d, got %d", numParams, len(args),
+			)}
+		}
+
+		in := make([]reflect.Value, len(args))
+		for i, arg := range args {
+			var paramType reflect.Type
+			if fnType.IsVariadic() && i >= numParams-1 {
+				paramType = fnType.In(numParams - 1).Elem()
+			} else {
+				paramType = fnType.In(i)
+			}
+
+			in[i] = reflect.New(paramType).Elem()
+			if err := fromObject(arg, in[i]); err != nil {
+				return &object.Error{Message: fmt.Sprintf("argument %d to %s: %s", i+1, name, err)}
+			}
+		}
+
+		// a panic in the host must not take down the program running it
+		defer func() {
+			if r := recover(); r != nil {
+				result = &object.Error{Message: fmt.Sprintf("%s panicked: %v", name, r)}
+			}
+		}()
+		out := fn.Call(in)
+
+		if returnsError {
+			if err, _ := out[len(out)-1].Interface().(error); err != nil {
+				return &object.Error{Message: err.Error()}
+			}
+			out = out[:len(out)-1]
+		}
+		if len(out) == 0 {
+			return eval.NONE
+		}
+
+		value, err := toObject(out[0], map[visit]bool{})
+		if err != nil {
+			return &object.Error{Message: fmt.Sprintf("result of %s: %s", name, err)}
+		}
+		return value
+	}
+
+	return &object.Builtin{Name: name, Fn: call}, nil
+}
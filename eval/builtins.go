@@ -0,0 +1,372 @@
+package eval
+
+import (
+	"fmt"
+	"gorilla/object"
+	"io"
+	"math"
+	"math/big"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+// Stdout is where print writes.
+var Stdout io.Writer = os.Stdout
+
+// builtins are resolved after the environment, so a user binding with the
+// same name shadows them.
+var builtins = map[string]*object.Builtin{
+	"print":  {Name: "print", Fn: builtinPrint},
+	"len":    {Name: "len", Fn: builtinLen},
+	"type":   {Name: "type", Fn: builtinType},
+	"str":    {Name: "str", Fn: builtinStr},
+	"int":    {Name: "int", Fn: builtinInt},
+	"first":  {Name: "first", Fn: builtinFirst},
+	"last":   {Name: "last", Fn: builtinLast},
+	"rest":   {Name: "rest", Fn: builtinRest, Cost: arrayCost(-1)},
+	"push":   {Name: "push", Fn: builtinPush, Cost: arrayCost(1)},
+	"range":  {Name: "range", Fn: builtinRange, Cost: rangeCost},
+	"keys":   {Name: "keys", Fn: builtinKeys, Cost: arrayCost(0)},
+	"values": {Name: "values", Fn: builtinValues, Cost: arrayCost(0)},
+}
+
+// newBuiltinError creates an error without a location; evalFunctionCall
+// attaches the span of the call.
+func newBuiltinError(format string, args ...any) *object.Error {
+	return &object.Error{Message: fmt.Sprintf(format, args...)}
+}
+
+// checkArity reports a call with fewer than min or more than max arguments.
+func checkArity(args []object.Object, min, max int) *object.Error {
+	if len(args) >= min && len(args) <= max {
+		return nil
+	}
+	if min == max {
+		return newBuiltinError("wrong number of arguments: expected %d, got %d", min, len(args))
+	}
+	return newBuiltinError("wrong number of arguments: expected %d to %d, got %d", min, max, len(args))
+}
+
+// arrayArgument returns args[0] of the builtin name, which must be an array.
+func arrayArgument(name string, args []object.Object) (*object.Array, *object.Error) {
+	arrObj, ok := args[0].(*object.Array)
+	if !ok {
+		return nil, newBuiltinError("argument to %s must be ARRAY, got %s", name, args[0].GetType())
+	}
+	return arrObj, nil
+}
+
+// hashArgument returns args[0] of the builtin name, which must be a hash.
+func hashArgument(name string, args []object.Object) (*object.Hash, *object.Error) {
+	hashObj, ok := args[0].(*object.Hash)
+	if !ok {
+		return nil, newBuiltinError("argument to %s must be HASH, got %s", name, args[0].GetType())
+	}
+	return hashObj, nil
+}
+
+// arrayCost returns the cost of a builtin that builds an array from the
+// array or hash it is given, with extra elements more or fewer. Invalid
+// arguments cost nothing, the builtin rejects them.
+func arrayCost(extra int) func(args ...object.Object) int64 {
+	return func(args ...object.Object) int64 {
+		if len(args) == 0 {
+			return 0
+		}
+		length := 0
+		switch arg := args[0].(type) {
+		case *object.Array:
+			length = len(arg.Elements) + extra
+		case *object.Hash:
+			length = arg.Len() + extra
+		default:
+			return 0
+		}
+		if length < 0 {
+			return 0
+		}
+		return 1 + int64(length)
+	}
+}
+
+// toString converts obj for print and str: strings are kept as they are,
+// anything else is written like the REPL shows it.
+func toString(obj object.Object) string {
+	if strObj, ok := obj.(*object.String); ok {
+		return strObj.Value
+	}
+	return obj.Inspect()
+}
+
+func builtinPrint(args ...object.Object) object.Object {
+	return Print(Stdout, args...)
+}
+
+// Print writes args to out as the print builtin does: separated by spaces
+// and followed by a newline. It returns None.
+func Print(out io.Writer, args ...object.Object) object.Object {
+	words := make([]string, len(args))
+	for i, arg := range args {
+		words[i] = toString(arg)
+	}
+	fmt.Fprintln(out, strings.Join(words, " "))
+	return NONE
+}
+
+func builtinLen(args ...object.Object) object.Object {
+	if errObj := checkArity(args, 1, 1); errObj != nil {
+		return errObj
+	}
+
+	switch arg := args[0].(type) {
+	case *object.String:
+		return &object.Int{Value: int64(utf8.RuneCountInString(arg.Value))}
+	case *object.Array:
+		return &object.Int{Value: int64(len(arg.Elements))}
+	case *object.Hash:
+		return &object.Int{Value: int64(arg.Len())}
+	default:
+		return newBuiltinError("argument to len not supported, got %s", arg.GetType())
+	}
+}
+
+// builtinType returns the name of the type of its argument, as in error
+// messages.
+func builtinType(args ...object.Object) object.Object {
+	if errObj := checkArity(args, 1, 1); errObj != nil {
+		return errObj
+	}
+	// a big integer is an implementation detail: to programs it is an INT
+	if args[0].GetType() == object.BIGINT {
+		return &object.String{Value: object.INT}
+	}
+	return &object.String{Value: string(args[0].GetType())}
+}
+
+func builtinStr(args ...object.Object) object.Object {
+	if errObj := checkArity(args, 1, 1); errObj != nil {
+		return errObj
+	}
+	if strObj, ok := args[0].(*object.String); ok {
+		return strObj
+	}
+	return &object.String{Value: toString(args[0])}
+}
+
+// builtinInt converts a number, bool or string to an integer. Floats are
+// truncated towards zero, and strings are read like integer literals.
+func builtinInt(args ...object.Object) object.Object {
+	if errObj := checkArity(args, 1, 1); errObj != nil {
+		return errObj
+	}
+
+	switch arg := args[0].(type) {
+	case *object.Int, *object.BigInt:
+		return arg
+	case *object.Bool:
+		if arg.Value {
+			return &object.Int{Value: 1}
+		}
+		return &object.Int{Value: 0}
+	case *object.Float:
+		if math.IsNaN(arg.Value) || math.IsInf(arg.Value, 0) {
+			return newBuiltinError("cannot convert %s to int", arg.Inspect())
+		}
+		value, _ := big.NewFloat(arg.Value).Int(nil)
+		return object.NewInteger(value)
+	case *object.String:
+		value, ok := new(big.Int).SetString(strings.TrimSpace(arg.Value), 0)
+		if !ok {
+			return newBuiltinError("invalid literal for int: %s", arg.Inspect())
+		}
+		return object.NewInteger(value)
+	default:
+		return newBuiltinError("argument to int not supported, got %s", arg.GetType())
+	}
+}
+
+func builtinFirst(args ...object.Object) object.Object {
+	if errObj := checkArity(args, 1, 1); errObj != nil {
+		return errObj
+	}
+	arrObj, errObj := arrayArgument("first", args)
+	if errObj != nil {
+		return errObj
+	}
+
+	if len(arrObj.Elements) == 0 {
+		return NONE
+	}
+	return arrObj.Elements[0]
+}
+
+func builtinLast(args ...object.Object) object.Object {
+	if errObj := checkArity(args, 1, 1); errObj != nil {
+		return errObj
+	}
+	arrObj, errObj := arrayArgument("last", args)
+	if errObj != nil {
+		return errObj
+	}
+
+	if len(arrObj.Elements) == 0 {
+		return NONE
+	}
+	return arrObj.Elements[len(arrObj.Elements)-1]
+}
+
+// builtinRest returns a new array of every element but the first, or None
+// for an empty array.
+func builtinRest(args ...object.Object) object.Object {
+	if errObj := checkArity(args, 1, 1); errObj != nil {
+		return errObj
+	}
+	arrObj, errObj := arrayArgument("rest", args)
+	if errObj != nil {
+		return errObj
+	}
+
+	if len(arrObj.Elements) == 0 {
+		return NONE
+	}
+	elements := make([]object.Object, len(arrObj.Elements)-1)
+	copy(elements, arrObj.Elements[1:])
+	return &object.Array{Elements: elements}
+}
+
+// builtinPush returns a new array with the value appended; the array itself
+// is left unchanged.
+func builtinPush(args ...object.Object) object.Object {
+	if errObj := checkArity(args, 2, 2); errObj != nil {
+		return errObj
+	}
+	arrObj, errObj := arrayArgument("push", args)
+	if errObj != nil {
+		return errObj
+	}
+
+	elements := make([]object.Object, len(arrObj.Elements), len(arrObj.Elements)+1)
+	copy(elements, arrObj.Elements)
+	return &object.Array{Elements: append(elements, args[1])}
+}
+
+// builtinRange returns the array of integers from start up to, but not
+// including, end, like Python's range(end), range(start, end) and
+// range(start, end, step).
+func builtinRange(args ...object.Object) object.Object {
+	start, end, step, errObj := rangeBounds(args)
+	if errObj != nil {
+		return errObj
+	}
+
+	length := rangeLength(start, end, step)
+	if length > maxRangeLength {
+		return newBuiltinError("range too long: %d elements, at most %d", length, maxRangeLength)
+	}
+
+	elements := make([]object.Object, length)
+	for i := range elements {
+		elements[i] = &object.Int{Value: start + int64(i)*step}
+	}
+	return &object.Array{Elements: elements}
+}
+
+func rangeCost(args ...object.Object) int64 {
+	start, end, step, errObj := rangeBounds(args)
+	if errObj != nil {
+		return 0
+	}
+	return 1 + int64(min(rangeLength(start, end, step), maxRangeLength))
+}
+
+// rangeBounds checks the arguments of range and fills in the defaults.
+func rangeBounds(args []object.Object) (start, end, step int64, errObj *object.Error) {
+	if errObj := checkArity(args, 1, 3); errObj != nil {
+		return 0, 0, 0, errObj
+	}
+
+	bounds := make([]int64, len(args))
+	for i, arg := range args {
+		intObj, ok := arg.(*object.Int)
+		if !ok {
+			return 0, 0, 0, newBuiltinError("arguments to range must be INT, got %s", arg.GetType())
+		}
+		bounds[i] = intObj.Value
+	}
+
+	start, end, step = 0, bounds[0], 1
+	if len(bounds) > 1 {
+		start, end = bounds[0], bounds[1]
+	}
+	if len(bounds) > 2 {
+		step = bounds[2]
+	}
+	if step == 0 {
+		return 0, 0, 0, newBuiltinError("range step cannot be zero")
+	}
+	return start, end, step, nil
+}
+
+// maxRangeLength bounds the arrays built by range, so a large bound is an
+// error rather than running the host out of memory.
+const maxRangeLength = 1 << 22
+
+// rangeLength counts the values from start up to, or down to, end by step,
+// without overflowing.
+func rangeLength(start, end, step int64) uint64 {
+	var distance, stride uint64
+	switch {
+	case step > 0 && start < end:
+		distance, stride = uint64(end-start), uint64(step)
+	case step < 0 && start > end:
+		distance, stride = uint64(start-end), uint64(-step)
+	default:
+		return 0
+	}
+	length := distance / stride
+	if distance%stride != 0 {
+		length++
+	}
+	return length
+}
+
+// builtinKeys returns the keys of a hash in insertion order.
+func builtinKeys(args ...object.Object) object.Object {
+	if errObj := checkArity(args, 1, 1); errObj != nil {
+		return errObj
+	}
+	hashObj, errObj := hashArgument("keys", args)
+	if errObj != nil {
+		return errObj
+	}
+
+	elements := make([]object.Object, 0, hashObj.Len())
+	for _, pair := range hashObj.GetPairs() {
+		elements = append(elements, pair.Key)
+	}
+	return &object.Array{Elements: elements}
+}
+
+// builtinValues returns the values of a hash in insertion order.
+func builtinValues(args ...object.Object) object.Object {
+	if errObj := checkArity(args, 1, 1); errObj != nil {
+		return errObj
+	}
+	hashObj, errObj := hashArgument("values", args)
+	if errObj != nil {
+		return errObj
+	}
+
+	elements := make([]object.Object, 0, hashObj.Len())
+	for _, pair := range hashObj.GetPairs() {
+		elements = append(elements, pair.Value)
+	}
+	return &object.Array{Elements: elements}
+}
+
+// LookupBuiltin returns the builtin registered under name.
+func LookupBuiltin(name string) (*object.Builtin, bool) {
+	builtin, ok := builtins[name]
+	return builtin, ok
+}
@@ -0,0 +1,216 @@
+package eval
+
+import (
+	"context"
+	"fmt"
+	"gorilla/ast"
+	"gorilla/object"
+)
+
+var (
+	NONE  = &object.None{}
+	TRUE  = &object.Bool{Value: true}
+	FALSE = &object.Bool{Value: false}
+
+	BREAK    = &object.Break{}
+	CONTINUE = &object.Continue{}
+)
+
+// EvalProgram evaluates every top-level statement in order and returns the
+// value of the last one. A top-level return stops evaluation early. The run
+// is charged to the budget of env; without one, it only gets the default
+// recursion limit.
+func EvalProgram(prog *ast.Program, env *object.Environment) object.Object {
+	if env.Budget() == nil {
+		env.SetBudget(object.NewBudget(context.Background(), object.Limits{}))
+	}
+
+	result := evalStatements(prog, env)
+	if isError(result) {
+		return result
+	}
+	// the run may have overrun its time since the last check
+	if errObj := env.Budget().Check(); errObj != nil {
+		return errObj
+	}
+	return result
+}
+
+func evalStatements(prog *ast.Program, env *object.Environment) object.Object {
+	var result object.Object = NONE
+	for _, stmt := range prog.Statements {
+		result = Eval(stmt, env)
+
+		switch result := result.(type) {
+		case *object.ReturnValue:
+			return result.Value
+		case *object.Error:
+			return result
+		}
+	}
+	return result
+}
+
+func Eval(node ast.Node, env *object.Environment) object.Object {
+	if errObj := env.Budget().Step(); errObj != nil {
+		return withSpan(errObj, node)
+	}
+
+	switch node := node.(type) {
+	// statements
+	case *ast.LetStatement:
+		return evalLetStatement(node, env)
+
+	case *ast.ReturnStatement:
+		return evalReturnStatement(node, env)
+
+	case *ast.ExpressionStatement:
+		return Eval(node.Expression, env)
+
+	case *ast.BlockStatement:
+		return evalBlockStatement(node, object.NewEnclosedEnvironment(env))
+
+	case *ast.IfStatement:
+		return evalIfStatement(node, env)
+
+	case *ast.ElseStatement:
+		return Eval(node.Statement, env)
+
+	case *ast.WhileStatement:
+		return evalWhileStatement(node, env)
+
+	case *ast.ForStatement:
+		return evalForStatement(node, env)
+
+	case *ast.Assignment:
+		return evalAssignment(node, env)
+
+	case *ast.BreakStatement:
+		return BREAK
+
+	case *ast.ContinueStatement:
+		return CONTINUE
+
+	// expressions
+	case *ast.IdentifierExpression:
+		return evalIdentifier(node, env)
+
+	case *ast.BoolLiteral:
+		return nativeBoolToBoolObject(node.GetValue())
+
+	case *ast.IntegerLiteral:
+		if bigValue := node.GetBigValue(); bigValue != nil {
+			return allocate(env, node, &object.BigInt{Value: bigValue})
+		}
+		return allocate(env, node, &object.Int{Value: node.GetValue()})
+
+	case *ast.FloatLiteral:
+		return allocate(env, node, &object.Float{Value: node.GetValue()})
+
+	case *ast.StringLiteral:
+		return allocate(env, node, &object.String{Value: node.GetValue()})
+
+	case *ast.ArrayLiteral:
+		elements, errObj := evalExpressions(node.Elements, env)
+		if errObj != nil {
+			return errObj
+		}
+		return allocate(env, node, &object.Array{Elements: elements})
+
+	case *ast.HashLiteral:
+		return allocate(env, node, evalHashLiteral(node, env))
+
+	case *ast.IndexExpression:
+		return evalIndexExpression(node, env)
+
+	case *ast.SliceExpression:
+		return allocate(env, node, evalSliceExpression(node, env))
+
+	case *ast.Prefix:
+		return allocate(env, node, evalPrefix(node, env))
+
+	case *ast.Infix:
+		return allocate(env, node, evalInfix(node, env))
+
+	case *ast.Logical:
+		return evalLogical(node, env)
+
+	case *ast.Comparison:
+		return evalComparison(node, env)
+
+	case *ast.Trinary:
+		return evalTrinary(node, env)
+
+	case *ast.Match:
+		return evalMatch(node, env)
+
+	case *ast.FunctionLiteral:
+		return allocate(env, node, &object.Function{
+			Parameters: node.Signiture,
+			Body:       node.Body,
+			Env:        env,
+		})
+
+	case *ast.FunctionCall:
+		return evalFunctionCall(node, env)
+
+	default:
+		return newError(node, "cannot evaluate %T", node)
+	}
+}
+
+// newError creates a runtime error located at node.
+func newError(node ast.Node, format string, args ...any) *object.Error {
+	return &object.Error{
+		Message: fmt.Sprintf(format, args...),
+		Span:    node.GetSpan(),
+	}
+}
+
+func isError(obj object.Object) bool {
+	return obj != nil && obj.GetType() == object.ERROR
+}
+
+func nativeBoolToBoolObject(value bool) *object.Bool {
+	if value {
+		return TRUE
+	}
+	return FALSE
+}
+
+// allocate charges the objects created for result to the budget of env.
+// Errors and the shared None, True and False cost nothing.
+func allocate(env *object.Environment, node ast.Node, result object.Object) object.Object {
+	if errObj := env.Budget().Alloc(allocationSize(result)); errObj != nil {
+		return withSpan(errObj, node)
+	}
+	return result
+}
+
+// allocationSize counts an array or hash as one object plus its length, and
+// a string or big integer as one object plus a word of its contents.
+func allocationSize(obj object.Object) int64 {
+	switch obj := obj.(type) {
+	case *object.None, *object.Bool, *object.Error:
+		return 0
+	case *object.Array:
+		return 1 + int64(len(obj.Elements))
+	case *object.Hash:
+		return 1 + int64(obj.Len())
+	case *object.String:
+		return 1 + int64(len(obj.Value)/8)
+	case *object.BigInt:
+		return 1 + int64(len(obj.Value.Bits()))
+	default:
+		return 1
+	}
+}
+
+// withSpan locates result at node if it is an error without a location, as
+// returned by the operators and builtins.
+func withSpan(result object.Object, node ast.Node) object.Object {
+	if errObj, ok := result.(*object.Error); ok && !errObj.Span.Start.IsValid() {
+		errObj.Span = node.GetSpan()
+	}
+	return result
+}
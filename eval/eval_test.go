@@ -0,0 +1,1106 @@
+package eval
+
+import (
+	"bytes"
+	"context"
+	"gorilla/lexer"
+	"gorilla/object"
+	"gorilla/parser"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEvalIntegerExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"return 5;", 5},
+		{"return -5;", -5},
+		{"return --5;", 5},
+		{"return 5 + 5 + 5 + 5 - 10;", 10},
+		{"return 2 * 2 * 2 * 2 * 2;", 32},
+		{"return -50 + 100 + -50;", 0},
+		{"return 20 + 2 * -10;", 0},
+		{"return 50 / 2 * 2 + 10;", 60},
+		{"return 3 * (3 * 3) + 10;", 37},
+		{"return (5 + 10 * 2 + 15 / 3) * 2 + -10;", 50},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestEvalNumberLiterals(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"0xFF;", "255"},
+		{"0o17;", "15"},
+		{"0b1010;", "10"},
+		{"1_000_000;", "1000000"},
+		{"-0x10;", "-16"},
+		{"3.14;", "3.14"},
+		{"1e9;", "1000000000.0"},
+		{".5;", "0.5"},
+		{"-2.5e-3;", "-0.0025"},
+		{"1e16;", "1e+16"},
+		{"1_000.5;", "1000.5"},
+	}
+
+	for _, tt := range tests {
+		result := testEval(t, tt.input)
+		if result.Inspect() != tt.expected {
+			t.Errorf("%s: expected %s. got %s", tt.input, tt.expected, result.Inspect())
+		}
+	}
+}
+
+func TestEvalFloatExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected float64
+	}{
+		{"1.5 + 1.5;", 3},
+		{"1 + 0.5;", 1.5},
+		{"0.5 * 4;", 2},
+		{"7 / 2.0;", 3.5},
+		{"10 - .25;", 9.75},
+		{"-(1.5);", -1.5},
+		{"2 * (1.0 + 2);", 6},
+	}
+
+	for _, tt := range tests {
+		testFloatObject(t, testEval(t, tt.input), tt.expected)
+	}
+
+	// division of two integers stays integer division
+	testIntegerObject(t, testEval(t, "7 / 2;"), 3)
+
+	boolTests := []struct {
+		input    string
+		expected bool
+	}{
+		{"1 == 1.0;", true},
+		{"1 != 1.5;", true},
+		{"0.5 < 1;", true},
+		{"2 >= 2.5;", false},
+		{"[1, 2] == [1.0, 2.0];", true},
+		{"!0.0;", true},
+		{"{1: True}[1.0];", true},
+	}
+
+	for _, tt := range boolTests {
+		testBoolObject(t, testEval(t, tt.input), tt.expected)
+	}
+
+	testErrorObject(t, testEval(t, "1.5 / 0;"), "division by zero")
+	testErrorObject(t, testEval(t, `1.5 + "a";`), "type mismatch: FLOAT + STRING")
+}
+
+func TestEvalBigIntegers(t *testing.T) {
+	tests := []struct {
+		input        string
+		expectedType object.ObjectType
+		expected     string
+	}{
+		{"9223372036854775807;", object.INT, "9223372036854775807"},
+		{"9223372036854775808;", object.BIGINT, "9223372036854775808"},
+		{"-9223372036854775808;", object.INT, "-9223372036854775808"},
+		{"0xFFFF_FFFF_FFFF_FFFF_FF;", object.BIGINT, "4722366482869645213695"},
+		{"9223372036854775807 + 1;", object.BIGINT, "9223372036854775808"},
+		{"-9223372036854775807 - 2;", object.BIGINT, "-9223372036854775809"},
+		{"4294967296 * 4294967296;", object.BIGINT, "18446744073709551616"},
+		{"-1 * -9223372036854775808;", object.BIGINT, "9223372036854775808"},
+		{"-9223372036854775808 / -1;", object.BIGINT, "9223372036854775808"},
+		{"let x = -9223372036854775807 - 1; -x;", object.BIGINT, "9223372036854775808"},
+		// results that fit again are demoted
+		{"9223372036854775808 - 1;", object.INT, "9223372036854775807"},
+		{"18446744073709551616 / 4294967296;", object.INT, "4294967296"},
+		{"-(-9223372036854775808 * -1);", object.INT, "-9223372036854775808"},
+		{"99999999999999999999 / -7;", object.BIGINT, "-14285714285714285714"},
+		{"18446744073709551616 * 0.5;", object.FLOAT, "9.223372036854776e+18"},
+	}
+
+	for _, tt := range tests {
+		result := testEval(t, tt.input)
+		if result.GetType() != tt.expectedType || result.Inspect() != tt.expected {
+			t.Errorf("%s: expected %s %s. got %s %s",
+				tt.input, tt.expectedType, tt.expected, result.GetType(), result.Inspect(),
+			)
+		}
+	}
+
+	boolTests := []struct {
+		input    string
+		expected bool
+	}{
+		{"9223372036854775808 > 9223372036854775807;", true},
+		{"9223372036854775808 == 9223372036854775807 + 1;", true},
+		{"9223372036854775808 == 9223372036854775808.0;", true},
+		{"{9223372036854775808: True}[9223372036854775807 + 1];", true},
+		{"!!(9223372036854775808 * 0);", false},
+	}
+
+	for _, tt := range boolTests {
+		testBoolObject(t, testEval(t, tt.input), tt.expected)
+	}
+
+	testErrorObject(t, testEval(t, "9223372036854775808 / 0;"), "division by zero")
+}
+
+func TestEvalArithmeticAndBitwiseOperators(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"7 % 3;", "1"},
+		// the remainder takes the sign of the divisor, as in Python
+		{"-7 % 3;", "2"},
+		{"7 % -3;", "-2"},
+		{"-7 % -3;", "-1"},
+		{"6 % -3;", "0"},
+		{"-9223372036854775808 % -1;", "0"},
+		{"7.5 % 2;", "1.5"},
+		{"-7.5 % 2;", "0.5"},
+		{"7.5 % -2;", "-0.5"},
+		{"99999999999999999999 % 10;", "9"},
+		{"-99999999999999999999 % 10;", "1"},
+		{"99999999999999999999 % -10;", "-1"},
+		{"-99999999999999999999 % -10;", "-9"},
+		{"2 ** 10;", "1024"},
+		{"2 ** 3 ** 2;", "512"},
+		{"-2 ** 2;", "-4"},
+		{"(-2) ** 3;", "-8"},
+		{"2 ** -1;", "0.5"},
+		{"4 ** 0.5;", "2.0"},
+		{"2 ** 64;", "18446744073709551616"},
+		{"1 ** 99999999999999999999;", "1"},
+		{"6 & 3;", "2"},
+		{"6 | 3;", "7"},
+		{"6 ^ 3;", "5"},
+		{"~5;", "-6"},
+		{"1 << 4;", "16"},
+		{"1 << 64;", "18446744073709551616"},
+		{"-16 >> 2;", "-4"},
+		{"-1 >> 100;", "-1"},
+		{"18446744073709551616 >> 64;", "1"},
+		{"~18446744073709551616;", "-18446744073709551617"},
+		{"18446744073709551616 | 1;", "18446744073709551617"},
+	}
+
+	for _, tt := range tests {
+		result := testEval(t, tt.input)
+		if result.Inspect() != tt.expected {
+			t.Errorf("%s: expected %s. got %s", tt.input, tt.expected, result.Inspect())
+		}
+	}
+
+	errorTests := []struct {
+		input    string
+		expected string
+	}{
+		{"1 % 0;", "division by zero"},
+		{"1.5 % 0;", "division by zero"},
+		{"18446744073709551616 % 0;", "division by zero"},
+		{"0 ** -1;", "division by zero"},
+		{"1 << -1;", "negative shift count"},
+		{"2 ** 99999999999999;", "integer result too large"},
+		{"1 << 99999999999999;", "integer result too large"},
+		{"let x = 2 ** 9000000; x * x;", "integer result too large"},
+		{"1.5 & 1;", "unknown operator: FLOAT & INT"},
+		{"~1.5;", "unknown operator: ~FLOAT"},
+		{`"a" % "b";`, "unknown operator: STRING % STRING"},
+	}
+
+	for _, tt := range errorTests {
+		testErrorObject(t, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestEvalBoolExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"return True;", true},
+		{"return !True;", false},
+		{"return !!0;", false},
+		{"return 1 < 2;", true},
+		{"return 1 >= 2;", false},
+		{"return 1 == 1;", true},
+		{"return 1 != 1;", false},
+		{"return True == False;", false},
+		{"return (1 < 2) == True;", true},
+		{"return 1 == True;", false},
+		{"return True && False;", false},
+		{"return False || 1;", true},
+		{"return 1 == 1 && 2 == 2;", true},
+		{"return 1 == 2 || 2 <= 3 && 3 >= 4;", false},
+	}
+
+	for _, tt := range tests {
+		testBoolObject(t, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestEvalShortCircuit(t *testing.T) {
+	// the right operand would fail if it was evaluated
+	testBoolObject(t, testEval(t, "False && missing;"), false)
+	testBoolObject(t, testEval(t, "0 && 1 / 0;"), false)
+	testBoolObject(t, testEval(t, `"yes" || missing();`), true)
+	testBoolObject(t, testEval(t, "let fail = fn() { return 1 / 0; }; 1 || fail();"), true)
+
+	testErrorObject(t, testEval(t, "True && 1 / 0;"), "division by zero")
+	testErrorObject(t, testEval(t, "False || missing;"), "identifier not found: missing")
+}
+
+func TestEvalChainedComparisons(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"0 <= 1 < 2;", true},
+		{"0 <= 2 < 2;", false},
+		{"3 > 2 > 1;", true},
+		{"3 > 2 > 1 > 1;", false},
+		{"1 == 1 == 1;", true},
+		{"1 == 1 != 2;", true},
+		{"1 < 2 == 2 < 3;", true},
+		// == chains like the other comparisons: 1 == 1 and 1 < 2
+		{"1 == 1 < 2;", true},
+		{"1 < 2 == True;", false},
+		{"(1 < 2) == True;", true},
+		// the chain stops at the first false comparison
+		{"2 < 1 < missing;", false},
+		{"1 < 2 < 3 < 0 < missing;", false},
+	}
+
+	for _, tt := range tests {
+		testBoolObject(t, testEval(t, tt.input), tt.expected)
+	}
+
+	testErrorObject(t, testEval(t, `1 < 2 < "a";`), "type mismatch: INT < STRING")
+}
+
+func TestEvalChainedComparisonOperandsOnce(t *testing.T) {
+	calls := 0
+	env := object.NewEnvironment()
+	env.Set("middle", &object.Builtin{Name: "middle", Fn: func(args ...object.Object) object.Object {
+		calls++
+		return &object.Int{Value: 5}
+	}})
+
+	prog, ok := parser.NewParser(lexer.NewLexer("0 < middle() < 10;")).ParseProgram()
+	if !ok {
+		t.Fatal("Could not parse the comparison")
+	}
+	testBoolObject(t, EvalProgram(prog, env), true)
+	if calls != 1 {
+		t.Errorf("Expected the middle operand to be evaluated once. got %d times", calls)
+	}
+}
+
+func TestEvalExpressionStatements(t *testing.T) {
+	testIntegerObject(t, testEval(t, "5;"), 5)
+	testIntegerObject(t, testEval(t, "let x = 2; x * 3;"), 6)
+	testIntegerObject(t, testEval(t, `
+		let counter = fn(n) { n + 1; return n; };
+		counter(3);
+	`), 3)
+	testNoneObject(t, testEval(t, "let x = 1;"))
+}
+
+func TestEvalStrings(t *testing.T) {
+	testStringObject(t, testEval(t, `"Hello World!";`), "Hello World!")
+	testStringObject(t, testEval(t, `"Hello" + " " + "World!";`), "Hello World!")
+	testStringObject(t, testEval(t, `
+		let greet = fn(name) { return "Hi, " + name; };
+		greet("\u{1F98D}");
+	`), "Hi, \U0001F98D")
+
+	boolTests := []struct {
+		input    string
+		expected bool
+	}{
+		{`"a" == "a";`, true},
+		{`"a" != "a";`, false},
+		{`"a" < "b";`, true},
+		{`"abc" >= "abd";`, false},
+		{`"a" == 1;`, false},
+		{`!"";`, true},
+	}
+	for _, tt := range boolTests {
+		testBoolObject(t, testEval(t, tt.input), tt.expected)
+	}
+
+	testIntegerObject(t, testEval(t, `len("");`), 0)
+	testIntegerObject(t, testEval(t, `len("four");`), 4)
+	testIntegerObject(t, testEval(t, `len("h\u{e9}llo");`), 5)
+	testErrorObject(t, testEval(t, `len(1);`), "argument to len not supported, got INT")
+	testErrorObject(t, testEval(t, `len("a", "b");`), "wrong number of arguments: expected 1, got 2")
+	testErrorObject(t, testEval(t, `"a" - "b";`), "unknown operator: STRING - STRING")
+	testErrorObject(t, testEval(t, `"a" + 1;`), "type mismatch: STRING + INT")
+
+	if inspect := testEval(t, `"say \"hi\"\n";`).Inspect(); inspect != `"say \"hi\"\n"` {
+		t.Errorf("Wrong String.Inspect(). got %s", inspect)
+	}
+}
+
+func TestEvalArrays(t *testing.T) {
+	arrObj, ok := testEval(t, "[1, 2 * 2, 3 + 3];").(*object.Array)
+	if !ok {
+		t.Fatalf("Expected Array object")
+	}
+	if len(arrObj.Elements) != 3 {
+		t.Fatalf("Expected 3 elements. got %d", len(arrObj.Elements))
+	}
+	testIntegerObject(t, arrObj.Elements[0], 1)
+	testIntegerObject(t, arrObj.Elements[1], 4)
+	testIntegerObject(t, arrObj.Elements[2], 6)
+
+	intTests := []struct {
+		input    string
+		expected int64
+	}{
+		{"[1, 2, 3][0];", 1},
+		{"[1, 2, 3][1 + 1];", 3},
+		{"let arr = [1, 2, 3]; arr[-1];", 3},
+		{"let arr = [1, 2, 3]; arr[-3];", 1},
+		{"let arr = [[1, 2], [3, 4]]; arr[1][0];", 3},
+		{"let first = fn(a) { return a[0]; }; first([7]);", 7},
+		{"len([1, 2, 3]);", 3},
+		{"len([]);", 0},
+	}
+	for _, tt := range intTests {
+		testIntegerObject(t, testEval(t, tt.input), tt.expected)
+	}
+
+	sliceTests := []struct {
+		input    string
+		expected string
+	}{
+		{"[1, 2, 3, 4][1:3];", "[2, 3]"},
+		{"[1, 2, 3, 4][:2];", "[1, 2]"},
+		{"[1, 2, 3, 4][2:];", "[3, 4]"},
+		{"[1, 2, 3, 4][:];", "[1, 2, 3, 4]"},
+		{"[1, 2, 3, 4][-2:];", "[3, 4]"},
+		{"[1, 2, 3, 4][:-1];", "[1, 2, 3]"},
+		{"[1, 2, 3, 4][3:1];", "[]"},
+		{"[1, 2, 3, 4][-10:10];", "[1, 2, 3, 4]"},
+		{`"h\u{e9}llo"[1:3];`, `"\u00e9l"`},
+		{`"hello"[-1];`, `"o"`},
+	}
+	for _, tt := range sliceTests {
+		result := testEval(t, tt.input)
+		expectedInspect := tt.expected
+		if strings.HasPrefix(tt.expected, `"`) {
+			value, _ := strconv.Unquote(tt.expected)
+			expectedInspect = (&object.String{Value: value}).Inspect()
+		}
+		if result.Inspect() != expectedInspect {
+			t.Errorf("%s: expected %s. got %s", tt.input, expectedInspect, result.Inspect())
+		}
+	}
+
+	testBoolObject(t, testEval(t, "[1, [2]] == [1, [2]];"), true)
+	testBoolObject(t, testEval(t, "[1, 2] == [1];"), false)
+	testBoolObject(t, testEval(t, "![];"), true)
+
+	errorTests := []struct {
+		input    string
+		expected string
+	}{
+		{"[1, 2, 3][3];", "index out of range: 3 (len 3)"},
+		{"[1, 2, 3][-4];", "index out of range: -4 (len 3)"},
+		{`[1][True];`, "index must be INT, got BOOL"},
+		{`[1]["a":];`, "slice index must be INT, got STRING"},
+		{"1[0];", "index operator not supported: INT"},
+		{"1[0:];", "slice operator not supported: INT"},
+	}
+	for _, tt := range errorTests {
+		testErrorObject(t, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestEvalHashes(t *testing.T) {
+	hashObj, ok := testEval(t, `
+		let two = "two";
+		{"one": 10 - 9, two: 1 + 1, "thr" + "ee": 6 / 2, 4: 4, True: 5, False: 6};
+	`).(*object.Hash)
+	if !ok {
+		t.Fatalf("Expected Hash object")
+	}
+
+	expectedPairs := []struct {
+		key   object.Hashable
+		value int64
+	}{
+		{&object.String{Value: "one"}, 1},
+		{&object.String{Value: "two"}, 2},
+		{&object.String{Value: "three"}, 3},
+		{&object.Int{Value: 4}, 4},
+		{TRUE, 5},
+		{FALSE, 6},
+	}
+	if hashObj.Len() != len(expectedPairs) {
+		t.Fatalf("Expected %d pairs. got %d", len(expectedPairs), hashObj.Len())
+	}
+	for i, pair := range hashObj.GetPairs() {
+		if pair.Key.HashKey() != expectedPairs[i].key.HashKey() {
+			t.Errorf("pairs[%d] - wrong key. expected %s, got %s",
+				i, expectedPairs[i].key.Inspect(), pair.Key.Inspect(),
+			)
+		}
+		testIntegerObject(t, pair.Value, expectedPairs[i].value)
+	}
+
+	intTests := []struct {
+		input    string
+		expected int64
+	}{
+		{`{"foo": 5}["foo"];`, 5},
+		{`let key = "foo"; {"foo": 5}[key];`, 5},
+		{`{5: 5}[5];`, 5},
+		{`{True: 5}[True];`, 5},
+		{`{"a": 1, "a": 2}["a"];`, 2},
+		{`len({"a": 1, "b": 2});`, 2},
+	}
+	for _, tt := range intTests {
+		testIntegerObject(t, testEval(t, tt.input), tt.expected)
+	}
+
+	testBoolObject(t, testEval(t, `{"a": 1, 2: [3]} == {2: [3], "a": 1};`), true)
+	testBoolObject(t, testEval(t, `{"a": 1} == {"a": 2};`), false)
+	testBoolObject(t, testEval(t, `!{};`), true)
+
+	if inspect := testEval(t, `{"b": 1, "a": [True]};`).Inspect(); inspect != `{"b": 1, "a": [True]}` {
+		t.Errorf("Wrong Hash.Inspect(). got %s", inspect)
+	}
+
+	errorTests := []struct {
+		input    string
+		expected string
+	}{
+		{`{"foo": 5}["bar"];`, `key not found: "bar"`},
+		{`let h = {[1]: 5};`, "unusable as hash key: ARRAY"},
+		{`{"a": 1}[{}];`, "unusable as hash key: HASH"},
+		{`let h = {fn() {}: 1};`, "unusable as hash key: FUNCTION"},
+	}
+	for _, tt := range errorTests {
+		testErrorObject(t, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestEvalSelfReferentialValues(t *testing.T) {
+	inspectTests := []struct {
+		input    string
+		expected string
+	}{
+		{"let a = [1]; a[0] = a; a;", "[[...]]"},
+		{"let a = [1]; a[0] = a; [a, a];", "[[[...]], [[...]]]"},
+		{`let h = {"k": 1}; h["k"] = h; h;`, `{"k": {...}}`},
+		{`let a = [1]; let h = {"a": a}; a[0] = h; a;`, `[{"a": [...]}]`},
+		{"let a = [1]; a[0] = a; str(a);", `"[[...]]"`},
+	}
+	for _, tt := range inspectTests {
+		if inspect := testEval(t, tt.input).Inspect(); inspect != tt.expected {
+			t.Errorf("%s - expected %s. got %s", tt.input, tt.expected, inspect)
+		}
+	}
+
+	boolTests := []struct {
+		input    string
+		expected bool
+	}{
+		{"let a = [1]; a[0] = a; a == a;", true},
+		{"let a = [1]; a[0] = a; a == [a];", true},
+		{"let a = [1]; a[0] = a; let b = [1]; b[0] = b; a == b;", false},
+		{`let h = {"k": 1}; h["k"] = h; h == {"k": h};`, true},
+		{`let h = {"k": 1}; h["k"] = h; h == {"k": 1};`, false},
+	}
+	for _, tt := range boolTests {
+		testBoolObject(t, testEval(t, tt.input), tt.expected)
+	}
+
+	errorTests := []struct {
+		input    string
+		expected string
+	}{
+		{"let a = [1]; a[0] = a; {a: 1};", "unusable as hash key: ARRAY"},
+		{`let h = {"k": 1}; h["k"] = h; h[h];`, "unusable as hash key: HASH"},
+	}
+	for _, tt := range errorTests {
+		testErrorObject(t, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestEvalBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`type(1);`, `"INT"`},
+		{`[type(2 ** 70), type(-9223372036854775808 - 1)];`, `["INT", "INT"]`},
+		{`[type(1.5), type("a"), type([]), type({}), type(first([])), type(len)];`, `["FLOAT", "STRING", "ARRAY", "HASH", "NONE", "BUILTIN"]`},
+		{`type(fn() {});`, `"FUNCTION"`},
+		{`str(12) + str("ab") + str([1, "c"]);`, `"12ab[1, \"c\"]"`},
+		{`str(2.0);`, `"2.0"`},
+		{`[int(7), int(-2.7), int(True), int(" 42 "), int("0x1f"), int("-1_000")];`, "[7, -2, 1, 42, 31, -1000]"},
+		{`int("99999999999999999999");`, "99999999999999999999"},
+		{"[first([1, 2]), last([1, 2]), rest([1, 2, 3])];", "[1, 2, [2, 3]]"},
+		{"[first([]), last([]), rest([]), rest([1])];", "[None, None, None, []]"},
+		{"let xs = [1]; let ys = push(xs, 2); [xs, ys];", "[[1], [1, 2]]"},
+		{"[range(3), range(2, 5), range(5, 0, -2), range(3, 1)];", "[[0, 1, 2], [2, 3, 4], [5, 3, 1], []]"},
+		{"range(9223372036854775806, 9223372036854775807, 9);", "[9223372036854775806]"},
+		{"range(-9223372036854775807 - 1, 0, -9223372036854775807 - 1);", "[]"},
+		{"range(9223372036854775807, -9223372036854775807 - 1, -9223372036854775807 - 1);", "[9223372036854775807, -1]"},
+		{`let h = {"b": 1, "a": [2]}; [keys(h), values(h)];`, `[["b", "a"], [1, [2]]]`},
+		{"let first = fn(xs) { return 0; }; first([1]);", "0"},
+	}
+
+	for _, tt := range tests {
+		result := testEval(t, tt.input)
+		if result.Inspect() != tt.expected {
+			t.Errorf("%s\n\texpected %s. got %s", tt.input, tt.expected, result.Inspect())
+		}
+	}
+
+	errorTests := []struct {
+		input    string
+		expected string
+	}{
+		{"type();", "wrong number of arguments: expected 1, got 0"},
+		{"push([1]);", "wrong number of arguments: expected 2, got 1"},
+		{"range();", "wrong number of arguments: expected 1 to 3, got 0"},
+		{"range(1, 2, 3, 4);", "wrong number of arguments: expected 1 to 3, got 4"},
+		{`range("3");`, "arguments to range must be INT, got STRING"},
+		{"range(1, 5, 0);", "range step cannot be zero"},
+		{"range(9223372036854775807);", "range too long: 9223372036854775807 elements, at most 4194304"},
+		{"range(-9223372036854775807 - 1, 9223372036854775807, 4);", "range too long: 4611686018427387904 elements, at most 4194304"},
+		{`int("1.5");`, `invalid literal for int: "1.5"`},
+		{"int([]);", "argument to int not supported, got ARRAY"},
+		{"first(1);", "argument to first must be ARRAY, got INT"},
+		{`rest("ab");`, "argument to rest must be ARRAY, got STRING"},
+		{"push({}, 1);", "argument to push must be ARRAY, got HASH"},
+		{"keys([1]);", "argument to keys must be HASH, got ARRAY"},
+		{"values(1);", "argument to values must be HASH, got INT"},
+	}
+
+	for _, tt := range errorTests {
+		testErrorObject(t, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestEvalPrint(t *testing.T) {
+	var out bytes.Buffer
+	defer func(stdout io.Writer) { Stdout = stdout }(Stdout)
+	Stdout = &out
+
+	testNoneObject(t, testEval(t, `print("a", 1, [1, "b"]); print(); print(first([]));`))
+	if out.String() != "a 1 [1, \"b\"]\n\nNone\n" {
+		t.Errorf("Unexpected output %q", out.String())
+	}
+}
+
+func TestEvalTrinary(t *testing.T) {
+	testIntegerObject(t, testEval(t, "return 1 if True else 2;"), 1)
+	testIntegerObject(t, testEval(t, "return 1 if 1 > 2 else 2;"), 2)
+}
+
+func TestEvalIfElseStatements(t *testing.T) {
+	testIntegerObject(t, testEval(t, `
+		let x = 10;
+		if (x > 5) {
+			return 1;
+		} else {
+			return 2;
+		}
+	`), 1)
+
+	testIntegerObject(t, testEval(t, `
+		let x = 3;
+		if (x > 5) {
+			return 1;
+		} else if (x > 2) {
+			return 2;
+		} else {
+			return 3;
+		}
+	`), 2)
+
+	testIntegerObject(t, testEval(t, `
+		let x = 1;
+		if (x > 5) {
+			return 1;
+		} elif (x > 2) {
+			return 2;
+		} elif (x > 0) {
+			return 3;
+		}
+	`), 3)
+
+	testNoneObject(t, testEval(t, `
+		if (False) {
+			return 1;
+		}
+	`))
+}
+
+func TestEvalReturnStatements(t *testing.T) {
+	testIntegerObject(t, testEval(t, `
+		if (10 > 1) {
+			if (10 > 1) {
+				return 10;
+			}
+			return 1;
+		}
+	`), 10)
+
+	testIntegerObject(t, testEval(t, `
+		return 9;
+		return 10;
+	`), 9)
+}
+
+func TestEvalLoops(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"while (False) { 1 / 0; }", "None"},
+		{"while (True) { break; }", "None"},
+		{"for (x in [1, 2]) { x; }", "None"},
+		{"for (x in []) { return 1; } 2;", "2"},
+		{"for (x in [1, 2, 3]) { if (x == 2) { break; } } 5;", "5"},
+		{"let f = fn() { while (True) { return 3; } }; f();", "3"},
+		{`
+			let find = fn(xs, target) {
+				for (x in xs) {
+					if (x == target) { return True; }
+				}
+				return False;
+			};
+			[find([1, 2, 3], 2), find([1, 2, 3], 5)];
+		`, "[True, False]"},
+		{`
+			let firstOdd = fn(xs) {
+				for (x in xs) {
+					if (x % 2 == 0) { continue; }
+					return x;
+				}
+			};
+			firstOdd([2, 4, 5, 7]);
+		`, "5"},
+		// break and continue apply to the innermost loop
+		{`
+			let f = fn() {
+				for (x in [1, 2]) {
+					for (y in [10, 20]) {
+						if (x == 1) { break; }
+						return x + y;
+					}
+				}
+			};
+			f();
+		`, "12"},
+		{`let f = fn(s) { for (c in s) { if (c != "a") { return c; } } }; f("a\u{e9}");`, `"é"`},
+		{`for (k in {"b": 1, "a": 2}) { return k; }`, `"b"`},
+	}
+
+	for _, tt := range tests {
+		result := testEval(t, tt.input)
+		if result.Inspect() != tt.expected {
+			t.Errorf("%s\n\texpected %s. got %s", tt.input, tt.expected, result.Inspect())
+		}
+	}
+
+	testErrorObject(t, testEval(t, "for (x in 5) { x; }"), "not iterable: INT")
+	testErrorObject(t, testEval(t, "for (x in [1]) { x; } x;"), "identifier not found: x")
+	testErrorObject(t, testEval(t, "while (True) { 1 / 0; }"), "division by zero")
+}
+
+func TestEvalMatch(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`match (2) { 1 => "one", 2 => "two", _ => "many" };`, `"two"`},
+		{`match (7) { 1 => "one", _ => "many" };`, `"many"`},
+		{"match (-1) { -1 => True, _ => False };", "True"},
+		{"match (2.0) { 2 => True, _ => False };", "True"},
+		{`match ("2") { 2 => "int", "2" => "string" };`, `"string"`},
+		{"match (3) { n => n * 2 };", "6"},
+		{"match ([1, 2]) { [a] => a, [a, b] => a + b, _ => 0 };", "3"},
+		{"match ([1, [2, 3]]) { [_, [x, 3]] => x, _ => 0 };", "2"},
+		{"match ([1, 2, 3]) { [a, b] => 0, _ => -1 };", "-1"},
+		{"match ([1]) { {1: x} => x, [x] => -x };", "-1"},
+		{`match ({"name": "ada", "age": 36}) { {"name": n, "age": 36} => n, _ => "" };`, `"ada"`},
+		{`match ({"name": "ada"}) { {"age": a} => a, _ => "no age" };`, `"no age"`},
+		{"match (5) { n if n > 10 => \"big\", n if n > 1 => \"medium\", _ => \"small\" };", `"medium"`},
+		{"match ([3, 1]) { [a, b] if a < b => \"sorted\", [a, b] => \"unsorted\" };", `"unsorted"`},
+		// bindings live in the arm, and do not leak when it fails
+		{"let x = 1; match (2) { [x] => x, n => x + n };", "3"},
+		{"let x = 1; match (2) { x => x }; x;", "1"},
+		{"let f = fn(v) { return match (v) { 0 => 1, n => n * f(n - 1) }; }; f(5);", "120"},
+	}
+
+	for _, tt := range tests {
+		result := testEval(t, tt.input)
+		if result.Inspect() != tt.expected {
+			t.Errorf("%s\n\texpected %s. got %s", tt.input, tt.expected, result.Inspect())
+		}
+	}
+
+	testErrorObject(t, testEval(t, "match (3) { 1 => 1, 2 => 2 };"), "no match arm for value 3")
+	testErrorObject(t, testEval(t, "match (1) { n if n / 0 => 1, _ => 2 };"), "division by zero")
+	testErrorObject(t, testEval(t, "match (1) { [x] => x, _ => x };"), "identifier not found: x")
+}
+
+func TestEvalWhileCondition(t *testing.T) {
+	calls := 0
+	env := object.NewEnvironment()
+	env.Set("next", &object.Builtin{Name: "next", Fn: func(args ...object.Object) object.Object {
+		calls++
+		return nativeBoolToBoolObject(calls <= 3)
+	}})
+
+	prog, ok := parser.NewParser(lexer.NewLexer("while (next()) { continue; }")).ParseProgram()
+	if !ok {
+		t.Fatal("Could not parse the loop")
+	}
+	testNoneObject(t, EvalProgram(prog, env))
+	if calls != 4 {
+		t.Errorf("Expected the condition to be evaluated 4 times. got %d times", calls)
+	}
+}
+
+func TestEvalAssignments(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let x = 1; x = 2; x;", "2"},
+		{"let x = 1; x = 2;", "None"},
+		{"let x = 10; x += 5; x -= 3; x *= 2; x /= 4; x;", "6"},
+		{`let s = "a"; s += "b"; s;`, `"ab"`},
+		{"let i = 0; let total = 0; while (i < 5) { i += 1; total += i; } total;", "15"},
+		{"let total = 0; for (x in [1, 2, 3]) { if (x == 2) { continue; } total += x; } total;", "4"},
+		// the nearest scope defining the name is updated
+		{"let x = 1; { let x = 2; x = 3; } x;", "1"},
+		{"let x = 1; { x = 3; } x;", "3"},
+		{`
+			let makeCounter = fn() {
+				let count = 0;
+				return fn() { count += 1; return count; };
+			};
+			let counter = makeCounter();
+			counter();
+			counter();
+		`, "2"},
+		{"let xs = [1, 2, 3]; xs[0] = 10; xs[-1] *= 2; xs;", "[10, 2, 6]"},
+		{`let h = {"a": 1}; h["a"] += 1; h["b"] = 3; h;`, `{"a": 2, "b": 3}`},
+		{"let grid = [[0, 0], [0, 0]]; grid[1][0] = 5; grid;", "[[0, 0], [5, 0]]"},
+		{"let xs = [1]; let ys = xs; ys[0] = 2; xs;", "[2]"},
+	}
+
+	for _, tt := range tests {
+		result := testEval(t, tt.input)
+		if result.Inspect() != tt.expected {
+			t.Errorf("%s\n\texpected %s. got %s", tt.input, tt.expected, result.Inspect())
+		}
+	}
+
+	errorTests := []struct {
+		input    string
+		expected string
+	}{
+		{"x = 1;", "assignment to undeclared identifier: x"},
+		{"{ let y = 1; } y = 2;", "assignment to undeclared identifier: y"},
+		{"x += 1;", "identifier not found: x"},
+		{"let x = 1; x = 1 / 0;", "division by zero"},
+		{"let x = True; x += 1;", "type mismatch: BOOL + INT"},
+		{"let xs = [1]; xs[1] = 2;", "index out of range: 1 (len 1)"},
+		{`let h = {}; h["a"] += 1;`, `key not found: "a"`},
+		{"let h = {}; h[[1]] = 1;", "unusable as hash key: ARRAY"},
+		{`let s = "ab"; s[0] = "c";`, "index assignment not supported: STRING"},
+	}
+
+	for _, tt := range errorTests {
+		testErrorObject(t, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestEvalLetStatements(t *testing.T) {
+	testIntegerObject(t, testEval(t, `
+		let a = 5;
+		let b = a * 2;
+		return a + b;
+	`), 15)
+
+	// bindings made inside a block do not leak out of it
+	testErrorObject(t, testEval(t, `
+		if (True) {
+			let inner = 1;
+		}
+		return inner;
+	`), "identifier not found: inner")
+}
+
+func TestEvalFunctions(t *testing.T) {
+	fnObj, ok := testEval(t, "return fn(x) { return x + 2; };").(*object.Function)
+	if !ok {
+		t.Fatalf("Expected Function object")
+	}
+	if len(fnObj.Parameters) != 1 || fnObj.Parameters[0].GetName() != "x" {
+		t.Errorf("Wrong function parameters: %s", fnObj.Inspect())
+	}
+	if inspect := testEval(t, "return fn() { return; };").Inspect(); !strings.Contains(inspect, "return;") {
+		t.Errorf("Wrong Function.Inspect(). got %s", inspect)
+	}
+
+	testIntegerObject(t, testEval(t, `
+		let add = fn(a, b) { return a + b; };
+		return add(5, add(1, 1));
+	`), 7)
+
+	testNoneObject(t, testEval(t, `
+		let noop = fn() { let x = 1; };
+		return noop();
+	`))
+
+	testIntegerObject(t, testEval(t, `
+		let fib = fn(n) {
+			return n if n < 2 else fib(n - 1) + fib(n - 2);
+		};
+		return fib(15);
+	`), 610)
+}
+
+func TestEvalClosures(t *testing.T) {
+	testIntegerObject(t, testEval(t, `
+		let newAdder = fn(x) {
+			return fn(y) { return x + y; };
+		};
+		let addTwo = newAdder(2);
+		return addTwo(3);
+	`), 5)
+
+	// the closure sees its defining scope, not the caller's
+	testIntegerObject(t, testEval(t, `
+		let x = 1;
+		let getX = fn() { return x; };
+		let callWithX = fn(x) { return getX(); };
+		return callWithX(100);
+	`), 1)
+}
+
+func TestEvalCallExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"fn(x) { return x; }(5);", 5},
+		{"fn() { return 7; }();", 7},
+		{"let makeAdder = fn(a) { return fn(b) { return a + b; }; }; makeAdder(1)(2);", 3},
+		{"let add = fn(a) { return fn(b) { return fn(c) { return a + b + c; }; }; }; add(1)(2)(3);", 6},
+		{"let fns = [fn(x) { return x * 2; }]; fns[0](21);", 42},
+		{`let ops = {"neg": fn(x) { return -x; }}; ops["neg"](3);`, -3},
+		{"let twice = fn(f, x) { return f(f(x)); }; twice(fn(x) { return x + 1; }, 0);", 2},
+		{"let id = fn(x) { return x; }; -id(2) + 1;", -1},
+		{"(fn(x) { return x; })(9);", 9},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(t, tt.input), tt.expected)
+	}
+
+	testErrorObject(t, testEval(t, "1(2);"), "not a function: INT")
+	testErrorObject(t, testEval(t, "[1][0]();"), "not a function: INT")
+}
+
+func TestEvalErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"return 5 + True;", "type mismatch: INT + BOOL"},
+		{"return -True;", "unknown operator: -BOOL"},
+		{"return True + False;", "unknown operator: BOOL + BOOL"},
+		{"return foobar;", "identifier not found: foobar"},
+		{"return 1 / 0;", "division by zero"},
+		{"let x = 1; return x(1);", "not a function: INT"},
+		{"let f = fn(a) { return a; }; return f();", "wrong number of arguments: expected 1, got 0"},
+		{
+			"if (10 > 1) { if (10 > 1) { return True + False; } return 1; }",
+			"unknown operator: BOOL + BOOL",
+		},
+	}
+
+	for _, tt := range tests {
+		testErrorObject(t, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestEvalErrorSpans(t *testing.T) {
+	input := "let f = fn(x) {\n\treturn x / 0;\n};\nreturn f(1);"
+	lx := lexer.NewLexer(input)
+	p := parser.NewParser(lx)
+	prog, _ := p.ParseProgram()
+
+	errObj, ok := EvalProgram(prog, object.NewEnvironment()).(*object.Error)
+	if !ok {
+		t.Fatalf("Expected Error object")
+	}
+
+	position := lx.GetFile().Position(errObj.Span.Start)
+	if position.String() != "2:9" {
+		t.Errorf("Expected error at 2:9. got %s", position)
+	}
+}
+
+func TestEvalLimits(t *testing.T) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	loop := "let i = 0; while (True) { i += 1; }"
+	recurse := "let f = fn(n) { return f(n + 1); }; f(0);"
+	tests := []struct {
+		ctx      context.Context
+		limits   object.Limits
+		input    string
+		limit    object.Limit
+		expected string
+	}{
+		{context.Background(), object.Limits{}, recurse, object.DepthLimit, "maximum recursion depth exceeded"},
+		{context.Background(), object.Limits{MaxDepth: 3}, recurse, object.DepthLimit, "maximum recursion depth exceeded"},
+		{context.Background(), object.Limits{MaxSteps: 100}, loop, object.StepLimit, "step limit exceeded: 100 steps"},
+		{context.Background(), object.Limits{MaxAllocs: 10}, "range(100);", object.AllocLimit, "allocation limit exceeded: 10 objects"},
+		{context.Background(), object.Limits{MaxAllocs: 10}, `let s = ""; while (True) { s += "a"; }`, object.AllocLimit, "allocation limit exceeded: 10 objects"},
+		{context.Background(), object.Limits{MaxAllocs: 1000}, "range(30000000);", object.AllocLimit, "allocation limit exceeded: 1000 objects"},
+		{context.Background(), object.Limits{MaxAllocs: 1000}, `let s = "a"; while (True) { s = s + s; }`, object.AllocLimit, "allocation limit exceeded: 1000 objects"},
+		{context.Background(), object.Limits{MaxAllocs: 1000}, "2 ** 100000;", object.AllocLimit, "allocation limit exceeded: 1000 objects"},
+		{context.Background(), object.Limits{MaxDuration: time.Millisecond}, loop, object.TimeLimit, "time limit exceeded: 1ms"},
+		// too few steps to reach a periodic check
+		{context.Background(), object.Limits{MaxDuration: time.Millisecond}, "let i = 0; while (i < 1000) { range(100000); i += 1; }", object.TimeLimit, "time limit exceeded: 1ms"},
+		{context.Background(), object.Limits{MaxDuration: time.Nanosecond}, "1;", object.TimeLimit, "time limit exceeded: 1ns"},
+		{canceled, object.Limits{}, loop, object.ContextDone, "execution stopped: context canceled"},
+		{canceled, object.Limits{}, "let f = fn() { return 1; }; f();", object.ContextDone, "execution stopped: context canceled"},
+	}
+
+	for _, tt := range tests {
+		prog, ok := parser.NewParser(lexer.NewLexer(tt.input)).ParseProgram()
+		if !ok {
+			t.Fatalf("Could not parse %q", tt.input)
+		}
+		env := object.NewEnvironment()
+		env.SetBudget(object.NewBudget(tt.ctx, tt.limits))
+
+		result := EvalProgram(prog, env)
+		if !testErrorObject(t, result, tt.expected) {
+			continue
+		}
+		if limit := result.(*object.Error).Limit; limit != tt.limit {
+			t.Errorf("%s: expected %s. got %s", tt.input, tt.limit, limit)
+		}
+	}
+
+	// the depth goes back down as calls return
+	env := object.NewEnvironment()
+	env.SetBudget(object.NewBudget(context.Background(), object.Limits{MaxDepth: 3}))
+	prog, _ := parser.NewParser(lexer.NewLexer("let f = fn(n) { return n; }; f(1) + f(2) + f(3) + f(4);")).ParseProgram()
+	testIntegerObject(t, EvalProgram(prog, env), 10)
+}
+
+func testEval(t *testing.T, input string) object.Object {
+	lx := lexer.NewLexer(input)
+	p := parser.NewParser(lx)
+
+	prog, ok := p.ParseProgram()
+	if !ok {
+		for _, msg := range p.Errors {
+			t.Error(msg)
+		}
+		t.Fatalf("Could not parse %q", input)
+	}
+
+	return EvalProgram(prog, object.NewEnvironment())
+}
+
+func testIntegerObject(t *testing.T, obj object.Object, expected int64) bool {
+	intObj, ok := obj.(*object.Int)
+	if !ok {
+		t.Errorf("Expected Int object. got %T (%s)", obj, obj.Inspect())
+		return false
+	}
+	if intObj.Value != expected {
+		t.Errorf("intObj.Value not %d. got=%d", expected, intObj.Value)
+		return false
+	}
+	return true
+}
+
+func testBoolObject(t *testing.T, obj object.Object, expected bool) bool {
+	boolObj, ok := obj.(*object.Bool)
+	if !ok {
+		t.Errorf("Expected Bool object. got %T (%s)", obj, obj.Inspect())
+		return false
+	}
+	if boolObj.Value != expected {
+		t.Errorf("boolObj.Value not %t. got=%t", expected, boolObj.Value)
+		return false
+	}
+	return true
+}
+
+func testFloatObject(t *testing.T, obj object.Object, expected float64) bool {
+	floatObj, ok := obj.(*object.Float)
+	if !ok {
+		t.Errorf("Expected Float object. got %T (%s)", obj, obj.Inspect())
+		return false
+	}
+	if floatObj.Value != expected {
+		t.Errorf("floatObj.Value not %g. got=%g", expected, floatObj.Value)
+		return false
+	}
+	return true
+}
+
+func testStringObject(t *testing.T, obj object.Object, expected string) bool {
+	strObj, ok := obj.(*object.String)
+	if !ok {
+		t.Errorf("Expected String object. got %T (%s)", obj, obj.Inspect())
+		return false
+	}
+	if strObj.Value != expected {
+		t.Errorf("strObj.Value not %q. got=%q", expected, strObj.Value)
+		return false
+	}
+	return true
+}
+
+func testNoneObject(t *testing.T, obj object.Object) bool {
+	if obj != NONE {
+		t.Errorf("Expected None object. got %T (%s)", obj, obj.Inspect())
+		return false
+	}
+	return true
+}
+
+func testErrorObject(t *testing.T, obj object.Object, expected string) bool {
+	errObj, ok := obj.(*object.Error)
+	if !ok {
+		t.Errorf("Expected Error object. got %T (%s)", obj, obj.Inspect())
+		return false
+	}
+	if errObj.Message != expected {
+		t.Errorf("errObj.Message not %q. got=%q", expected, errObj.Message)
+		return false
+	}
+	return true
+}
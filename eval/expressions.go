@@ -0,0 +1,181 @@
+package eval
+
+import (
+	"gorilla/ast"
+	"gorilla/object"
+	"gorilla/token"
+)
+
+func evalIdentifier(identifier *ast.IdentifierExpression, env *object.Environment) object.Object {
+	if value, ok := env.Get(identifier.GetName()); ok {
+		return value
+	}
+	if builtin, ok := builtins[identifier.GetName()]; ok {
+		return builtin
+	}
+	return newError(identifier, "identifier not found: %s", identifier.GetName())
+}
+
+func evalPrefix(prefixOp *ast.Prefix, env *object.Environment) object.Object {
+	operand := Eval(prefixOp.Operand, env)
+	if isError(operand) {
+		return operand
+	}
+
+	return withSpan(Prefix(prefixOp.GetOperatorType(), operand), prefixOp)
+}
+
+func evalInfix(inFix *ast.Infix, env *object.Environment) object.Object {
+	left := Eval(inFix.Left, env)
+	if isError(left) {
+		return left
+	}
+
+	right := Eval(inFix.Right, env)
+	if isError(right) {
+		return right
+	}
+
+	return withSpan(Infix(inFix.GetOperatorType(), left, right), inFix)
+}
+
+// evalLogical only evaluates the right operand if the left one does not
+// decide the result. Both operators give a Bool.
+func evalLogical(logical *ast.Logical, env *object.Environment) object.Object {
+	left := Eval(logical.Left, env)
+	if isError(left) {
+		return left
+	}
+
+	leftTruthy := IsTruthy(left)
+	if logical.GetOperatorType() == token.AND && !leftTruthy {
+		return FALSE
+	}
+	if logical.GetOperatorType() == token.OR && leftTruthy {
+		return TRUE
+	}
+
+	right := Eval(logical.Right, env)
+	if isError(right) {
+		return right
+	}
+	return nativeBoolToBoolObject(IsTruthy(right))
+}
+
+// evalComparison evaluates each operand at most once, stopping at the
+// first comparison that is false.
+func evalComparison(comparison *ast.Comparison, env *object.Environment) object.Object {
+	left := Eval(comparison.Operands[0], env)
+	if isError(left) {
+		return left
+	}
+
+	var result object.Object
+	for i, operator := range comparison.Operators {
+		right := Eval(comparison.Operands[i+1], env)
+		if isError(right) {
+			return right
+		}
+
+		result = withSpan(Infix(operator.Type, left, right), comparison)
+		if isError(result) || !IsTruthy(result) {
+			return result
+		}
+		left = right
+	}
+	return result
+}
+
+func evalTrinary(trinary *ast.Trinary, env *object.Environment) object.Object {
+	condition := Eval(trinary.Middle, env)
+	if isError(condition) {
+		return condition
+	}
+
+	if IsTruthy(condition) {
+		return Eval(trinary.Left, env)
+	}
+	return Eval(trinary.Right, env)
+}
+
+// evalExpressions evaluates exprs left to right, stopping at the first error.
+func evalExpressions(exprs []ast.ExpressionNode, env *object.Environment) ([]object.Object, object.Object) {
+	results := make([]object.Object, 0, len(exprs))
+	for _, expr := range exprs {
+		result := Eval(expr, env)
+		if isError(result) {
+			return nil, result
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+func evalFunctionCall(fnCall *ast.FunctionCall, env *object.Environment) object.Object {
+	function := Eval(fnCall.Function, env)
+	if isError(function) {
+		return function
+	}
+
+	args, errObj := evalExpressions(fnCall.Arguments, env)
+	if errObj != nil {
+		return errObj
+	}
+
+	return withSpan(Apply(env.Budget(), function, args), fnCall)
+}
+
+// Apply calls function with args, charging the call to budget, which may
+// be nil. Errors of the call itself, such as a wrong number of arguments,
+// carry no location; errors raised in the body of the function keep
+// theirs.
+func Apply(budget *object.Budget, function object.Object, args []object.Object) object.Object {
+	if builtin, ok := function.(*object.Builtin); ok {
+		// a builtin that knows its cost is charged before it allocates
+		if builtin.Cost != nil {
+			if errObj := budget.Alloc(builtin.Cost(args...)); errObj != nil {
+				return errObj
+			}
+			return builtin.Fn(args...)
+		}
+		result := builtin.Fn(args...)
+		if errObj := budget.Alloc(allocationSize(result)); errObj != nil {
+			return errObj
+		}
+		return result
+	}
+
+	fnObj, ok := function.(*object.Function)
+	if !ok {
+		return newOperatorError("not a function: %s", function.GetType())
+	}
+
+	if len(args) != len(fnObj.Parameters) {
+		return newOperatorError("wrong number of arguments: expected %d, got %d",
+			len(fnObj.Parameters), len(args),
+		)
+	}
+
+	if errObj := budget.Enter(); errObj != nil {
+		return errObj
+	}
+	defer budget.Leave()
+
+	// the body is charged to the caller, whichever run defined the function
+	callEnv := object.NewEnclosedEnvironment(fnObj.Env)
+	callEnv.SetBudget(budget)
+	for i, param := range fnObj.Parameters {
+		callEnv.Set(param.GetName(), args[i])
+	}
+
+	// the body runs directly in callEnv, so parameters and locals share a scope
+	result := evalBlockStatement(fnObj.Body, callEnv)
+	switch result := result.(type) {
+	case *object.ReturnValue:
+		return result.Value
+	case *object.Error:
+		return result
+	default:
+		return NONE
+	}
+}
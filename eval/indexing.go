@@ -0,0 +1,74 @@
+package eval
+
+import (
+	"gorilla/ast"
+	"gorilla/object"
+)
+
+func evalHashLiteral(hashLit *ast.HashLiteral, env *object.Environment) object.Object {
+	hashObj := object.NewHash()
+	for _, pair := range hashLit.Pairs {
+		key := Eval(pair.Key, env)
+		if isError(key) {
+			return key
+		}
+
+		hashKey, ok := key.(object.Hashable)
+		if !ok {
+			return newError(pair.Key, "unusable as hash key: %s", key.GetType())
+		}
+
+		value := Eval(pair.Value, env)
+		if isError(value) {
+			return value
+		}
+		hashObj.Set(hashKey, value)
+	}
+	return hashObj
+}
+
+func evalIndexExpression(indexOp *ast.IndexExpression, env *object.Environment) object.Object {
+	left := Eval(indexOp.Left, env)
+	if isError(left) {
+		return left
+	}
+
+	index := Eval(indexOp.Index, env)
+	if isError(index) {
+		return index
+	}
+
+	return withSpan(Index(left, index), indexOp)
+}
+
+func evalSliceExpression(sliceOp *ast.SliceExpression, env *object.Environment) object.Object {
+	left := Eval(sliceOp.Left, env)
+	if isError(left) {
+		return left
+	}
+
+	start, errObj := evalSliceBound(sliceOp.Start, env)
+	if errObj != nil {
+		return errObj
+	}
+	end, errObj := evalSliceBound(sliceOp.End, env)
+	if errObj != nil {
+		return errObj
+	}
+
+	return withSpan(Slice(left, start, end), sliceOp)
+}
+
+// evalSliceBound evaluates an optional slice bound, returning nil for an
+// omitted one.
+func evalSliceBound(bound ast.ExpressionNode, env *object.Environment) (object.Object, object.Object) {
+	if bound == nil {
+		return nil, nil
+	}
+
+	value := Eval(bound, env)
+	if isError(value) {
+		return nil, value
+	}
+	return value, nil
+}
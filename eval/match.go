@@ -0,0 +1,84 @@
+package eval
+
+import (
+	"gorilla/ast"
+	"gorilla/object"
+)
+
+// evalMatch evaluates the body of the first arm that matches the value. The
+// names bound by an arm live in a scope of their own, shared by its guard
+// and body.
+func evalMatch(match *ast.Match, env *object.Environment) object.Object {
+	value := Eval(match.Value, env)
+	if isError(value) {
+		return value
+	}
+
+	for _, arm := range match.Arms {
+		armEnv := object.NewEnclosedEnvironment(env)
+		bind := func(name string, bound object.Object) {
+			armEnv.Set(name, bound)
+		}
+		if !MatchPattern(arm.Pattern, value, bind) {
+			continue
+		}
+
+		if arm.Guard != nil {
+			guard := Eval(arm.Guard, armEnv)
+			if isError(guard) {
+				return guard
+			}
+			if !IsTruthy(guard) {
+				continue
+			}
+		}
+		return Eval(arm.Body, armEnv)
+	}
+	return newError(match, "no match arm for value %s", value.Inspect())
+}
+
+// MatchPattern reports whether value matches pattern, calling bind for each
+// name the pattern binds. Names may already be bound when a later part of
+// the pattern fails, so the caller should discard them in that case.
+func MatchPattern(pattern ast.Pattern, value object.Object, bind func(name string, value object.Object)) bool {
+	switch pattern := pattern.(type) {
+	case *ast.WildcardPattern:
+		return true
+
+	case *ast.BindingPattern:
+		bind(pattern.Identifier.GetName(), value)
+		return true
+
+	case *ast.LiteralPattern:
+		return IsEqual(Eval(pattern.Value, nil), value)
+
+	case *ast.ArrayPattern:
+		arrObj, ok := value.(*object.Array)
+		if !ok || len(arrObj.Elements) != len(pattern.Elements) {
+			return false
+		}
+		for i, element := range pattern.Elements {
+			if !MatchPattern(element, arrObj.Elements[i], bind) {
+				return false
+			}
+		}
+		return true
+
+	case *ast.HashPattern:
+		hashObj, ok := value.(*object.Hash)
+		if !ok {
+			return false
+		}
+		for _, pair := range pattern.Pairs {
+			key := Eval(pair.Key, nil).(object.Hashable)
+			pairValue, ok := hashObj.Get(key)
+			if !ok || !MatchPattern(pair.Value, pairValue, bind) {
+				return false
+			}
+		}
+		return true
+
+	default:
+		return false
+	}
+}
@@ -0,0 +1,632 @@
+package eval
+
+import (
+	"fmt"
+	"gorilla/object"
+	"gorilla/token"
+	"math"
+	"math/big"
+)
+
+// The operators below work on values only, so the evaluator and the vm
+// share one definition of the language semantics. Their errors carry no
+// location; the caller attaches the span of the expression with withSpan.
+
+// newOperatorError creates an error without a location.
+func newOperatorError(format string, args ...any) *object.Error {
+	return &object.Error{Message: fmt.Sprintf(format, args...)}
+}
+
+// IsTruthy follows Python: None, False, 0, "", [] and {} are false,
+// everything else is true.
+func IsTruthy(obj object.Object) bool {
+	switch obj := obj.(type) {
+	case *object.None:
+		return false
+	case *object.Bool:
+		return obj.Value
+	case *object.Int:
+		return obj.Value != 0
+	case *object.BigInt:
+		return obj.Value.Sign() != 0
+	case *object.Float:
+		return obj.Value != 0
+	case *object.String:
+		return obj.Value != ""
+	case *object.Array:
+		return len(obj.Elements) > 0
+	case *object.Hash:
+		return obj.Len() > 0
+	default:
+		return true
+	}
+}
+
+// IsEqual compares two values structurally. Numbers compare by value, so
+// 1 == 1.0; other values of different types are never equal.
+func IsEqual(left object.Object, right object.Object) bool {
+	return isEqual(left, right, map[object.Object]bool{})
+}
+
+// isEqual compares an array or hash it is already inside of, one in
+// visiting, by identity, so comparing values that contain themselves ends.
+func isEqual(left object.Object, right object.Object, visiting map[object.Object]bool) bool {
+	if isNumber(left) && isNumber(right) && left.GetType() != right.GetType() {
+		if left.GetType() == object.FLOAT || right.GetType() == object.FLOAT {
+			return toFloat(left) == toFloat(right)
+		}
+		return toBig(left).Cmp(toBig(right)) == 0
+	}
+	if left.GetType() != right.GetType() {
+		return false
+	}
+
+	switch left := left.(type) {
+	case *object.Bool:
+		return left.Value == right.(*object.Bool).Value
+	case *object.Int:
+		return left.Value == right.(*object.Int).Value
+	case *object.BigInt:
+		return left.Value.Cmp(right.(*object.BigInt).Value) == 0
+	case *object.Float:
+		return left.Value == right.(*object.Float).Value
+	case *object.String:
+		return left.Value == right.(*object.String).Value
+	case *object.Array:
+		if visiting[left] {
+			return left == right
+		}
+		visiting[left] = true
+		defer delete(visiting, left)
+
+		rightElements := right.(*object.Array).Elements
+		if len(left.Elements) != len(rightElements) {
+			return false
+		}
+		for i, element := range left.Elements {
+			if !isEqual(element, rightElements[i], visiting) {
+				return false
+			}
+		}
+		return true
+	case *object.Hash:
+		if visiting[left] {
+			return left == right
+		}
+		visiting[left] = true
+		defer delete(visiting, left)
+
+		rightHash := right.(*object.Hash)
+		if left.Len() != rightHash.Len() {
+			return false
+		}
+		for _, pair := range left.GetPairs() {
+			rightValue, ok := rightHash.Get(pair.Key)
+			if !ok || !isEqual(pair.Value, rightValue, visiting) {
+				return false
+			}
+		}
+		return true
+	case *object.None:
+		return true
+	default:
+		return left == right
+	}
+}
+
+// Prefix applies the prefix operator to operand.
+func Prefix(operator token.TokenType, operand object.Object) object.Object {
+	switch operator {
+	case token.BANG:
+		return nativeBoolToBoolObject(!IsTruthy(operand))
+
+	case token.MINUS:
+		switch operand := operand.(type) {
+		case *object.Int:
+			if operand.Value == math.MinInt64 {
+				return object.NewInteger(new(big.Int).Neg(big.NewInt(operand.Value)))
+			}
+			return &object.Int{Value: -operand.Value}
+		case *object.BigInt:
+			return object.NewInteger(new(big.Int).Neg(operand.Value))
+		case *object.Float:
+			return &object.Float{Value: -operand.Value}
+		default:
+			return newOperatorError("unknown operator: -%s", operand.GetType())
+		}
+
+	case token.BIT_NOT:
+		switch operand := operand.(type) {
+		case *object.Int:
+			return &object.Int{Value: ^operand.Value}
+		case *object.BigInt:
+			return object.NewInteger(new(big.Int).Not(operand.Value))
+		default:
+			return newOperatorError("unknown operator: ~%s", operand.GetType())
+		}
+
+	default:
+		return newOperatorError("unknown operator: %s%s", operator, operand.GetType())
+	}
+}
+
+// Infix applies the binary operator to left and right.
+func Infix(operator token.TokenType, left, right object.Object) object.Object {
+	switch {
+	case operator == token.POWER && isNumber(left) && isNumber(right):
+		return power(left, right)
+	case integerOperators[operator] && isNumber(left) && isNumber(right) &&
+		!(isInteger(left) && isInteger(right)):
+		return newOperatorError("unknown operator: %s %s %s",
+			left.GetType(), operator, right.GetType(),
+		)
+
+	case left.GetType() == object.INT && right.GetType() == object.INT:
+		return integerInfix(operator, left.(*object.Int), right.(*object.Int))
+	case isInteger(left) && isInteger(right):
+		// at least one of them is a BigInt
+		return bigInfix(operator, toBig(left), toBig(right))
+	case isNumber(left) && isNumber(right):
+		// an integer mixed with a Float is promoted to Float
+		return floatInfix(operator, toFloat(left), toFloat(right))
+	case left.GetType() == object.STRING && right.GetType() == object.STRING:
+		return stringInfix(operator, left.(*object.String), right.(*object.String))
+
+	case operator == token.EQ:
+		return nativeBoolToBoolObject(IsEqual(left, right))
+	case operator == token.NOT_EQ:
+		return nativeBoolToBoolObject(!IsEqual(left, right))
+
+	case left.GetType() != right.GetType():
+		return newOperatorError("type mismatch: %s %s %s",
+			left.GetType(), operator, right.GetType(),
+		)
+	default:
+		return newOperatorError("unknown operator: %s %s %s",
+			left.GetType(), operator, right.GetType(),
+		)
+	}
+}
+
+// integerOperators only apply to integers, not to floats.
+var integerOperators = map[token.TokenType]bool{
+	token.BIT_AND:     true,
+	token.BIT_OR:      true,
+	token.BIT_XOR:     true,
+	token.SHIFT_LEFT:  true,
+	token.SHIFT_RIGHT: true,
+}
+
+// maxIntegerBits bounds the size of the results of '**', '<<' and '*',
+// which could otherwise exhaust memory with one or a few operations.
+const maxIntegerBits = 1 << 24
+
+// integerInfix computes on int64s and redoes the computation with big.Int
+// when the result overflows.
+func integerInfix(operator token.TokenType, left, right *object.Int) object.Object {
+	a, b := left.Value, right.Value
+	switch operator {
+	case token.PLUS:
+		sum := a + b
+		if (a^sum)&(b^sum) < 0 {
+			return bigInfix(operator, big.NewInt(a), big.NewInt(b))
+		}
+		return &object.Int{Value: sum}
+	case token.MINUS:
+		difference := a - b
+		if (a^b)&(a^difference) < 0 {
+			return bigInfix(operator, big.NewInt(a), big.NewInt(b))
+		}
+		return &object.Int{Value: difference}
+	case token.ASTERISK:
+		product := a * b
+		overflow := a != 0 && (product/a != b ||
+			a == -1 && b == math.MinInt64 || b == -1 && a == math.MinInt64)
+		if overflow {
+			return bigInfix(operator, big.NewInt(a), big.NewInt(b))
+		}
+		return &object.Int{Value: product}
+	case token.SLASH:
+		if b == 0 {
+			return newOperatorError("division by zero")
+		}
+		if a == math.MinInt64 && b == -1 {
+			return bigInfix(operator, big.NewInt(a), big.NewInt(b))
+		}
+		return &object.Int{Value: a / b}
+	case token.PERCENT:
+		if b == 0 {
+			return newOperatorError("division by zero")
+		}
+		// floored, as in Python: the result takes the sign of b
+		remainder := a % b
+		if remainder != 0 && (remainder < 0) != (b < 0) {
+			remainder += b
+		}
+		return &object.Int{Value: remainder}
+
+	case token.BIT_AND:
+		return &object.Int{Value: a & b}
+	case token.BIT_OR:
+		return &object.Int{Value: a | b}
+	case token.BIT_XOR:
+		return &object.Int{Value: a ^ b}
+	case token.SHIFT_LEFT, token.SHIFT_RIGHT:
+		return shift(operator, big.NewInt(a), big.NewInt(b))
+
+	case token.LT:
+		return nativeBoolToBoolObject(left.Value < right.Value)
+	case token.GT:
+		return nativeBoolToBoolObject(left.Value > right.Value)
+	case token.LE:
+		return nativeBoolToBoolObject(left.Value <= right.Value)
+	case token.GE:
+		return nativeBoolToBoolObject(left.Value >= right.Value)
+	case token.EQ:
+		return nativeBoolToBoolObject(left.Value == right.Value)
+	case token.NOT_EQ:
+		return nativeBoolToBoolObject(left.Value != right.Value)
+
+	default:
+		return newOperatorError("unknown operator: INT %s INT", operator)
+	}
+}
+
+// bigInfix computes on big.Ints. Like Int division, '/' truncates toward
+// zero and '%' takes the sign of the dividend. The result is demoted to an
+// Int if it fits.
+func bigInfix(operator token.TokenType, left, right *big.Int) object.Object {
+	switch operator {
+	case token.PLUS:
+		return object.NewInteger(new(big.Int).Add(left, right))
+	case token.MINUS:
+		return object.NewInteger(new(big.Int).Sub(left, right))
+	case token.ASTERISK:
+		if left.BitLen()+right.BitLen() > maxIntegerBits {
+			return newOperatorError("integer result too large")
+		}
+		return object.NewInteger(new(big.Int).Mul(left, right))
+	case token.SLASH:
+		if right.Sign() == 0 {
+			return newOperatorError("division by zero")
+		}
+		return object.NewInteger(new(big.Int).Quo(left, right))
+	case token.PERCENT:
+		if right.Sign() == 0 {
+			return newOperatorError("division by zero")
+		}
+		remainder := new(big.Int).Rem(left, right)
+		if remainder.Sign() != 0 && remainder.Sign() != right.Sign() {
+			remainder.Add(remainder, right)
+		}
+		return object.NewInteger(remainder)
+
+	case token.BIT_AND:
+		return object.NewInteger(new(big.Int).And(left, right))
+	case token.BIT_OR:
+		return object.NewInteger(new(big.Int).Or(left, right))
+	case token.BIT_XOR:
+		return object.NewInteger(new(big.Int).Xor(left, right))
+	case token.SHIFT_LEFT, token.SHIFT_RIGHT:
+		return shift(operator, left, right)
+
+	case token.LT:
+		return nativeBoolToBoolObject(left.Cmp(right) < 0)
+	case token.GT:
+		return nativeBoolToBoolObject(left.Cmp(right) > 0)
+	case token.LE:
+		return nativeBoolToBoolObject(left.Cmp(right) <= 0)
+	case token.GE:
+		return nativeBoolToBoolObject(left.Cmp(right) >= 0)
+	case token.EQ:
+		return nativeBoolToBoolObject(left.Cmp(right) == 0)
+	case token.NOT_EQ:
+		return nativeBoolToBoolObject(left.Cmp(right) != 0)
+
+	default:
+		return newOperatorError("unknown operator: BIGINT %s BIGINT", operator)
+	}
+}
+
+// shift shifts value by count bits. Right shifts are arithmetic, so they
+// round toward negative infinity like Python's.
+func shift(operator token.TokenType, value, count *big.Int) object.Object {
+	if count.Sign() < 0 {
+		return newOperatorError("negative shift count")
+	}
+
+	if operator == token.SHIFT_RIGHT {
+		if !count.IsInt64() || count.Int64() > int64(value.BitLen()) {
+			// every bit is shifted out
+			if value.Sign() < 0 {
+				return &object.Int{Value: -1}
+			}
+			return &object.Int{Value: 0}
+		}
+		return object.NewInteger(new(big.Int).Rsh(value, uint(count.Int64())))
+	}
+
+	if value.Sign() == 0 {
+		return &object.Int{Value: 0}
+	}
+	if !count.IsInt64() || count.Int64()+int64(value.BitLen()) > maxIntegerBits {
+		return newOperatorError("integer result too large")
+	}
+	return object.NewInteger(new(big.Int).Lsh(value, uint(count.Int64())))
+}
+
+// power computes left ** right. Two integers give an integer, unless the
+// exponent is negative: like Python, 2 ** -1 is 0.5.
+func power(left, right object.Object) object.Object {
+	if isInteger(left) && isInteger(right) && toBig(right).Sign() >= 0 {
+		base, exponent := toBig(left), toBig(right)
+
+		// 0, 1 and -1 stay small whatever the exponent
+		if base.CmpAbs(big.NewInt(1)) > 0 {
+			if !exponent.IsInt64() || exponent.Int64() > maxIntegerBits/int64(base.BitLen()-1) {
+				return newOperatorError("integer result too large")
+			}
+		}
+		return object.NewInteger(new(big.Int).Exp(base, exponent, nil))
+	}
+
+	base, exponent := toFloat(left), toFloat(right)
+	if base == 0 && exponent < 0 {
+		return newOperatorError("division by zero")
+	}
+	return &object.Float{Value: math.Pow(base, exponent)}
+}
+
+func floatInfix(operator token.TokenType, left, right float64) object.Object {
+	switch operator {
+	case token.PLUS:
+		return &object.Float{Value: left + right}
+	case token.MINUS:
+		return &object.Float{Value: left - right}
+	case token.ASTERISK:
+		return &object.Float{Value: left * right}
+	case token.SLASH:
+		if right == 0 {
+			return newOperatorError("division by zero")
+		}
+		return &object.Float{Value: left / right}
+	case token.PERCENT:
+		if right == 0 {
+			return newOperatorError("division by zero")
+		}
+		remainder := math.Mod(left, right)
+		if remainder != 0 && (remainder < 0) != (right < 0) {
+			remainder += right
+		}
+		return &object.Float{Value: remainder}
+
+	case token.LT:
+		return nativeBoolToBoolObject(left < right)
+	case token.GT:
+		return nativeBoolToBoolObject(left > right)
+	case token.LE:
+		return nativeBoolToBoolObject(left <= right)
+	case token.GE:
+		return nativeBoolToBoolObject(left >= right)
+	case token.EQ:
+		return nativeBoolToBoolObject(left == right)
+	case token.NOT_EQ:
+		return nativeBoolToBoolObject(left != right)
+
+	default:
+		return newOperatorError("unknown operator: FLOAT %s FLOAT", operator)
+	}
+}
+
+func isInteger(obj object.Object) bool {
+	return obj.GetType() == object.INT || obj.GetType() == object.BIGINT
+}
+
+func isNumber(obj object.Object) bool {
+	return isInteger(obj) || obj.GetType() == object.FLOAT
+}
+
+// toBig converts an Int or BigInt to a big.Int. A BigInt is returned as
+// is, so the result must not be modified.
+func toBig(obj object.Object) *big.Int {
+	if intObj, ok := obj.(*object.Int); ok {
+		return big.NewInt(intObj.Value)
+	}
+	return obj.(*object.BigInt).Value
+}
+
+// toFloat converts a number to float64.
+func toFloat(obj object.Object) float64 {
+	switch obj := obj.(type) {
+	case *object.Int:
+		return float64(obj.Value)
+	case *object.BigInt:
+		value, _ := new(big.Float).SetInt(obj.Value).Float64()
+		return value
+	default:
+		return obj.(*object.Float).Value
+	}
+}
+
+func stringInfix(operator token.TokenType, left, right *object.String) object.Object {
+	switch operator {
+	case token.PLUS:
+		return &object.String{Value: left.Value + right.Value}
+
+	case token.LT:
+		return nativeBoolToBoolObject(left.Value < right.Value)
+	case token.GT:
+		return nativeBoolToBoolObject(left.Value > right.Value)
+	case token.LE:
+		return nativeBoolToBoolObject(left.Value <= right.Value)
+	case token.GE:
+		return nativeBoolToBoolObject(left.Value >= right.Value)
+	case token.EQ:
+		return nativeBoolToBoolObject(left.Value == right.Value)
+	case token.NOT_EQ:
+		return nativeBoolToBoolObject(left.Value != right.Value)
+
+	default:
+		return newOperatorError("unknown operator: STRING %s STRING", operator)
+	}
+}
+
+// Index returns left[index] for arrays, strings and hashes.
+func Index(left, index object.Object) object.Object {
+	switch left := left.(type) {
+	case *object.Array:
+		i, errObj := getIndex(index, len(left.Elements))
+		if errObj != nil {
+			return errObj
+		}
+		return left.Elements[i]
+
+	case *object.String:
+		runes := []rune(left.Value)
+		i, errObj := getIndex(index, len(runes))
+		if errObj != nil {
+			return errObj
+		}
+		return &object.String{Value: string(runes[i])}
+
+	case *object.Hash:
+		key, ok := index.(object.Hashable)
+		if !ok {
+			return newOperatorError("unusable as hash key: %s", index.GetType())
+		}
+		value, ok := left.Get(key)
+		if !ok {
+			return newOperatorError("key not found: %s", key.Inspect())
+		}
+		return value
+
+	default:
+		return newOperatorError("index operator not supported: %s", left.GetType())
+	}
+}
+
+// SetIndex replaces left[index] with value. An array index must be in
+// range, while a hash gains the key if it is new.
+func SetIndex(left, index, value object.Object) *object.Error {
+	switch left := left.(type) {
+	case *object.Array:
+		i, errObj := getIndex(index, len(left.Elements))
+		if errObj != nil {
+			return errObj
+		}
+		left.Elements[i] = value
+		return nil
+
+	case *object.Hash:
+		key, ok := index.(object.Hashable)
+		if !ok {
+			return newOperatorError("unusable as hash key: %s", index.GetType())
+		}
+		left.Set(key, value)
+		return nil
+
+	default:
+		return newOperatorError("index assignment not supported: %s", left.GetType())
+	}
+}
+
+// getIndex checks that index is an Int within a sequence of the given length
+// and returns it as an offset from the start. Negative indexes count from
+// the end, as in Python.
+func getIndex(index object.Object, length int) (int, *object.Error) {
+	intObj, ok := index.(*object.Int)
+	if !ok {
+		return 0, newOperatorError("index must be INT, got %s", index.GetType())
+	}
+
+	i := intObj.Value
+	if i < 0 {
+		i += int64(length)
+	}
+	if i < 0 || i >= int64(length) {
+		return 0, newOperatorError("index out of range: %d (len %d)", intObj.Value, length)
+	}
+	return int(i), nil
+}
+
+// Slice returns left[start:end] for arrays and strings. A nil bound was
+// omitted in the source.
+func Slice(left, start, end object.Object) object.Object {
+	var length int
+	switch left := left.(type) {
+	case *object.Array:
+		length = len(left.Elements)
+	case *object.String:
+		length = len([]rune(left.Value))
+	default:
+		return newOperatorError("slice operator not supported: %s", left.GetType())
+	}
+
+	from, errObj := getSliceBound(start, 0, length)
+	if errObj != nil {
+		return errObj
+	}
+	to, errObj := getSliceBound(end, length, length)
+	if errObj != nil {
+		return errObj
+	}
+	to = max(from, to)
+
+	switch left := left.(type) {
+	case *object.Array:
+		elements := make([]object.Object, to-from)
+		copy(elements, left.Elements[from:to])
+		return &object.Array{Elements: elements}
+	default:
+		runes := []rune(left.(*object.String).Value)
+		return &object.String{Value: string(runes[from:to])}
+	}
+}
+
+// getSliceBound resolves an optional slice bound. Like Python, negative
+// bounds count from the end and out of range bounds are clamped rather than
+// reported.
+func getSliceBound(bound object.Object, defaultValue int, length int) (int, *object.Error) {
+	if bound == nil {
+		return defaultValue, nil
+	}
+
+	intObj, ok := bound.(*object.Int)
+	if !ok {
+		return 0, newOperatorError("slice index must be INT, got %s", bound.GetType())
+	}
+
+	i := intObj.Value
+	if i < 0 {
+		i += int64(length)
+	}
+	return int(max(0, min(i, int64(length)))), nil
+}
+
+// Iterate returns the elements a for loop visits: the elements of an array,
+// the characters of a string, or the keys of a hash in insertion order.
+func Iterate(iterable object.Object) ([]object.Object, *object.Error) {
+	switch iterable := iterable.(type) {
+	case *object.Array:
+		return iterable.Elements, nil
+
+	case *object.String:
+		runes := []rune(iterable.Value)
+		elements := make([]object.Object, len(runes))
+		for i, r := range runes {
+			elements[i] = &object.String{Value: string(r)}
+		}
+		return elements, nil
+
+	case *object.Hash:
+		pairs := iterable.GetPairs()
+		keys := make([]object.Object, len(pairs))
+		for i, pair := range pairs {
+			keys[i] = pair.Key
+		}
+		return keys, nil
+
+	default:
+		return nil, newOperatorError("not iterable: %s", iterable.GetType())
+	}
+}
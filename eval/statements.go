@@ -0,0 +1,178 @@
+package eval
+
+import (
+	"gorilla/ast"
+	"gorilla/object"
+)
+
+func evalLetStatement(letStmt *ast.LetStatement, env *object.Environment) object.Object {
+	value := Eval(letStmt.Expression, env)
+	if isError(value) {
+		return value
+	}
+
+	env.Set(letStmt.Identifier.GetName(), value)
+	return NONE
+}
+
+// evalAssignment updates an existing binding, or an element of an array or
+// hash. The target is evaluated before the value, and an assignment is worth
+// None like a let statement.
+func evalAssignment(assign *ast.Assignment, env *object.Environment) object.Object {
+	switch target := assign.Target.(type) {
+	case *ast.IdentifierExpression:
+		var current object.Object
+		if assign.IsCompound() {
+			current = Eval(target, env)
+			if isError(current) {
+				return current
+			}
+		}
+
+		value := evalAssignedValue(assign, current, env)
+		if isError(value) {
+			return value
+		}
+		if !env.Assign(target.GetName(), value) {
+			return newError(target, "assignment to undeclared identifier: %s", target.GetName())
+		}
+
+	case *ast.IndexExpression:
+		left := Eval(target.Left, env)
+		if isError(left) {
+			return left
+		}
+		index := Eval(target.Index, env)
+		if isError(index) {
+			return index
+		}
+
+		var current object.Object
+		if assign.IsCompound() {
+			current = withSpan(Index(left, index), target)
+			if isError(current) {
+				return current
+			}
+		}
+
+		value := evalAssignedValue(assign, current, env)
+		if isError(value) {
+			return value
+		}
+		if errObj := SetIndex(left, index, value); errObj != nil {
+			return withSpan(errObj, target)
+		}
+
+	default:
+		return newError(assign, "cannot assign to %s", assign.Target.ToString())
+	}
+	return NONE
+}
+
+// evalAssignedValue evaluates the right-hand side of assign. A compound
+// assignment combines it with current, the old value of the target.
+func evalAssignedValue(assign *ast.Assignment, current object.Object, env *object.Environment) object.Object {
+	value := Eval(assign.Value, env)
+	if isError(value) || !assign.IsCompound() {
+		return value
+	}
+	return allocate(env, assign, withSpan(Infix(assign.GetInfixOperator(), current, value), assign))
+}
+
+func evalReturnStatement(returnStmt *ast.ReturnStatement, env *object.Environment) object.Object {
+	if returnStmt.ReturnValue == nil {
+		return &object.ReturnValue{Value: NONE}
+	}
+
+	value := Eval(returnStmt.ReturnValue, env)
+	if isError(value) {
+		return value
+	}
+	return &object.ReturnValue{Value: value}
+}
+
+// evalBlockStatement runs the statements of block in env. Return values,
+// loop signals and errors are passed up unchanged so the caller can unwind.
+func evalBlockStatement(block *ast.BlockStatement, env *object.Environment) object.Object {
+	var result object.Object = NONE
+	for _, stmt := range block.Statements {
+		result = Eval(stmt, env)
+
+		switch result.GetType() {
+		case object.RETURN_VALUE, object.BREAK, object.CONTINUE, object.ERROR:
+			return result
+		}
+	}
+	return result
+}
+
+func evalIfStatement(ifStmt *ast.IfStatement, env *object.Environment) object.Object {
+	condition := Eval(ifStmt.Condition, env)
+	if isError(condition) {
+		return condition
+	}
+
+	if IsTruthy(condition) {
+		return Eval(ifStmt.Statement, env)
+	} else if ifStmt.Else != nil {
+		return Eval(ifStmt.Else, env)
+	}
+	return NONE
+}
+
+// evalWhileStatement runs the body while the condition holds. Like a let
+// statement, a loop evaluates to None.
+func evalWhileStatement(whileStmt *ast.WhileStatement, env *object.Environment) object.Object {
+	for {
+		if errObj := env.Budget().Check(); errObj != nil {
+			return withSpan(errObj, whileStmt)
+		}
+		condition := Eval(whileStmt.Condition, env)
+		if isError(condition) {
+			return condition
+		}
+		if !IsTruthy(condition) {
+			return NONE
+		}
+
+		result := Eval(whileStmt.Body, env)
+		switch result.GetType() {
+		case object.RETURN_VALUE, object.ERROR:
+			return result
+		case object.BREAK:
+			return NONE
+		}
+	}
+}
+
+// evalForStatement runs the body once for each element of the iterable. The
+// loop variable lives in a scope of its own around the body, so it does not
+// outlive the loop.
+func evalForStatement(forStmt *ast.ForStatement, env *object.Environment) object.Object {
+	iterable := Eval(forStmt.Iterable, env)
+	if isError(iterable) {
+		return iterable
+	}
+
+	elements, errObj := Iterate(iterable)
+	if errObj != nil {
+		return withSpan(errObj, forStmt.Iterable)
+	}
+
+	loopEnv := object.NewEnclosedEnvironment(env)
+	for _, element := range elements {
+		if errObj := env.Budget().Check(); errObj != nil {
+			return withSpan(errObj, forStmt)
+		}
+		loopEnv.Set(forStmt.Variable.GetName(), element)
+
+		result := Eval(forStmt.Body, loopEnv)
+		switch result.GetType() {
+		case object.RETURN_VALUE, object.ERROR:
+			return result
+		case object.BREAK:
+			return NONE
+		}
+	}
+	return NONE
+}
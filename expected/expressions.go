@@ -86,6 +86,62 @@ func (expected *IntegerLiteral) Test(t *testing.T, node ast.Node) bool {
 	return true
 }
 
+// FloatLiteral only checks the value, since a float can be written in
+// many ways.
+type FloatLiteral struct {
+	Value float64
+}
+
+func (expected *FloatLiteral) getTokenType() token.TokenType {
+	return token.FLOAT
+}
+
+func (expected *FloatLiteral) getTokenLiteral() string {
+	return strconv.FormatFloat(expected.Value, 'g', -1, 64)
+}
+
+func (expected *FloatLiteral) Test(t *testing.T, node ast.Node) bool {
+	floatLit, ok := node.(*ast.FloatLiteral)
+	if !ok {
+		t.Errorf("Expected FloatLiteral. got %T expression", node)
+		return false
+	}
+
+	if floatLit.GetValue() != expected.Value {
+		t.Errorf("floatLit.Value not %s. got=%s",
+			expected.getTokenLiteral(), floatLit.GetTokenLiteral(),
+		)
+		return false
+	}
+	return true
+}
+
+type StringLiteral struct {
+	Value string
+}
+
+func (expected *StringLiteral) getTokenType() token.TokenType {
+	return token.STRING
+}
+
+func (expected *StringLiteral) getTokenLiteral() string {
+	return expected.Value
+}
+
+func (expected *StringLiteral) Test(t *testing.T, node ast.Node) bool {
+	strLit, ok := node.(*ast.StringLiteral)
+	if !ok {
+		t.Errorf("Expected StringLiteral. got %T expression", node)
+		return false
+	}
+
+	if strLit.GetValue() != expected.Value {
+		t.Errorf("strLit.Value not %q. got=%q", expected.Value, strLit.GetValue())
+		return false
+	}
+	return true
+}
+
 type Identifier struct {
 	Name string
 }
@@ -164,16 +220,16 @@ func (expected *FunctionLiteral) Test(t *testing.T, node ast.Node) bool {
 }
 
 type FunctionCall struct {
-	FunctionName Identifier
-	Arguments    []ExpressionNode
+	Function  ExpressionNode
+	Arguments []ExpressionNode
 }
 
 func (expected *FunctionCall) getTokenType() token.TokenType {
-	return token.IDENT
+	return token.LPAREN
 }
 
 func (expected *FunctionCall) getTokenLiteral() string {
-	return expected.FunctionName.Name
+	return "("
 }
 
 func (expected *FunctionCall) Test(t *testing.T, node ast.Node) bool {
@@ -189,8 +245,8 @@ func (expected *FunctionCall) Test(t *testing.T, node ast.Node) bool {
 		return !pass
 	}
 
-	if expected.FunctionName.Test(t, &fnCall.FunctionName) == !pass {
-		t.Errorf("Incorrect Function Name:" + fnCall.FunctionName.ToString())
+	if expected.Function.Test(t, fnCall.Function) == !pass {
+		t.Errorf("Incorrect Function: %s", fnCall.Function.ToString())
 		return !pass
 	}
 
@@ -294,6 +350,37 @@ func (expected *Infix) Test(t *testing.T, node ast.Node) bool {
 	return expected.Left.Test(t, inFix.Left) && expected.Right.Test(t, inFix.Right)
 }
 
+type Logical struct {
+	OperatorType token.TokenType
+	Left         ExpressionNode
+	Right        ExpressionNode
+}
+
+func (expected *Logical) getTokenType() token.TokenType {
+	return expected.OperatorType
+}
+
+func (expected *Logical) getTokenLiteral() string {
+	return string(expected.OperatorType)
+}
+
+func (expected *Logical) Test(t *testing.T, node ast.Node) bool {
+	logical, ok := node.(*ast.Logical)
+	if !ok {
+		t.Errorf("Expected Logical. got %T expression", node)
+		return false
+	}
+
+	if logical.GetOperatorType() != expected.OperatorType {
+		t.Errorf("Expected logical.Operator.Type = %s. got = %s",
+			expected.getTokenLiteral(), logical.GetOperatorType(),
+		)
+		return false
+	}
+
+	return expected.Left.Test(t, logical.Left) && expected.Right.Test(t, logical.Right)
+}
+
 type Trinary struct {
 	Left   ExpressionNode
 	Middle ExpressionNode
@@ -341,3 +428,141 @@ func (expected *Trinary) Test(t *testing.T, node ast.Node) bool {
 		expected.Middle.Test(t, trinary.Middle) &&
 		expected.Right.Test(t, trinary.Right))
 }
+
+type ArrayLiteral struct {
+	Elements []ExpressionNode
+}
+
+func (expected *ArrayLiteral) getTokenType() token.TokenType {
+	return token.LBRACKET
+}
+
+func (expected *ArrayLiteral) getTokenLiteral() string {
+	return "["
+}
+
+func (expected *ArrayLiteral) Test(t *testing.T, node ast.Node) bool {
+	arrLit, ok := node.(*ast.ArrayLiteral)
+	if !ok {
+		t.Errorf("Expected ArrayLiteral. got %T expression", node)
+		return false
+	}
+
+	if len(arrLit.Elements) != len(expected.Elements) {
+		t.Errorf("Invalid ArrayLiteral: Expected %d elements. got %d",
+			len(expected.Elements), len(arrLit.Elements),
+		)
+		return false
+	}
+
+	for i, element := range expected.Elements {
+		if !element.Test(t, arrLit.Elements[i]) {
+			t.Errorf("Incorrect element %d", i)
+			return false
+		}
+	}
+	return true
+}
+
+type IndexExpression struct {
+	Left  ExpressionNode
+	Index ExpressionNode
+}
+
+func (expected *IndexExpression) getTokenType() token.TokenType {
+	return token.LBRACKET
+}
+
+func (expected *IndexExpression) getTokenLiteral() string {
+	return "["
+}
+
+func (expected *IndexExpression) Test(t *testing.T, node ast.Node) bool {
+	indexOp, ok := node.(*ast.IndexExpression)
+	if !ok {
+		t.Errorf("Expected IndexExpression. got %T expression", node)
+		return false
+	}
+
+	return expected.Left.Test(t, indexOp.Left) && expected.Index.Test(t, indexOp.Index)
+}
+
+// SliceExpression expects Start or End to be absent when they are nil.
+type SliceExpression struct {
+	Left  ExpressionNode
+	Start ExpressionNode
+	End   ExpressionNode
+}
+
+func (expected *SliceExpression) getTokenType() token.TokenType {
+	return token.COLON
+}
+
+func (expected *SliceExpression) getTokenLiteral() string {
+	return ":"
+}
+
+func (expected *SliceExpression) Test(t *testing.T, node ast.Node) bool {
+	sliceOp, ok := node.(*ast.SliceExpression)
+	if !ok {
+		t.Errorf("Expected SliceExpression. got %T expression", node)
+		return false
+	}
+
+	if !expected.Left.Test(t, sliceOp.Left) {
+		return false
+	}
+	return testOptionalNode(t, "Start", expected.Start, sliceOp.Start) &&
+		testOptionalNode(t, "End", expected.End, sliceOp.End)
+}
+
+func testOptionalNode(t *testing.T, name string, expected ExpressionNode, node ast.ExpressionNode) bool {
+	if expected == nil || node == nil {
+		if expected != nil || node != nil {
+			t.Errorf("Expected %s to be present only if expected. got %v", name, node)
+			return false
+		}
+		return true
+	}
+	return expected.Test(t, node)
+}
+
+type HashPair struct {
+	Key   ExpressionNode
+	Value ExpressionNode
+}
+
+type HashLiteral struct {
+	Pairs []HashPair
+}
+
+func (expected *HashLiteral) getTokenType() token.TokenType {
+	return token.LBRACE
+}
+
+func (expected *HashLiteral) getTokenLiteral() string {
+	return "{"
+}
+
+func (expected *HashLiteral) Test(t *testing.T, node ast.Node) bool {
+	hashLit, ok := node.(*ast.HashLiteral)
+	if !ok {
+		t.Errorf("Expected HashLiteral. got %T expression", node)
+		return false
+	}
+
+	if len(hashLit.Pairs) != len(expected.Pairs) {
+		t.Errorf("Invalid HashLiteral: Expected %d pairs. got %d",
+			len(expected.Pairs), len(hashLit.Pairs),
+		)
+		return false
+	}
+
+	for i, pair := range expected.Pairs {
+		if !pair.Key.Test(t, hashLit.Pairs[i].Key) || !pair.Value.Test(t, hashLit.Pairs[i].Value) {
+			t.Errorf("Incorrect pair %d", i)
+			return false
+		}
+	}
+	return true
+}
@@ -76,6 +76,33 @@ func (expected *ReturnStatement) Test(t *testing.T, node ast.Node) bool {
 	return expected.Expression.Test(t, returnStmt.ReturnValue)
 }
 
+type ExpressionStatement struct {
+	Expression ExpressionNode
+}
+
+func (expected *ExpressionStatement) getTokenType() token.TokenType {
+	return expected.Expression.getTokenType()
+}
+
+func (expected *ExpressionStatement) getTokenLiteral() string {
+	return expected.Expression.getTokenLiteral()
+}
+
+func (expected *ExpressionStatement) Test(t *testing.T, node ast.Node) bool {
+	exprStmt, ok := node.(*ast.ExpressionStatement)
+	if !ok {
+		t.Errorf("Expression statement not found. Got %q token", node.GetTokenType())
+		return false
+	}
+
+	if exprStmt.Expression == nil {
+		t.Errorf("Invalid Expression statement: Expression is nil")
+		return false
+	}
+
+	return expected.Expression.Test(t, exprStmt.Expression)
+}
+
 type BlockStatement struct {
 	Statements []StatementNode
 }
@@ -197,3 +224,142 @@ func (expected *ElseStatement) Test(t *testing.T, node ast.Node) bool {
 func NewElseIfStatement(condition ExpressionNode, stmt StatementNode, elseNode *ElseStatement) *ElseStatement {
 	return &ElseStatement{&IfStatement{condition, stmt, elseNode}}
 }
+
+type WhileStatement struct {
+	Condition ExpressionNode
+	Body      *BlockStatement
+}
+
+func (expected *WhileStatement) getTokenType() token.TokenType {
+	return token.WHILE
+}
+
+func (expected *WhileStatement) getTokenLiteral() string {
+	return "while"
+}
+
+func (expected *WhileStatement) Test(t *testing.T, node ast.Node) bool {
+	whileStmt, ok := node.(*ast.WhileStatement)
+	if !ok {
+		t.Errorf("While statement not found. Got %q token", node.GetTokenType())
+		return false
+	}
+
+	if !expected.Condition.Test(t, whileStmt.Condition) {
+		t.Errorf("Invalid While statement: Incorrect condition")
+		return false
+	}
+
+	if !expected.Body.Test(t, whileStmt.Body) {
+		t.Errorf("Invalid While statement: Incorrect body")
+		return false
+	}
+	return true
+}
+
+type ForStatement struct {
+	Variable string
+	Iterable ExpressionNode
+	Body     *BlockStatement
+}
+
+func (expected *ForStatement) getTokenType() token.TokenType {
+	return token.FOR
+}
+
+func (expected *ForStatement) getTokenLiteral() string {
+	return "for"
+}
+
+func (expected *ForStatement) Test(t *testing.T, node ast.Node) bool {
+	forStmt, ok := node.(*ast.ForStatement)
+	if !ok {
+		t.Errorf("For statement not found. Got %q token", node.GetTokenType())
+		return false
+	}
+
+	if forStmt.Variable.GetName() != expected.Variable {
+		t.Errorf("forStmt.Variable not %s. got=%s", expected.Variable, forStmt.Variable.GetName())
+		return false
+	}
+
+	if !expected.Iterable.Test(t, forStmt.Iterable) {
+		t.Errorf("Invalid For statement: Incorrect iterable")
+		return false
+	}
+
+	if !expected.Body.Test(t, forStmt.Body) {
+		t.Errorf("Invalid For statement: Incorrect body")
+		return false
+	}
+	return true
+}
+
+type BreakStatement struct{}
+
+func (expected *BreakStatement) getTokenType() token.TokenType {
+	return token.BREAK
+}
+
+func (expected *BreakStatement) getTokenLiteral() string {
+	return "break"
+}
+
+func (expected *BreakStatement) Test(t *testing.T, node ast.Node) bool {
+	if _, ok := node.(*ast.BreakStatement); !ok {
+		t.Errorf("Break statement not found. Got %q token", node.GetTokenType())
+		return false
+	}
+	return true
+}
+
+type ContinueStatement struct{}
+
+func (expected *ContinueStatement) getTokenType() token.TokenType {
+	return token.CONTINUE
+}
+
+func (expected *ContinueStatement) getTokenLiteral() string {
+	return "continue"
+}
+
+func (expected *ContinueStatement) Test(t *testing.T, node ast.Node) bool {
+	if _, ok := node.(*ast.ContinueStatement); !ok {
+		t.Errorf("Continue statement not found. Got %q token", node.GetTokenType())
+		return false
+	}
+	return true
+}
+
+type Assignment struct {
+	Target   ExpressionNode
+	Operator token.TokenType
+	Value    ExpressionNode
+}
+
+func (expected *Assignment) getTokenType() token.TokenType {
+	return expected.Operator
+}
+
+func (expected *Assignment) getTokenLiteral() string {
+	return string(expected.Operator)
+}
+
+func (expected *Assignment) Test(t *testing.T, node ast.Node) bool {
+	assign, ok := node.(*ast.Assignment)
+	if !ok {
+		t.Errorf("Assignment not found. Got %q token", node.GetTokenType())
+		return false
+	}
+
+	if assign.Operator.Type != expected.Operator {
+		t.Errorf("assign.Operator not %s. got=%s", expected.Operator, assign.Operator.Type)
+		return false
+	}
+
+	if !expected.Target.Test(t, assign.Target) {
+		t.Errorf("Invalid Assignment: Incorrect target")
+		return false
+	}
+	return expected.Value.Test(t, assign.Value)
+}
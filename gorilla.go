@@ -0,0 +1,253 @@
+// Package gorilla embeds the Gorilla interpreter in Go programs.
+//
+// An Interpreter compiles source into a Program once, and runs it as often
+// as needed, each time in a fresh global scope:
+//
+//	interp := gorilla.NewInterpreter(gorilla.Options{})
+//	interp.Register("lookup", func(id int64) (string, error) { ... })
+//
+//	prog, err := interp.Compile(`lookup(user["id"]) == "admin";`)
+//	result, err := interp.Run(ctx, prog, map[string]any{"user": user})
+//
+// Go values passed in are converted with ToObject, and results can be
+// converted back with FromObject.
+package gorilla
+
+import (
+	"context"
+	"fmt"
+	"gorilla/ast"
+	"gorilla/eval"
+	"gorilla/lexer"
+	"gorilla/object"
+	"gorilla/parser"
+	"gorilla/token"
+	"io"
+	"reflect"
+	"sync"
+)
+
+// Options configure an Interpreter.
+type Options struct {
+	// Stdout receives the output of print. It defaults to os.Stdout.
+	Stdout io.Writer
+
+	// Limits bound every Run and Call. A run that hits one fails with a
+	// *RuntimeError naming it.
+	Limits object.Limits
+}
+
+// Interpreter compiles and runs programs. The functions and variables
+// registered on it are visible to every program it runs, but a program
+// cannot change them for the next one.
+//
+// Its methods are safe for concurrent use, and a Program can be run by
+// several goroutines at once, as long as the Go functions registered are
+// safe for concurrent use too. What runs share is not protected though: the
+// values a run returns, such as functions, keep the state of the run and
+// must not be used by two Calls at once, and an object.Object passed to Set
+// must not be changed by runs that overlap.
+type Interpreter struct {
+	opts Options
+
+	mu      sync.RWMutex
+	globals map[string]any // registered with Register and Set
+}
+
+func NewInterpreter(opts Options) *Interpreter {
+	interp := &Interpreter{
+		opts:    opts,
+		globals: map[string]any{},
+	}
+	if opts.Stdout != nil {
+		interp.globals["print"] = &object.Builtin{Name: "print", Fn: func(args ...object.Object) object.Object {
+			return eval.Print(opts.Stdout, args...)
+		}}
+	}
+	return interp
+}
+
+// Program is parsed source, ready to run.
+type Program struct {
+	prog *ast.Program
+	file *token.File // locates the runtime errors of the program
+
+	// Warnings are the problems found by the parser that do not stop the
+	// program from running, such as a match without a wildcard arm.
+	Warnings parser.ErrorList
+}
+
+// RuntimeError is an error raised by a running program.
+type RuntimeError struct {
+	Message  string
+	Position token.Position // invalid for errors outside of any source, like calling a non-function
+	Limit    object.Limit   // the limit that stopped the program, or object.NoLimit
+
+	err error // the context error, when the context stopped the program
+}
+
+func (err *RuntimeError) Error() string {
+	if !err.Position.IsValid() {
+		return err.Message
+	}
+	return err.Position.String() + ": " + err.Message
+}
+
+// Unwrap returns context.Canceled or context.DeadlineExceeded when the
+// context of the run stopped it.
+func (err *RuntimeError) Unwrap() error {
+	return err.err
+}
+
+// Compile parses src. The error is a parser.ErrorList listing every syntax
+// error.
+func (interp *Interpreter) Compile(src string) (*Program, error) {
+	file := token.NewFileSet().AddFile("", len(src))
+	p := parser.NewParser(lexer.NewFileLexer(file, src))
+	prog, ok := p.ParseProgram()
+	if !ok {
+		return nil, p.Errors
+	}
+	return &Program{prog: prog, file: file, Warnings: p.Warnings}, nil
+}
+
+// Register makes the Go function fn callable as name. The arguments are
+// converted to the parameter types of fn with FromObject, and a variadic
+// fn accepts any number of trailing arguments. fn may return nothing, a
+// value, an error, or a value and an error; a non-nil error is raised as a
+// runtime error in the program. A user binding of the same name shadows
+// fn, as it does the builtins.
+func (interp *Interpreter) Register(name string, fn any) error {
+	if !isIdentifier(name) {
+		return fmt.Errorf("invalid name %q", name)
+	}
+	fnValue := reflect.ValueOf(fn)
+	if fnValue.Kind() != reflect.Func || fnValue.IsNil() {
+		return fmt.Errorf("%s: expected a function, got %T", name, fn)
+	}
+
+	builtin, err := hostFunction(name, fnValue)
+	if err != nil {
+		return err
+	}
+
+	interp.mu.Lock()
+	defer interp.mu.Unlock()
+	interp.globals[name] = builtin
+	return nil
+}
+
+// Set defines the variable name for every program. value is converted with
+// ToObject anew for each run, so one run cannot change it for the next. An
+// object.Object is kept as is though, so an array or hash set that way is
+// shared by every run, along with the changes they make to it.
+func (interp *Interpreter) Set(name string, value any) error {
+	if !isIdentifier(name) {
+		return fmt.Errorf("invalid name %q", name)
+	}
+	if _, err := ToObject(value); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+
+	interp.mu.Lock()
+	defer interp.mu.Unlock()
+	interp.globals[name] = value
+	return nil
+}
+
+// Run runs prog with the registered functions and variables, and globals,
+// which take precedence over them. It returns the value of the last
+// statement, or of a top-level return. A failing program returns a
+// *RuntimeError, as does a program stopped by ctx or by the limits of the
+// interpreter.
+func (interp *Interpreter) Run(ctx context.Context, prog *Program, globals map[string]any) (object.Object, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	env := object.NewEnvironment()
+	interp.mu.RLock()
+	for name, value := range interp.globals {
+		obj, err := ToObject(value)
+		if err != nil {
+			interp.mu.RUnlock()
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		env.Set(name, obj)
+	}
+	interp.mu.RUnlock()
+
+	for name, value := range globals {
+		if !isIdentifier(name) {
+			return nil, fmt.Errorf("invalid name %q", name)
+		}
+		obj, err := ToObject(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		env.Set(name, obj)
+	}
+
+	budget := object.NewBudget(ctx, interp.opts.Limits)
+	env.SetBudget(budget)
+	env.SetFile(prog.file)
+	return result(prog.file, budget, eval.EvalProgram(prog.prog, env))
+}
+
+// Call calls fn, a function value returned by a run, with args converted by
+// ToObject. The call gets limits of its own, independent of the run that
+// defined fn.
+func (interp *Interpreter) Call(ctx context.Context, fn object.Object, args ...any) (object.Object, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	objs := make([]object.Object, len(args))
+	for i, arg := range args {
+		obj, err := ToObject(arg)
+		if err != nil {
+			return nil, fmt.Errorf("argument %d: %w", i+1, err)
+		}
+		objs[i] = obj
+	}
+
+	budget := object.NewBudget(ctx, interp.opts.Limits)
+	obj := eval.Apply(budget, fn, objs)
+	if errObj := budget.Check(); errObj != nil && obj.GetType() != object.ERROR {
+		obj = errObj
+	}
+
+	// the errors raised in fn are located in the program that defined it
+	var file *token.File
+	if fnObj, ok := fn.(*object.Function); ok {
+		file = fnObj.Env.File()
+	}
+	return result(file, budget, obj)
+}
+
+// result turns an error object raised by the code of file into a
+// *RuntimeError.
+func result(file *token.File, budget *object.Budget, obj object.Object) (object.Object, error) {
+	errObj, ok := obj.(*object.Error)
+	if !ok {
+		return obj, nil
+	}
+
+	runtimeErr := &RuntimeError{
+		Message: errObj.Message,
+		Limit:   errObj.Limit,
+	}
+	if file != nil {
+		runtimeErr.Position = file.Position(errObj.Span.Start)
+	}
+	if errObj.Limit == object.ContextDone {
+		runtimeErr.err = budget.Err()
+	}
+	return nil, runtimeErr
+}
+
+// isIdentifier reports whether name can be used as a variable in a program.
+func isIdentifier(name string) bool {
+	tok := lexer.NewLexer(name).GetNextToken()
+	return tok.Type == token.IDENT && tok.Literal == name
+}
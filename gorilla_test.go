@@ -0,0 +1,494 @@
+package gorilla
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"gorilla/object"
+	"gorilla/parser"
+	"math/big"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type user struct {
+	Name   string
+	Age    int
+	Admin  bool     `gorilla:"admin"`
+	Tags   []string `gorilla:"tags"`
+	secret string
+	Skip   string `gorilla:"-"`
+}
+
+func TestRun(t *testing.T) {
+	interp := NewInterpreter(Options{})
+	if err := interp.Set("limit", 18); err != nil {
+		t.Fatal(err)
+	}
+
+	prog, err := interp.Compile(`user["Age"] >= limit && !user["admin"] && len(user["tags"]) == 2;`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		user     user
+		expected bool
+	}{
+		{user{Name: "ada", Age: 36, Tags: []string{"a", "b"}}, true},
+		{user{Name: "bob", Age: 12, Tags: []string{"a", "b"}}, false},
+		{user{Name: "eve", Age: 40, Admin: true, Tags: []string{"a", "b"}}, false},
+	}
+
+	for _, tt := range tests {
+		result, err := interp.Run(context.Background(), prog, map[string]any{"user": tt.user})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var allowed bool
+		if err := FromObject(result, &allowed); err != nil {
+			t.Fatal(err)
+		}
+		if allowed != tt.expected {
+			t.Errorf("%s: expected %t. got %t", tt.user.Name, tt.expected, allowed)
+		}
+	}
+}
+
+func TestRunIsolation(t *testing.T) {
+	interp := NewInterpreter(Options{})
+	interp.Set("xs", []int64{1})
+
+	prog, err := interp.Compile("xs[0] += 1; let y = xs[0]; y;")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		result, err := interp.Run(context.Background(), prog, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Inspect() != "2" {
+			t.Errorf("run %d: expected 2. got %s", i, result.Inspect())
+		}
+	}
+}
+
+func TestConcurrentRuns(t *testing.T) {
+	interp := NewInterpreter(Options{Limits: object.Limits{MaxSteps: 100000}})
+	interp.Set("xs", []int64{1})
+	interp.Register("double", func(n int64) int64 { return 2 * n })
+
+	prog, err := interp.Compile(`
+		xs[0] += n;
+		let total = 0;
+		for (x in range(n)) { total += double(x); }
+		fn(m) { return xs[0] + total + m; };
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for n := int64(0); n < 8; n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			fn, err := interp.Run(context.Background(), prog, map[string]any{"n": n})
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			result, err := interp.Call(context.Background(), fn, 1)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if expected := fmt.Sprint(2 + n + n*(n-1)); result.Inspect() != expected {
+				t.Errorf("n=%d: expected %s. got %s", n, expected, result.Inspect())
+			}
+		}()
+		// registering and setting other names does not disturb the runs
+		interp.Set(fmt.Sprintf("v%d", n), n)
+	}
+	wg.Wait()
+}
+
+func TestCompileErrors(t *testing.T) {
+	interp := NewInterpreter(Options{})
+
+	_, err := interp.Compile("let x = ;\nlet y 1;")
+	var errs parser.ErrorList
+	if !errors.As(err, &errs) || len(errs) != 2 {
+		t.Fatalf("Expected 2 parser errors. got %v", err)
+	}
+
+	prog, err := interp.Compile("match (1) { 1 => 2 };")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(prog.Warnings) != 1 {
+		t.Errorf("Expected 1 warning. got %d", len(prog.Warnings))
+	}
+}
+
+func TestRuntimeErrors(t *testing.T) {
+	interp := NewInterpreter(Options{})
+	interp.Compile("let unrelated = 1;")
+
+	prog, err := interp.Compile("let f = fn(x) {\n\treturn x / 0;\n};\nf(1);")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = interp.Run(context.Background(), prog, nil)
+	var runtimeErr *RuntimeError
+	if !errors.As(err, &runtimeErr) {
+		t.Fatalf("Expected a RuntimeError. got %v", err)
+	}
+	if runtimeErr.Error() != "2:9: division by zero" {
+		t.Errorf("Unexpected error %q", runtimeErr.Error())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := interp.Run(ctx, prog, nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled. got %v", err)
+	}
+}
+
+func TestLimits(t *testing.T) {
+	interp := NewInterpreter(Options{Limits: object.Limits{MaxSteps: 1000, MaxDepth: 10}})
+
+	tests := []struct {
+		input    string
+		limit    object.Limit
+		expected string
+	}{
+		{"while (True) {}", object.StepLimit, "1:14: step limit exceeded: 1000 steps"},
+		{"let f = fn(n) { return f(n + 1); };\nf(0);", object.DepthLimit, "1:24: maximum recursion depth exceeded"},
+	}
+
+	for _, tt := range tests {
+		prog, err := interp.Compile(tt.input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = interp.Run(context.Background(), prog, nil)
+		var runtimeErr *RuntimeError
+		if !errors.As(err, &runtimeErr) {
+			t.Fatalf("%s: expected a RuntimeError. got %v", tt.input, err)
+		}
+		if runtimeErr.Limit != tt.limit || runtimeErr.Error() != tt.expected {
+			t.Errorf("%s: expected %s %q. got %s %q", tt.input, tt.limit, tt.expected, runtimeErr.Limit, runtimeErr.Error())
+		}
+	}
+
+	prog, err := interp.Compile("let i = 0; while (i < 10) { i += 1; } i;")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := interp.Run(context.Background(), prog, nil); err != nil {
+		t.Errorf("Expected every run to get the full budget. got %v", err)
+	}
+
+	// a call that overran its time fails, even when nothing checked it
+	prog, err = interp.Compile("fn() { return 1; };")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fn, err := interp.Run(context.Background(), prog, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	interp = NewInterpreter(Options{Limits: object.Limits{MaxDuration: time.Nanosecond}})
+	_, err = interp.Call(context.Background(), fn)
+	var runtimeErr *RuntimeError
+	if !errors.As(err, &runtimeErr) || runtimeErr.Limit != object.TimeLimit {
+		t.Errorf("Expected the time limit to be hit. got %v", err)
+	}
+}
+
+func TestContextDeadline(t *testing.T) {
+	interp := NewInterpreter(Options{})
+	prog, err := interp.Compile("let spin = fn() { while (True) {} }; spin;")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	spin, err := interp.Run(ctx, prog, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = interp.Call(ctx, spin)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected context.DeadlineExceeded. got %v", err)
+	}
+	var runtimeErr *RuntimeError
+	if !errors.As(err, &runtimeErr) || runtimeErr.Limit != object.ContextDone {
+		t.Errorf("Expected a RuntimeError stopped by the context. got %v", err)
+	}
+}
+
+func TestCall(t *testing.T) {
+	interp := NewInterpreter(Options{})
+	prog, err := interp.Compile(`let greet = fn(u) { return "hi " + u["Name"]; }; greet;`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	greet, err := interp.Run(context.Background(), prog, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := interp.Call(context.Background(), greet, user{Name: "ada"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Inspect() != `"hi ada"` {
+		t.Errorf(`Expected "hi ada". got %s`, result.Inspect())
+	}
+
+	if _, err := interp.Call(context.Background(), greet, map[string]int{"Name": 1}); err == nil ||
+		err.Error() != "1:28: type mismatch: STRING + INT" {
+		t.Errorf("Unexpected error %v", err)
+	}
+	if _, err := interp.Call(context.Background(), greet); err == nil ||
+		err.Error() != "wrong number of arguments: expected 1, got 0" {
+		t.Errorf("Unexpected error %v", err)
+	}
+	if _, err := interp.Call(context.Background(), &object.Int{Value: 1}); err == nil ||
+		err.Error() != "not a function: INT" {
+		t.Errorf("Unexpected error %v", err)
+	}
+}
+
+func TestHostFunctions(t *testing.T) {
+	interp := NewInterpreter(Options{})
+	register := func(name string, fn any) {
+		if err := interp.Register(name, fn); err != nil {
+			t.Fatal(err)
+		}
+	}
+	register("double", func(x int64) int64 { return 2 * x })
+	register("join", func(sep string, words ...string) string { return strings.Join(words, sep) })
+	register("lookup", func(id int) (user, error) {
+		if id != 1 {
+			return user{}, errors.New("no such user")
+		}
+		return user{Name: "ada", Age: 36}, nil
+	})
+	register("check", func(u user) bool { return u.Age > 30 })
+	register("raw", func(args ...object.Object) object.Object { return &object.Int{Value: int64(len(args))} })
+	register("boom", func() { panic("oops") })
+	register("scale", func(xs []float64, k float64) []float64 {
+		for i := range xs {
+			xs[i] *= k
+		}
+		return xs
+	})
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"double(21);", "42"},
+		{`join("-", "a", "b", "c");`, `"a-b-c"`},
+		{`join(",");`, `""`},
+		{`lookup(1)["Name"];`, `"ada"`},
+		{`check({"Age": 40});`, "True"},
+		{"raw(1, [2], {});", "3"},
+		{"scale([1, 2.5], 2);", "[2.0, 5.0]"},
+		{"let double = fn(x) { return x; }; double(2);", "2"},
+		{"type(double);", `"BUILTIN"`},
+	}
+
+	for _, tt := range tests {
+		prog, err := interp.Compile(tt.input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		result, err := interp.Run(context.Background(), prog, nil)
+		if err != nil {
+			t.Errorf("%s: %s", tt.input, err)
+			continue
+		}
+		if result.Inspect() != tt.expected {
+			t.Errorf("%s: expected %s. got %s", tt.input, tt.expected, result.Inspect())
+		}
+	}
+
+	errorTests := []struct {
+		input    string
+		expected string
+	}{
+		{"double();", "1:1: wrong number of arguments: expected 1, got 0"},
+		{"join();", "1:1: wrong number of arguments: expected at least 1, got 0"},
+		{`double("a");`, "1:1: argument 1 to double: cannot convert STRING to int64"},
+		{"lookup(2);", "1:1: no such user"},
+		{"boom();", "1:1: boom panicked: oops"},
+		{`check({"Age": "old"});`, "1:1: argument 1 to check: field Age: cannot convert STRING to int"},
+	}
+
+	for _, tt := range errorTests {
+		prog, err := interp.Compile(tt.input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = interp.Run(context.Background(), prog, nil)
+		if err == nil || err.Error() != tt.expected {
+			t.Errorf("%s: expected error %q. got %v", tt.input, tt.expected, err)
+		}
+	}
+
+	if err := interp.Register("let", func() {}); err == nil {
+		t.Errorf("Expected a keyword to be rejected as a name")
+	}
+	if err := interp.Register("f", 5); err == nil {
+		t.Errorf("Expected a non-function to be rejected")
+	}
+	if err := interp.Register("f", func() (int, int) { return 0, 0 }); err == nil {
+		t.Errorf("Expected unsupported results to be rejected")
+	}
+}
+
+func TestPrintOutput(t *testing.T) {
+	var out bytes.Buffer
+	interp := NewInterpreter(Options{Stdout: &out})
+
+	prog, err := interp.Compile(`print("a", 1); print([True]);`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := interp.Run(context.Background(), prog, nil); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "a 1\n[True]\n" {
+		t.Errorf("Unexpected output %q", out.String())
+	}
+}
+
+func TestToObject(t *testing.T) {
+	var nilUser *user
+	tests := []struct {
+		value    any
+		expected string
+	}{
+		{nil, "None"},
+		{nilUser, "None"},
+		{true, "True"},
+		{int8(-3), "-3"},
+		{uint64(1 << 63), "9223372036854775808"},
+		{big.NewInt(7), "7"},
+		{1.5, "1.5"},
+		{"s", `"s"`},
+		{[]int{1, 2}, "[1, 2]"},
+		{[2]bool{true, false}, "[True, False]"},
+		{map[string]int{"b": 2, "a": 1}, `{"a": 1, "b": 2}`},
+		{map[int]string{10: "x", 9: "y"}, `{9: "y", 10: "x"}`},
+		{&user{Name: "ada", Tags: []string{"x"}, secret: "s", Skip: "s"}, `{"Name": "ada", "Age": 0, "admin": False, "tags": ["x"]}`},
+		{&object.String{Value: "kept"}, `"kept"`},
+	}
+
+	for _, tt := range tests {
+		obj, err := ToObject(tt.value)
+		if err != nil {
+			t.Errorf("%#v: %s", tt.value, err)
+			continue
+		}
+		if obj.Inspect() != tt.expected {
+			t.Errorf("%#v: expected %s. got %s", tt.value, tt.expected, obj.Inspect())
+		}
+	}
+
+	if _, err := ToObject(make(chan int)); err == nil {
+		t.Errorf("Expected channels to be rejected")
+	}
+	if _, err := ToObject(map[[1]int]int{{1}: 1}); err == nil {
+		t.Errorf("Expected array keys to be rejected")
+	}
+
+	// a value met twice converts twice, one that contains itself fails
+	shared := []int{1}
+	if obj, err := ToObject([][]int{shared, shared}); err != nil || obj.Inspect() != "[[1], [1]]" {
+		t.Errorf("Expected [[1], [1]]. got %v, %v", obj, err)
+	}
+	slice := []any{1}
+	slice[0] = slice
+	hash := map[string]any{}
+	hash["self"] = hash
+	type node struct{ Next *node }
+	loop := &node{}
+	loop.Next = loop
+	cyclicTests := []struct {
+		value    any
+		expected string
+	}{
+		{slice, "cannot convert []interface {} that contains itself"},
+		{hash, "cannot convert map[string]interface {} that contains itself"},
+		{loop, "cannot convert *gorilla.node that contains itself"},
+	}
+	for _, tt := range cyclicTests {
+		if _, err := ToObject(tt.value); err == nil || err.Error() != tt.expected {
+			t.Errorf("Expected %q. got %v", tt.expected, err)
+		}
+	}
+}
+
+func TestFromObject(t *testing.T) {
+	obj, err := ToObject(map[string]any{
+		"Name": "ada",
+		"Age":  36,
+		"tags": []string{"a"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var u user
+	if err := FromObject(obj, &u); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(u, user{Name: "ada", Age: 36, Tags: []string{"a"}}) {
+		t.Errorf("Unexpected user %+v", u)
+	}
+
+	var natural any
+	if err := FromObject(obj, &natural); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]any{"Age": int64(36), "Name": "ada", "tags": []any{"a"}}
+	if !reflect.DeepEqual(natural, expected) {
+		t.Errorf("Unexpected value %#v", natural)
+	}
+
+	mixed, _ := ToObject(map[any]any{1: "one", "two": 2.0})
+	if err := FromObject(mixed, &natural); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(natural, map[any]any{int64(1): "one", "two": 2.0}) {
+		t.Errorf("Unexpected value %#v", natural)
+	}
+
+	var small int8
+	if err := FromObject(&object.Int{Value: 300}, &small); err == nil {
+		t.Errorf("Expected an overflow error")
+	}
+	var ptr *int
+	if err := FromObject(&object.None{}, &ptr); err != nil || ptr != nil {
+		t.Errorf("Expected None to set a nil pointer. got %v, %v", ptr, err)
+	}
+	if err := FromObject(&object.Int{Value: 5}, &ptr); err != nil || *ptr != 5 {
+		t.Errorf("Expected a pointer to 5. got %v, %v", ptr, err)
+	}
+	if err := FromObject(&object.Int{Value: 5}, small); err == nil {
+		t.Errorf("Expected a non-pointer target to be rejected")
+	}
+}
@@ -1,19 +1,55 @@
 package lexer
 
+import (
+	"gorilla/token"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
 type Lexer struct {
+	file        *token.File
 	input       string
 	pos         int
 	nextPos     int
 	currentChar byte
+
+	// Errors are the malformed tokens read so far.
+	Errors []*Error
+
+	keepComments bool
+	comments     []token.Comment // read since the last token
 }
 
+// NewLexer lexes input as an anonymous file in a FileSet of its own.
 func NewLexer(input string) *Lexer {
-	lx := &Lexer{input: input}
+	file := token.NewFileSet().AddFile("", len(input))
+	return NewFileLexer(file, input)
+}
+
+// NewFileLexer lexes input as the content of file, so that token positions
+// can be resolved through file or the FileSet it belongs to.
+func NewFileLexer(file *token.File, input string) *Lexer {
+	lx := &Lexer{file: file, input: input}
 	lx.readChar()
 	return lx
 }
 
+func (lx *Lexer) GetFile() *token.File {
+	return lx.file
+}
+
+// SetKeepComments makes the lexer attach comments to the tokens around
+// them as Leading and Trailing trivia, instead of dropping them.
+func (lx *Lexer) SetKeepComments(keep bool) {
+	lx.keepComments = keep
+}
+
 func (lx *Lexer) readChar() {
+	if lx.currentChar == '\n' {
+		lx.file.AddLine(lx.nextPos)
+	}
+
 	lx.pos = lx.nextPos
 	if lx.nextPos < len(lx.input) {
 		lx.currentChar = lx.input[lx.pos]
@@ -32,9 +68,11 @@ func (lx *Lexer) getNextChar() byte {
 	return lx.input[lx.nextPos]
 }
 
+// readIdentifier reads a name. It starts with a letter or '_' and may
+// continue with digits, eg. `_1` or `utf8`.
 func (lx *Lexer) readIdentifier() string {
 	startPos := lx.pos
-	for isValidLetter(lx.getNextChar()) {
+	for isValidLetter(lx.getNextChar()) || isNumber(lx.getNextChar()) {
 		lx.readChar()
 	}
 	return lx.input[startPos : lx.pos+1]
@@ -50,23 +88,235 @@ func isNumber(inputChar byte) bool {
 	return '0' <= inputChar && inputChar <= '9'
 }
 
-func (lx *Lexer) readInterger() string {
-	startPos := lx.pos
-	for isNumber(lx.getNextChar()) {
+// readNumber reads an integer or float literal. Integers may have a base
+// prefix, as in 0xFF, 0o17 and 0b1010, and any number may separate its
+// digits with '_'. The digits are only checked when the literal is
+// converted, so `0b12` is read whole and reported then. A float has a
+// fraction (3.14, .5), an exponent (1e9) or both.
+func (lx *Lexer) readNumber() (token.TokenType, string) {
+	input := lx.input
+	start := lx.pos
+	end := start
+	skipDigits := func(isDigit func(byte) bool) {
+		for end < len(input) && (isDigit(input[end]) || input[end] == '_') {
+			end++
+		}
+	}
+
+	tokenType := token.INT
+	if input[start] == '0' && start+1 < len(input) && strings.ContainsRune("xXoObB", rune(input[start+1])) {
+		end += 2
+		skipDigits(func(char byte) bool { return isValidLetter(char) || isNumber(char) })
+	} else {
+		skipDigits(isNumber)
+
+		if end+1 < len(input) && input[end] == '.' && isNumber(input[end+1]) {
+			tokenType = token.FLOAT
+			end++
+			skipDigits(isNumber)
+		}
+
+		if end < len(input) && (input[end] == 'e' || input[end] == 'E') {
+			exponent := end + 1
+			if exponent < len(input) && (input[exponent] == '+' || input[exponent] == '-') {
+				exponent++
+			}
+			if exponent < len(input) && isNumber(input[exponent]) {
+				tokenType = token.FLOAT
+				end = exponent
+				skipDigits(isNumber)
+			}
+		}
+	}
+
+	// stop on the last char of the literal, like the other tokens
+	for lx.pos < end-1 {
 		lx.readChar()
 	}
-	return lx.input[startPos : lx.pos+1]
+	return tokenType, input[start:end]
 }
 
+// readString reads a double-quoted string, starting on the opening quote and
+// stopping on the closing one, and returns its unescaped value. errMsg
+// describes the problem if the string is unterminated or has an invalid
+// escape sequence, the first one of which is reported.
+func (lx *Lexer) readString() (value string, errMsg string) {
+	var out strings.Builder
+	invalidEscape := func(start int) {
+		if errMsg == "" {
+			errMsg = "Invalid escape sequence " + lx.input[start:min(lx.pos+1, len(lx.input))]
+		}
+	}
+	for {
+		lx.readChar()
+		if lx.pos >= len(lx.input) {
+			return out.String(), `Unterminated string, expected "`
+		}
+
+		switch lx.currentChar {
+		case '"':
+			return out.String(), errMsg
+
+		case '\\':
+			escapeStart := lx.pos
+			lx.readChar()
+			switch lx.currentChar {
+			case 'n':
+				out.WriteByte('\n')
+			case 't':
+				out.WriteByte('\t')
+			case 'r':
+				out.WriteByte('\r')
+			case '"', '\\':
+				out.WriteByte(lx.currentChar)
+			case 'u':
+				r, valid := lx.readUnicodeEscape()
+				if !valid {
+					invalidEscape(escapeStart)
+				}
+				out.WriteRune(r)
+			default:
+				if lx.pos >= len(lx.input) {
+					return out.String(), `Unterminated string, expected "`
+				}
+				invalidEscape(escapeStart)
+			}
+
+		default:
+			out.WriteByte(lx.currentChar)
+		}
+	}
+}
+
+// readUnicodeEscape reads the {XXXX} part of a \u{XXXX} escape, stopping on
+// the closing brace.
+func (lx *Lexer) readUnicodeEscape() (rune, bool) {
+	if lx.getNextChar() != '{' {
+		return utf8.RuneError, false
+	}
+	lx.readChar()
+
+	startPos := lx.pos + 1
+	for isHexDigit(lx.getNextChar()) {
+		lx.readChar()
+	}
+	digits := lx.input[startPos : lx.pos+1]
+
+	if lx.getNextChar() != '}' {
+		return utf8.RuneError, false
+	}
+	lx.readChar()
+
+	value, err := strconv.ParseUint(digits, 16, 32)
+	if err != nil || len(digits) > 6 || !utf8.ValidRune(rune(value)) {
+		return utf8.RuneError, false
+	}
+	return rune(value), true
+}
+
+func isHexDigit(inputChar byte) bool {
+	return isNumber(inputChar) ||
+		'a' <= inputChar && inputChar <= 'f' ||
+		'A' <= inputChar && inputChar <= 'F'
+}
+
+// skip skips whitespace and comments. An unterminated block comment is
+// left for readToken to report.
 func (lx *Lexer) skip() {
-	for lx.currentChar == ' ' ||
-		lx.currentChar == '\t' ||
-		lx.currentChar == '\n' ||
-		lx.currentChar == '\r' {
+	for {
+		switch lx.currentChar {
+		case ' ', '\t', '\n', '\r':
+			lx.readChar()
+		case '/':
+			if !lx.skipComment() {
+				return
+			}
+		default:
+			return
+		}
+	}
+}
+
+// skipTrailing skips the spaces and comments after a token up to the end
+// of its line, and returns the comments.
+func (lx *Lexer) skipTrailing() []token.Comment {
+	var comments []token.Comment
+	for {
+		switch lx.currentChar {
+		case ' ', '\t', '\r':
+			lx.readChar()
+		case '/':
+			if !lx.skipComment() {
+				return comments
+			}
+			// skipComment queued it as a leading comment of the next token
+			last := len(lx.comments) - 1
+			comments = append(comments, lx.comments[last])
+			lx.comments = lx.comments[:last]
+		default:
+			return comments
+		}
+	}
+}
+
+// skipComment skips the comment starting on the current char and reports
+// whether there was one. If comments are kept, it is queued for the next
+// token.
+func (lx *Lexer) skipComment() bool {
+	length := lx.commentLength()
+	if length == 0 {
+		return false
+	}
+
+	startPos := lx.pos
+	for range length {
 		lx.readChar()
 	}
+	if lx.keepComments {
+		lx.comments = append(lx.comments, token.Comment{
+			Text: lx.input[startPos:lx.pos],
+			Span: token.Span{Start: lx.file.Pos(startPos), End: lx.file.Pos(lx.pos)},
+		})
+	}
+	return true
+}
+
+// commentLength returns the length of the comment starting on the current
+// char, or 0 if there is none or it is an unterminated block comment. A
+// line comment stops before the newline. Block comments nest, so
+// `/* a /* b */ c */` is a single comment.
+func (lx *Lexer) commentLength() int {
+	rest := lx.input[lx.pos:]
+
+	if strings.HasPrefix(rest, "//") {
+		if end := strings.IndexByte(rest, '\n'); end >= 0 {
+			return end
+		}
+		return len(rest)
+	}
+
+	if !strings.HasPrefix(rest, "/*") {
+		return 0
+	}
+	depth := 0
+	for i := 0; i+1 < len(rest); i++ {
+		switch rest[i : i+2] {
+		case "/*":
+			depth++
+			i++
+		case "*/":
+			depth--
+			i++
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return 0
 }
 
 func (lx *Lexer) Copy() *Lexer {
-	return NewLexer(lx.input)
+	copied := NewFileLexer(lx.file, lx.input)
+	copied.keepComments = lx.keepComments
+	return copied
 }
@@ -31,7 +31,7 @@ func testExpectedToken(t *testing.T, input string, tests []expected.Token) {
 }
 
 func TestNextToken(t *testing.T) {
-	testExpectedToken(t, `+-*/=(){},;`, []expected.Token{
+	testExpectedToken(t, `+-*/ =(){},;`, []expected.Token{
 		{token.PLUS, "+"},
 		{token.MINUS, "-"},
 		{token.ASTERISK, "*"},
@@ -104,7 +104,7 @@ func TestNextToken(t *testing.T) {
     x + y;
     };
     let result = add(five, ten);
-    !-/*5;
+    !-/ *5;
     5 < 10 > 5;
     if (5 < 10) {
     return True;
@@ -216,3 +216,270 @@ func TestNextToken(t *testing.T) {
 	})
 
 }
+
+func TestTokenPositions(t *testing.T) {
+	input := "let x = 5;\n  return x;\n"
+	file := token.NewFileSet().AddFile("test.gor", len(input))
+	lx := NewFileLexer(file, input)
+
+	tests := []struct {
+		literal  string
+		position string
+		length   int
+	}{
+		{"let", "test.gor:1:1", 3},
+		{"x", "test.gor:1:5", 1},
+		{"=", "test.gor:1:7", 1},
+		{"5", "test.gor:1:9", 1},
+		{";", "test.gor:1:10", 1},
+		{"return", "test.gor:2:3", 6},
+		{"x", "test.gor:2:10", 1},
+		{";", "test.gor:2:11", 1},
+		{"", "test.gor:2:13", 0},
+	}
+
+	for i, tt := range tests {
+		tok := lx.GetNextToken()
+		if tok.Literal != tt.literal {
+			t.Fatalf("tests[%d] - wrong literal. expected=%q, got %q",
+				i, tt.literal, tok.Literal,
+			)
+		}
+
+		position := file.Position(tok.Span.Start)
+		if position.String() != tt.position {
+			t.Errorf("tests[%d] - wrong position for %q. expected=%s, got %s",
+				i, tt.literal, tt.position, position,
+			)
+		}
+
+		if length := int(tok.Span.End - tok.Span.Start); length != tt.length {
+			t.Errorf("tests[%d] - wrong span length for %q. expected=%d, got %d",
+				i, tt.literal, tt.length, length,
+			)
+		}
+	}
+}
+
+func TestStrings(t *testing.T) {
+	testExpectedToken(t, `"foobar" "foo bar" "" "a\tb\nc" "say \"hi\"" "back\\slash" "\u{1F98D}\u{e9}"`, []expected.Token{
+		{token.STRING, "foobar"},
+		{token.STRING, "foo bar"},
+		{token.STRING, ""},
+		{token.STRING, "a\tb\nc"},
+		{token.STRING, `say "hi"`},
+		{token.STRING, `back\slash`},
+		{token.STRING, "\U0001F98D\u00e9"},
+		{token.EOF, ""},
+	})
+
+	testExpectedToken(t, `"bad \q escape"; "\u{110000}"; "\u{zz}"; "open`, []expected.Token{
+		{token.ILLEGAL, `"bad \q escape"`},
+		{token.SEMICOLON, ";"},
+		{token.ILLEGAL, `"\u{110000}"`},
+		{token.SEMICOLON, ";"},
+		{token.ILLEGAL, `"\u{zz}"`},
+		{token.SEMICOLON, ";"},
+		{token.ILLEGAL, `"open`},
+		{token.EOF, ""},
+	})
+}
+
+func TestStringErrors(t *testing.T) {
+	lx := NewLexer(`"bad \q escape"; "\u{110000}";` + "\n" + `"open`)
+	for tok := lx.GetNextToken(); tok.Type != token.EOF; tok = lx.GetNextToken() {
+	}
+
+	expectedErrs := []string{
+		`1:1: Invalid escape sequence \q`,
+		`1:18: Invalid escape sequence \u{110000}`,
+		`2:1: Unterminated string, expected "`,
+	}
+	if len(lx.Errors) != len(expectedErrs) {
+		t.Fatalf("Expected %d lexer errors. got %v", len(expectedErrs), lx.Errors)
+	}
+	for i, expectedErr := range expectedErrs {
+		if lx.Errors[i].Error() != expectedErr {
+			t.Errorf("Expected error %q. got %q", expectedErr, lx.Errors[i].Error())
+		}
+	}
+}
+
+func TestBrackets(t *testing.T) {
+	testExpectedToken(t, `[1, "a"][0:1]`, []expected.Token{
+		{token.LBRACKET, "["},
+		{token.INT, "1"},
+		{token.COMMA, ","},
+		{token.STRING, "a"},
+		{token.RBRACKET, "]"},
+		{token.LBRACKET, "["},
+		{token.INT, "0"},
+		{token.COLON, ":"},
+		{token.INT, "1"},
+		{token.RBRACKET, "]"},
+		{token.EOF, ""},
+	})
+}
+
+func TestIdentifiersWithDigits(t *testing.T) {
+	testExpectedToken(t, `_1 + utf8 - 2x`, []expected.Token{
+		{token.IDENT, "_1"},
+		{token.PLUS, "+"},
+		{token.IDENT, "utf8"},
+		{token.MINUS, "-"},
+		{token.INT, "2"},
+		{token.IDENT, "x"},
+		{token.EOF, ""},
+	})
+}
+
+func TestComments(t *testing.T) {
+	testExpectedToken(t, `// a line comment
+let x = 1; // after x
+/* a /* nested */ block */ x / 2 /**/;
+// comment at EOF`, []expected.Token{
+		{token.LET, "let"},
+		{token.IDENT, "x"},
+		{token.ASSIGN, "="},
+		{token.INT, "1"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "x"},
+		{token.SLASH, "/"},
+		{token.INT, "2"},
+		{token.SEMICOLON, ";"},
+		{token.EOF, ""},
+	})
+}
+
+func TestCommentTrivia(t *testing.T) {
+	input := "// doc\n/* more */ let x = 1; // x\n/* end */"
+	lx := NewLexer(input)
+	lx.SetKeepComments(true)
+
+	tests := []struct {
+		literal  string
+		leading  []string
+		trailing []string
+	}{
+		{"let", []string{"// doc", "/* more */"}, nil},
+		{"x", nil, nil},
+		{"=", nil, nil},
+		{"1", nil, nil},
+		{";", nil, []string{"// x"}},
+		{"", []string{"/* end */"}, nil},
+	}
+
+	for i, tt := range tests {
+		tok := lx.GetNextToken()
+		if tok.Literal != tt.literal {
+			t.Fatalf("tests[%d] - wrong literal. expected=%q, got %q",
+				i, tt.literal, tok.Literal,
+			)
+		}
+		testComments(t, input, lx.GetFile(), tok.Leading, tt.leading)
+		testComments(t, input, lx.GetFile(), tok.Trailing, tt.trailing)
+	}
+}
+
+func testComments(t *testing.T, input string, file *token.File, comments []token.Comment, expected []string) {
+	t.Helper()
+	if len(comments) != len(expected) {
+		t.Fatalf("Expected comments %q. got %v", expected, comments)
+	}
+	for i, comment := range comments {
+		if comment.Text != expected[i] {
+			t.Errorf("comments[%d] - expected %q. got %q", i, expected[i], comment.Text)
+		}
+		source := input[file.Offset(comment.Span.Start):file.Offset(comment.Span.End)]
+		if source != comment.Text {
+			t.Errorf("comments[%d] - span covers %q, not %q", i, source, comment.Text)
+		}
+	}
+}
+
+func TestUnterminatedComment(t *testing.T) {
+	input := "let x = 1;\n/* open /* nested */ still open"
+	testExpectedToken(t, input, []expected.Token{
+		{token.LET, "let"},
+		{token.IDENT, "x"},
+		{token.ASSIGN, "="},
+		{token.INT, "1"},
+		{token.SEMICOLON, ";"},
+		{token.ILLEGAL, "/* open /* nested */ still open"},
+		{token.EOF, ""},
+	})
+
+	lx := NewLexer(input)
+	for tok := lx.GetNextToken(); tok.Type != token.EOF; tok = lx.GetNextToken() {
+	}
+	if len(lx.Errors) != 1 {
+		t.Fatalf("Expected 1 lexer error. got %v", lx.Errors)
+	}
+	expectedErr := "2:1: Unterminated block comment, expected */"
+	if lx.Errors[0].Error() != expectedErr {
+		t.Errorf("Expected error %q. got %q", expectedErr, lx.Errors[0].Error())
+	}
+}
+
+func TestNumbers(t *testing.T) {
+	testExpectedToken(t, `0xFF 0o17 0b1010 1_000_000 3.14 1e9 .5 2.5E-3 1.x 1e 0b12`, []expected.Token{
+		{token.INT, "0xFF"},
+		{token.INT, "0o17"},
+		{token.INT, "0b1010"},
+		{token.INT, "1_000_000"},
+		{token.FLOAT, "3.14"},
+		{token.FLOAT, "1e9"},
+		{token.FLOAT, ".5"},
+		{token.FLOAT, "2.5E-3"},
+		// a dot or an e that is not part of the number is left alone
+		{token.INT, "1"},
+		{token.ILLEGAL, "ILLEGAL"},
+		{token.IDENT, "x"},
+		{token.INT, "1"},
+		{token.IDENT, "e"},
+		// invalid digits are reported when the literal is converted
+		{token.INT, "0b12"},
+		{token.EOF, ""},
+	})
+}
+
+func TestOperators(t *testing.T) {
+	testExpectedToken(t, `% ** * & && | || ^ ~ << <= < >> >= >`, []expected.Token{
+		{token.PERCENT, "%"},
+		{token.POWER, "**"},
+		{token.ASTERISK, "*"},
+		{token.BIT_AND, "&"},
+		{token.AND, "&&"},
+		{token.BIT_OR, "|"},
+		{token.OR, "||"},
+		{token.BIT_XOR, "^"},
+		{token.BIT_NOT, "~"},
+		{token.SHIFT_LEFT, "<<"},
+		{token.LE, "<="},
+		{token.LT, "<"},
+		{token.SHIFT_RIGHT, ">>"},
+		{token.GE, ">="},
+		{token.GT, ">"},
+		{token.EOF, ""},
+	})
+
+	testExpectedToken(t, `=> == =`, []expected.Token{
+		{token.ARROW, "=>"},
+		{token.EQ, "=="},
+		{token.ASSIGN, "="},
+		{token.EOF, ""},
+	})
+
+	testExpectedToken(t, `= += -= *= /= **= == -`, []expected.Token{
+		{token.ASSIGN, "="},
+		{token.PLUS_ASSIGN, "+="},
+		{token.MINUS_ASSIGN, "-="},
+		{token.ASTERISK_ASSIGN, "*="},
+		{token.SLASH_ASSIGN, "/="},
+		{token.POWER, "**"},
+		{token.ASSIGN, "="},
+		{token.EQ, "=="},
+		{token.MINUS, "-"},
+		{token.EOF, ""},
+	})
+}
@@ -3,15 +3,33 @@ package lexer
 import "gorilla/token"
 
 func (lx *Lexer) GetNextToken() token.Token {
+	lx.skip()
+	startPos := lx.pos
+
+	tok := lx.readToken()
+	// currentChar is still the last char of tok at this point
+	tok.Span = token.Span{
+		Start: lx.file.Pos(startPos),
+		End:   lx.file.Pos(min(lx.pos+1, len(lx.input))),
+	}
+	lx.readChar()
+
+	if lx.keepComments {
+		tok.Leading = lx.comments
+		lx.comments = nil
+		tok.Trailing = lx.skipTrailing()
+	}
+	return tok
+}
+
+func (lx *Lexer) readToken() token.Token {
 	var nextTokenType token.TokenType
-	defer lx.readChar()
 
-	lx.skip()
 	switch lx.currentChar {
 	case 0:
 		return token.Token{
 			Type:    token.EOF,
-			Literal: "EOF",
+			Literal: "",
 		}
 	case '=':
 		if lx.getNextChar() == '=' {
@@ -20,6 +38,12 @@ func (lx *Lexer) GetNextToken() token.Token {
 				Type:    token.EQ,
 				Literal: "==",
 			}
+		} else if lx.getNextChar() == '>' {
+			lx.readChar()
+			return token.Token{
+				Type:    token.ARROW,
+				Literal: "=>",
+			}
 		} else {
 			nextTokenType = token.ASSIGN
 		}
@@ -40,6 +64,12 @@ func (lx *Lexer) GetNextToken() token.Token {
 				Type:    token.LE,
 				Literal: "<=",
 			}
+		} else if lx.getNextChar() == '<' {
+			lx.readChar()
+			return token.Token{
+				Type:    token.SHIFT_LEFT,
+				Literal: "<<",
+			}
 		} else {
 			nextTokenType = token.LT
 		}
@@ -50,16 +80,71 @@ func (lx *Lexer) GetNextToken() token.Token {
 				Type:    token.GE,
 				Literal: ">=",
 			}
+		} else if lx.getNextChar() == '>' {
+			lx.readChar()
+			return token.Token{
+				Type:    token.SHIFT_RIGHT,
+				Literal: ">>",
+			}
 		} else {
 			nextTokenType = token.GT
 		}
 	case '+':
+		if lx.getNextChar() == '=' {
+			lx.readChar()
+			return token.Token{
+				Type:    token.PLUS_ASSIGN,
+				Literal: "+=",
+			}
+		}
 		nextTokenType = token.PLUS
 	case '-':
+		if lx.getNextChar() == '=' {
+			lx.readChar()
+			return token.Token{
+				Type:    token.MINUS_ASSIGN,
+				Literal: "-=",
+			}
+		}
 		nextTokenType = token.MINUS
 	case '*':
-		nextTokenType = token.ASTERISK
+		if lx.getNextChar() == '*' {
+			lx.readChar()
+			return token.Token{
+				Type:    token.POWER,
+				Literal: "**",
+			}
+		} else if lx.getNextChar() == '=' {
+			lx.readChar()
+			return token.Token{
+				Type:    token.ASTERISK_ASSIGN,
+				Literal: "*=",
+			}
+		} else {
+			nextTokenType = token.ASTERISK
+		}
+	case '%':
+		nextTokenType = token.PERCENT
 	case '/':
+		if lx.getNextChar() == '*' {
+			// skip only leaves unterminated block comments
+			startPos := lx.pos
+			for lx.nextPos < len(lx.input) {
+				lx.readChar()
+			}
+			lx.raiseError(startPos, "Unterminated block comment, expected */")
+			return token.Token{
+				Type:    token.ILLEGAL,
+				Literal: lx.input[startPos:],
+			}
+		}
+		if lx.getNextChar() == '=' {
+			lx.readChar()
+			return token.Token{
+				Type:    token.SLASH_ASSIGN,
+				Literal: "/=",
+			}
+		}
 		nextTokenType = token.SLASH
 	case '(':
 		nextTokenType = token.LPAREN
@@ -69,12 +154,41 @@ func (lx *Lexer) GetNextToken() token.Token {
 		nextTokenType = token.LBRACE
 	case '}':
 		nextTokenType = token.RBRACE
+	case '[':
+		nextTokenType = token.LBRACKET
+	case ']':
+		nextTokenType = token.RBRACKET
 	case ',':
 		nextTokenType = token.COMMA
 	case ';':
 		nextTokenType = token.SEMICOLON
 	case ':':
 		nextTokenType = token.COLON
+	case '.':
+		// only a float like .5 starts with a dot
+		if !isNumber(lx.getNextChar()) {
+			nextTokenType = token.ILLEGAL
+			break
+		}
+		tokenType, literal := lx.readNumber()
+		return token.Token{
+			Type:    tokenType,
+			Literal: literal,
+		}
+	case '"':
+		startPos := lx.pos
+		value, errMsg := lx.readString()
+		if errMsg != "" {
+			lx.raiseError(startPos, errMsg)
+			return token.Token{
+				Type:    token.ILLEGAL,
+				Literal: lx.input[startPos:min(lx.pos+1, len(lx.input))],
+			}
+		}
+		return token.Token{
+			Type:    token.STRING,
+			Literal: value,
+		}
 
 	// logical operators
 	case '&':
@@ -85,7 +199,7 @@ func (lx *Lexer) GetNextToken() token.Token {
 				Literal: "&&",
 			}
 		} else {
-			nextTokenType = token.ILLEGAL
+			nextTokenType = token.BIT_AND
 		}
 	case '|':
 		if lx.getNextChar() == '|' {
@@ -95,9 +209,15 @@ func (lx *Lexer) GetNextToken() token.Token {
 				Literal: "||",
 			}
 		} else {
-			nextTokenType = token.ILLEGAL
+			nextTokenType = token.BIT_OR
 		}
 
+	// bitwise operators
+	case '^':
+		nextTokenType = token.BIT_XOR
+	case '~':
+		nextTokenType = token.BIT_NOT
+
 	default:
 		if isValidLetter(lx.currentChar) {
 			ident := lx.readIdentifier()
@@ -106,9 +226,10 @@ func (lx *Lexer) GetNextToken() token.Token {
 				Literal: ident,
 			}
 		} else if isNumber(lx.currentChar) {
+			tokenType, literal := lx.readNumber()
 			return token.Token{
-				Type:    token.INT,
-				Literal: lx.readInterger(),
+				Type:    tokenType,
+				Literal: literal,
 			}
 		} else {
 			nextTokenType = token.ILLEGAL
@@ -0,0 +1,35 @@
+package lexer
+
+import "gorilla/token"
+
+// Error is a piece of input the lexer could not turn into a valid token.
+// The token itself is returned as ILLEGAL.
+type Error struct {
+	Span     token.Span
+	Position token.Position
+	Msg      string
+}
+
+func (err *Error) Error() string {
+	return err.Position.String() + ": " + err.Msg
+}
+
+// raiseError records msg for the input from offset start to the current
+// char.
+func (lx *Lexer) raiseError(start int, msg string) {
+	span := token.Span{
+		Start: lx.file.Pos(start),
+		End:   lx.file.Pos(min(lx.pos+1, len(lx.input))),
+	}
+	lx.Errors = append(lx.Errors, &Error{span, lx.file.Position(span.Start), msg})
+}
+
+// ErrorAt returns the error recorded for the token starting at pos, or nil.
+func (lx *Lexer) ErrorAt(pos token.Pos) *Error {
+	for _, err := range lx.Errors {
+		if err.Span.Start == pos {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+package object
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// DefaultMaxDepth bounds the nesting of function calls when Limits leaves
+// MaxDepth unset, so runaway recursion is reported as an error instead of
+// overflowing the Go stack.
+const DefaultMaxDepth = 1024
+
+// checkInterval is the number of steps between checks of the context and
+// the clock, which are too slow to make on every step.
+const checkInterval = 1024
+
+// Limits bound the resources of a run. A zero field means no limit, except
+// for MaxDepth which then defaults to DefaultMaxDepth.
+//
+// For MaxAllocs, an array or hash counts as one object plus its length, and
+// a string or big integer as one object plus one per 8 bytes of contents.
+// The builtins that build arrays are charged before they allocate, other
+// operations right after, so a run stops at most one operation too late.
+type Limits struct {
+	MaxSteps    int64         // evaluation steps, one per node evaluated
+	MaxDepth    int           // nested function calls
+	MaxDuration time.Duration // wall-clock time
+	MaxAllocs   int64         // objects created
+}
+
+// Limit names the limit that stopped a run.
+type Limit int
+
+const (
+	NoLimit     Limit = iota // the error is not about a limit
+	StepLimit                // Limits.MaxSteps
+	DepthLimit               // Limits.MaxDepth
+	TimeLimit                // Limits.MaxDuration
+	AllocLimit               // Limits.MaxAllocs
+	ContextDone              // the context was canceled or passed its deadline
+)
+
+func (limit Limit) String() string {
+	switch limit {
+	case StepLimit:
+		return "step limit"
+	case DepthLimit:
+		return "depth limit"
+	case TimeLimit:
+		return "time limit"
+	case AllocLimit:
+		return "allocation limit"
+	case ContextDone:
+		return "context done"
+	default:
+		return "no limit"
+	}
+}
+
+// Budget tracks a run against its Limits and its context. The methods of a
+// nil *Budget never fail, so code can be run without one.
+type Budget struct {
+	ctx      context.Context
+	limits   Limits
+	deadline time.Time // zero without MaxDuration
+
+	steps  int64
+	depth  int
+	allocs int64
+}
+
+func NewBudget(ctx context.Context, limits Limits) *Budget {
+	if limits.MaxDepth == 0 {
+		limits.MaxDepth = DefaultMaxDepth
+	}
+	budget := &Budget{ctx: ctx, limits: limits}
+	if limits.MaxDuration > 0 {
+		budget.deadline = time.Now().Add(limits.MaxDuration)
+	}
+	return budget
+}
+
+func newLimitError(limit Limit, format string, args ...any) *Error {
+	return &Error{Message: fmt.Sprintf(format, args...), Limit: limit}
+}
+
+// Step records one evaluation step. Every checkInterval steps it also
+// checks the context and the clock, which calls and loop iterations check
+// as well.
+func (budget *Budget) Step() *Error {
+	if budget == nil {
+		return nil
+	}
+	budget.steps++
+	if budget.limits.MaxSteps > 0 && budget.steps > budget.limits.MaxSteps {
+		return newLimitError(StepLimit, "step limit exceeded: %d steps", budget.limits.MaxSteps)
+	}
+	if budget.steps%checkInterval == 0 {
+		return budget.Check()
+	}
+	return nil
+}
+
+// Check reports whether the context is done or the time is up.
+func (budget *Budget) Check() *Error {
+	if budget == nil {
+		return nil
+	}
+	if err := budget.ctx.Err(); err != nil {
+		return newLimitError(ContextDone, "execution stopped: %s", err)
+	}
+	if !budget.deadline.IsZero() && time.Now().After(budget.deadline) {
+		return newLimitError(TimeLimit, "time limit exceeded: %s", budget.limits.MaxDuration)
+	}
+	return nil
+}
+
+// Enter records a function call, which must be matched by a call to Leave
+// unless it fails. It also checks the context and the clock.
+func (budget *Budget) Enter() *Error {
+	if budget == nil {
+		return nil
+	}
+	if budget.depth >= budget.limits.MaxDepth {
+		return newLimitError(DepthLimit, "maximum recursion depth exceeded")
+	}
+	if errObj := budget.Check(); errObj != nil {
+		return errObj
+	}
+	budget.depth++
+	return nil
+}
+
+// Leave records the return of a function call.
+func (budget *Budget) Leave() {
+	if budget != nil {
+		budget.depth--
+	}
+}
+
+// Alloc records the creation of count objects.
+func (budget *Budget) Alloc(count int64) *Error {
+	if budget == nil {
+		return nil
+	}
+	budget.allocs += count
+	if budget.limits.MaxAllocs > 0 && budget.allocs > budget.limits.MaxAllocs {
+		return newLimitError(AllocLimit, "allocation limit exceeded: %d objects", budget.limits.MaxAllocs)
+	}
+	return nil
+}
+
+// Err returns the context error when the run stopped because its context
+// is done.
+func (budget *Budget) Err() error {
+	if budget == nil {
+		return nil
+	}
+	return budget.ctx.Err()
+}
@@ -0,0 +1,81 @@
+package object
+
+import (
+	"fmt"
+	"gorilla/ast"
+	"gorilla/code"
+)
+
+const (
+	COMPILED_FUNCTION = "COMPILED_FUNCTION"
+	PATTERN           = "PATTERN"
+)
+
+// Capture tells the vm where a closure finds one of its free variables when
+// it is created: a local of the enclosing function, a global defined in a
+// block, or a free variable of the enclosing closure.
+type Capture struct {
+	Name   string
+	Local  bool
+	Global bool
+	Index  int
+}
+
+// CompiledFunction is the bytecode of a function literal. It lives in the
+// constant pool; at runtime it is always wrapped in a Closure.
+type CompiledFunction struct {
+	Instructions  code.Instructions
+	SourceMap     code.SourceMap
+	NumLocals     int
+	NumParameters int
+	Captures      []Capture
+
+	// LocalNames names the local slots, to report reads of unset ones.
+	LocalNames []string
+
+	// LocalsCaptured is set when an inner function captures one of the
+	// locals, which then have to outlive the call.
+	LocalsCaptured bool
+
+	Literal *ast.FunctionLiteral
+}
+
+func (fnObj *CompiledFunction) GetType() ObjectType {
+	return COMPILED_FUNCTION
+}
+
+func (fnObj *CompiledFunction) Inspect() string {
+	return fmt.Sprintf("CompiledFunction[%p]", fnObj)
+}
+
+// Closure is a compiled function together with its captured variables. The
+// variables are shared with the scope that defined them, so later updates
+// are visible as they are to a Function's Env.
+type Closure struct {
+	Fn   *CompiledFunction
+	Free []*Object
+}
+
+// GetType reports FUNCTION, since to a program a Closure is just a function.
+func (closureObj *Closure) GetType() ObjectType {
+	return FUNCTION
+}
+
+func (closureObj *Closure) Inspect() string {
+	literal := closureObj.Fn.Literal
+	return (&Function{Parameters: literal.Signiture, Body: literal.Body}).Inspect()
+}
+
+// Pattern is the pattern of a match arm. It lives in the constant pool and
+// is tested against a value by OpMatch.
+type Pattern struct {
+	Node ast.Pattern
+}
+
+func (patternObj *Pattern) GetType() ObjectType {
+	return PATTERN
+}
+
+func (patternObj *Pattern) Inspect() string {
+	return "Pattern[" + patternObj.Node.ToString() + "]"
+}
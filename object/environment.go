@@ -0,0 +1,93 @@
+package object
+
+import (
+	"gorilla/token"
+	"sort"
+)
+
+// Environment maps names to values. Each function call and block gets its
+// own Environment whose outer pointer is the enclosing scope.
+type Environment struct {
+	store  map[string]Object
+	outer  *Environment
+	budget *Budget     // of the run using the scope, shared with enclosed scopes
+	file   *token.File // the source of the code using the scope, shared with enclosed scopes
+}
+
+func NewEnvironment() *Environment {
+	return &Environment{store: map[string]Object{}}
+}
+
+func NewEnclosedEnvironment(outer *Environment) *Environment {
+	env := NewEnvironment()
+	env.outer = outer
+	env.budget = outer.budget
+	env.file = outer.file
+	return env
+}
+
+// Budget returns the budget of the run using env, or nil if there is none.
+// A nil env has no budget.
+func (env *Environment) Budget() *Budget {
+	if env == nil {
+		return nil
+	}
+	return env.budget
+}
+
+// SetBudget sets the budget of env and of the scopes enclosed in it later.
+func (env *Environment) SetBudget(budget *Budget) {
+	env.budget = budget
+}
+
+// File returns the source file of the code using env, or nil if it is not
+// known. It locates the errors raised in functions defined in env.
+func (env *Environment) File() *token.File {
+	if env == nil {
+		return nil
+	}
+	return env.file
+}
+
+// SetFile sets the source file of env and of the scopes enclosed in it
+// later.
+func (env *Environment) SetFile(file *token.File) {
+	env.file = file
+}
+
+func (env *Environment) Get(name string) (Object, bool) {
+	obj, ok := env.store[name]
+	if !ok && env.outer != nil {
+		return env.outer.Get(name)
+	}
+	return obj, ok
+}
+
+// Set binds name in this scope, shadowing any outer binding.
+func (env *Environment) Set(name string, obj Object) Object {
+	env.store[name] = obj
+	return obj
+}
+
+// Assign rebinds name in the nearest scope that defines it. It reports
+// false if no scope does.
+func (env *Environment) Assign(name string, obj Object) bool {
+	for scope := env; scope != nil; scope = scope.outer {
+		if _, ok := scope.store[name]; ok {
+			scope.store[name] = obj
+			return true
+		}
+	}
+	return false
+}
+
+// Names lists the names bound in this scope, sorted, without the outer
+// scopes.
+func (env *Environment) Names() []string {
+	names := make([]string, 0, len(env.store))
+	for name := range env.store {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,123 @@
+package object
+
+import (
+	"bytes"
+	"math"
+	"math/big"
+)
+
+// Hashable is implemented by the values that can be used as hash keys.
+type Hashable interface {
+	Object
+	HashKey() HashKey
+}
+
+// HashKey identifies a key by type and value. Strings are kept verbatim in
+// Text, so distinct strings never collide.
+type HashKey struct {
+	Type  ObjectType
+	Value uint64
+	Text  string
+}
+
+func (boolObj *Bool) HashKey() HashKey {
+	if boolObj.Value {
+		return HashKey{Type: BOOL, Value: 1}
+	}
+	return HashKey{Type: BOOL, Value: 0}
+}
+
+func (intObj *Int) HashKey() HashKey {
+	return HashKey{Type: INT, Value: uint64(intObj.Value)}
+}
+
+func (bigObj *BigInt) HashKey() HashKey {
+	return HashKey{Type: BIGINT, Text: bigObj.Value.String()}
+}
+
+// HashKey of a whole Float is the one of the equal Int or BigInt, so 1 and
+// 1.0 are the same key, as in Python.
+func (floatObj *Float) HashKey() HashKey {
+	value := floatObj.Value
+	if value != math.Trunc(value) || math.IsInf(value, 0) {
+		return HashKey{Type: FLOAT, Value: math.Float64bits(value)}
+	}
+
+	intValue, _ := big.NewFloat(value).Int(nil)
+	return NewInteger(intValue).(Hashable).HashKey()
+}
+
+func (strObj *String) HashKey() HashKey {
+	return HashKey{Type: STRING, Text: strObj.Value}
+}
+
+type HashPair struct {
+	Key   Hashable
+	Value Object
+}
+
+// Hash is a dictionary that remembers insertion order, like Python's dict.
+type Hash struct {
+	Pairs map[HashKey]HashPair
+	Keys  []HashKey // in insertion order
+}
+
+func NewHash() *Hash {
+	return &Hash{Pairs: map[HashKey]HashPair{}}
+}
+
+func (hashObj *Hash) GetType() ObjectType {
+	return HASH
+}
+
+func (hashObj *Hash) Get(key Hashable) (Object, bool) {
+	pair, ok := hashObj.Pairs[key.HashKey()]
+	return pair.Value, ok
+}
+
+// Set adds or replaces the value for key. A replaced key keeps its place.
+func (hashObj *Hash) Set(key Hashable, value Object) {
+	hashKey := key.HashKey()
+	if _, ok := hashObj.Pairs[hashKey]; !ok {
+		hashObj.Keys = append(hashObj.Keys, hashKey)
+	}
+	hashObj.Pairs[hashKey] = HashPair{Key: key, Value: value}
+}
+
+func (hashObj *Hash) Len() int {
+	return len(hashObj.Keys)
+}
+
+// GetPairs returns the pairs in insertion order.
+func (hashObj *Hash) GetPairs() []HashPair {
+	pairs := make([]HashPair, len(hashObj.Keys))
+	for i, hashKey := range hashObj.Keys {
+		pairs[i] = hashObj.Pairs[hashKey]
+	}
+	return pairs
+}
+
+func (hashObj *Hash) Inspect() string {
+	return hashObj.inspect(map[Object]bool{})
+}
+
+func (hashObj *Hash) inspect(visiting map[Object]bool) string {
+	if visiting[hashObj] {
+		return "{...}"
+	}
+	visiting[hashObj] = true
+	defer delete(visiting, hashObj)
+
+	var out bytes.Buffer
+	out.WriteString("{")
+	for i, pair := range hashObj.GetPairs() {
+		out.WriteString(pair.Key.Inspect())
+		out.WriteString(": ")
+		out.WriteString(inspectElement(pair.Value, visiting))
+		if i < hashObj.Len()-1 {
+			out.WriteString(", ")
+		}
+	}
+	out.WriteString("}")
+	return out.String()
+}
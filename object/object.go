@@ -1,13 +1,33 @@
 package object
 
-import "fmt"
+import (
+	"bytes"
+	"fmt"
+	"gorilla/ast"
+	"gorilla/token"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
 
 type ObjectType string
 
 const (
-	NONE = "NONE"
-	BOOL = "BOOL"
-	INT  = "INT"
+	NONE         = "NONE"
+	BOOL         = "BOOL"
+	INT          = "INT"
+	BIGINT       = "BIGINT"
+	FLOAT        = "FLOAT"
+	STRING       = "STRING"
+	ARRAY        = "ARRAY"
+	HASH         = "HASH"
+	FUNCTION     = "FUNCTION"
+	BUILTIN      = "BUILTIN"
+	RETURN_VALUE = "RETURN_VALUE"
+	BREAK        = "BREAK"
+	CONTINUE     = "CONTINUE"
+	ERROR        = "ERROR"
 )
 
 type Object interface {
@@ -35,7 +55,10 @@ func (boolObj *Bool) GetType() ObjectType {
 }
 
 func (boolObj *Bool) Inspect() string {
-	return fmt.Sprintf("%t", boolObj.Value)
+	if boolObj.Value {
+		return "True"
+	}
+	return "False"
 }
 
 type Int struct {
@@ -49,3 +72,215 @@ func (intObj *Int) GetType() ObjectType {
 func (intObj *Int) Inspect() string {
 	return fmt.Sprintf("%d", intObj.Value)
 }
+
+// BigInt is an integer that does not fit an Int. Arithmetic promotes an
+// Int to a BigInt on overflow and demotes the result back when it fits, so
+// a BigInt always holds a value outside the int64 range.
+type BigInt struct {
+	Value *big.Int
+}
+
+func (bigObj *BigInt) GetType() ObjectType {
+	return BIGINT
+}
+
+func (bigObj *BigInt) Inspect() string {
+	return bigObj.Value.String()
+}
+
+// NewInteger returns value as an Int if it fits one, otherwise as a BigInt.
+func NewInteger(value *big.Int) Object {
+	if value.IsInt64() {
+		return &Int{Value: value.Int64()}
+	}
+	return &BigInt{Value: value}
+}
+
+type Float struct {
+	Value float64
+}
+
+func (floatObj *Float) GetType() ObjectType {
+	return FLOAT
+}
+
+// Inspect formats like Python's repr: a whole number keeps its ".0" and
+// very large or small numbers use an exponent.
+func (floatObj *Float) Inspect() string {
+	value := floatObj.Value
+	switch {
+	case math.IsNaN(value):
+		return "nan"
+	case math.IsInf(value, 1):
+		return "inf"
+	case math.IsInf(value, -1):
+		return "-inf"
+	}
+
+	if abs := math.Abs(value); abs != 0 && (abs < 1e-4 || abs >= 1e16) {
+		return strconv.FormatFloat(value, 'e', -1, 64)
+	}
+	text := strconv.FormatFloat(value, 'f', -1, 64)
+	if !strings.Contains(text, ".") {
+		text += ".0"
+	}
+	return text
+}
+
+type String struct {
+	Value string
+}
+
+func (strObj *String) GetType() ObjectType {
+	return STRING
+}
+
+func (strObj *String) Inspect() string {
+	return ast.QuoteString(strObj.Value)
+}
+
+type Array struct {
+	Elements []Object
+}
+
+func (arrObj *Array) GetType() ObjectType {
+	return ARRAY
+}
+
+func (arrObj *Array) Inspect() string {
+	return arrObj.inspect(map[Object]bool{})
+}
+
+// inspect writes the arrays and hashes in visiting, the ones it is already
+// inside of, as [...] and {...}, so an array that contains itself can be
+// printed.
+func (arrObj *Array) inspect(visiting map[Object]bool) string {
+	if visiting[arrObj] {
+		return "[...]"
+	}
+	visiting[arrObj] = true
+	defer delete(visiting, arrObj)
+
+	var out bytes.Buffer
+	out.WriteString("[")
+	for i, element := range arrObj.Elements {
+		out.WriteString(inspectElement(element, visiting))
+		if i < len(arrObj.Elements)-1 {
+			out.WriteString(", ")
+		}
+	}
+	out.WriteString("]")
+	return out.String()
+}
+
+func inspectElement(obj Object, visiting map[Object]bool) string {
+	switch obj := obj.(type) {
+	case *Array:
+		return obj.inspect(visiting)
+	case *Hash:
+		return obj.inspect(visiting)
+	default:
+		return obj.Inspect()
+	}
+}
+
+// Function is a first-class function value. Env is the environment the
+// function literal was evaluated in, so free variables resolve lexically.
+type Function struct {
+	Parameters []*ast.IdentifierExpression
+	Body       *ast.BlockStatement
+	Env        *Environment
+}
+
+func (fnObj *Function) GetType() ObjectType {
+	return FUNCTION
+}
+
+func (fnObj *Function) Inspect() string {
+	var out bytes.Buffer
+	out.WriteString("fn(")
+	for i, param := range fnObj.Parameters {
+		out.WriteString(param.GetName())
+		if i < len(fnObj.Parameters)-1 {
+			out.WriteString(", ")
+		}
+	}
+	out.WriteString(") ")
+	out.WriteString(fnObj.Body.ToString())
+	return out.String()
+}
+
+type BuiltinFunction func(args ...Object) Object
+
+// Builtin is a function implemented in Go. Errors are reported by returning
+// an *Error rather than panicking.
+type Builtin struct {
+	Name string
+	Fn   BuiltinFunction
+
+	// Cost, if set, counts the objects a call with args allocates, as
+	// Budget.Alloc does, so a budget can refuse the call before it runs.
+	Cost func(args ...Object) int64
+}
+
+func (builtinObj *Builtin) GetType() ObjectType {
+	return BUILTIN
+}
+
+func (builtinObj *Builtin) Inspect() string {
+	return "<builtin function " + builtinObj.Name + ">"
+}
+
+// ReturnValue wraps the value of a return statement while it unwinds
+// through the enclosing blocks.
+type ReturnValue struct {
+	Value Object
+}
+
+func (returnObj *ReturnValue) GetType() ObjectType {
+	return RETURN_VALUE
+}
+
+func (returnObj *ReturnValue) Inspect() string {
+	return returnObj.Value.Inspect()
+}
+
+// Break is the signal of a break statement, passed up through the
+// enclosing blocks to the loop it leaves.
+type Break struct{}
+
+func (breakObj *Break) GetType() ObjectType {
+	return BREAK
+}
+
+func (breakObj *Break) Inspect() string {
+	return "break"
+}
+
+// Continue is the signal of a continue statement, passed up through the
+// enclosing blocks to the loop it continues.
+type Continue struct{}
+
+func (continueObj *Continue) GetType() ObjectType {
+	return CONTINUE
+}
+
+func (continueObj *Continue) Inspect() string {
+	return "continue"
+}
+
+// Error is a runtime error. Span is the source range of the node that
+// raised it, and Limit the limit of the run it hit, if any.
+type Error struct {
+	Message string
+	Span    token.Span
+	Limit   Limit
+}
+
+func (errObj *Error) GetType() ObjectType {
+	return ERROR
+}
+
+func (errObj *Error) Inspect() string {
+	return "Runtime error: " + errObj.Message
+}
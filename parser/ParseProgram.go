@@ -1,39 +1,30 @@
 package parser
 
 import (
+	"fmt"
 	"gorilla/ast"
 	"gorilla/parser/precedences"
 	"gorilla/token"
 )
 
+// ParseProgram parses statements until EOF. A statement that fails to parse
+// is reported in p.Errors and skipped, so one call finds every independent
+// syntax error. ok is false if any error was reported.
 func (p *Parser) ParseProgram() (*ast.Program, bool) {
 	prog := &ast.Program{}
 
 	for p.currentToken.Type != token.EOF {
 		// println("Parsing statement:", p.currentToken.Literal)
+		stmtStart := p.currentToken.Span.Start
 		statement := p.parseStatement()
 		if statement == nil {
-			// for _, msg := range p.Errors {
-			// 	println(msg)
-			// }
-			p.raiseParseProgramError()
-			return prog, false
+			p.synchronize(stmtStart, false)
+			continue
 		}
-		// if statement == nil {
-		// 	p.raiseParseStatementError(statement)
-		// 	return prog, false
-		// }
 
 		prog.Statements = append(prog.Statements, statement)
-		// if p.currentToken.Type == token.SEMICOLON {
-		// 	p.loadNextToken()
-		// 	// p.raiseNextTokenError(token.SEMICOLON)
-		// 	// return prog, !ok
-		// }
-
-		// p.loadNextToken()
 	}
-	return prog, true
+	return prog, len(p.Errors) == 0
 }
 
 func (p *Parser) parseStatement() ast.StatementNode {
@@ -41,6 +32,7 @@ func (p *Parser) parseStatement() ast.StatementNode {
 
 	// === Ends with ';' === //
 	case token.LET:
+		letToken := p.currentToken
 		if p.nextToken.Type != token.IDENT {
 			p.raiseNextTokenError(token.IDENT)
 			return nil
@@ -51,7 +43,7 @@ func (p *Parser) parseStatement() ast.StatementNode {
 		p.loadNextToken()
 
 		if p.currentToken.Type != token.ASSIGN {
-			p.raiseNextTokenError(token.ASSIGN)
+			p.raiseCurrentTokenError(token.ASSIGN)
 			return nil
 		}
 		p.loadNextToken()
@@ -59,46 +51,48 @@ func (p *Parser) parseStatement() ast.StatementNode {
 		expression, ok := p.parseExpression(precedences.LOWEST)
 		if !ok {
 			p.raiseExpressionError()
-			p.raiseParseStatementError(token.LET, nil)
 			return nil
 		}
 		p.loadNextToken()
 
-		stmt := &ast.LetStatement{identifier, expression}
+		stmt := &ast.LetStatement{letToken, identifier, expression}
 		if p.currentToken.Type != token.SEMICOLON {
-			p.raiseTokenError(token.SEMICOLON)
-			p.raiseParseStatementError(token.LET, stmt)
+			p.raiseCurrentTokenError(token.SEMICOLON)
 			return nil
 		}
 		p.loadNextToken()
-		// p.skipToSemicolon()
 
 		return stmt
 
 	case token.RETURN:
+		returnToken := p.currentToken
 		if p.nextToken.Type == token.SEMICOLON {
 			// empty return
-			return &ast.ReturnStatement{}
+			p.loadNextToken()
+			p.loadNextToken()
+			return &ast.ReturnStatement{Token: returnToken}
 		}
 		p.loadNextToken()
 
 		returnValue, ok := p.parseExpression(precedences.LOWEST)
 		if !ok {
-			p.raiseParseStatementError(token.RETURN, nil)
+			p.raiseExpressionError()
 			return nil
 		}
 		p.loadNextToken()
 
-		stmt := &ast.ReturnStatement{returnValue}
+		stmt := &ast.ReturnStatement{returnToken, returnValue}
 		if p.currentToken.Type != token.SEMICOLON {
-			p.raiseTokenError(token.SEMICOLON)
-			p.raiseParseStatementError(token.RETURN, stmt)
+			p.raiseCurrentTokenError(token.SEMICOLON)
 			return nil
 		}
 		p.loadNextToken()
 
 		return stmt
 
+	case token.BREAK, token.CONTINUE:
+		return p.parseLoopControl()
+
 	// === Ends with '}' === //
 	case token.IF:
 		stmt, ok := p.parseIfElseStatement()
@@ -109,7 +103,29 @@ func (p *Parser) parseStatement() ast.StatementNode {
 		p.loadNextToken()
 		return stmt
 
+	case token.WHILE:
+		stmt, ok := p.parseWhileStatement()
+		if !ok {
+			p.raiseError("Could not parse while statement")
+			return nil
+		}
+		p.loadNextToken()
+		return stmt
+
+	case token.FOR:
+		stmt, ok := p.parseForStatement()
+		if !ok {
+			p.raiseError("Could not parse for statement")
+			return nil
+		}
+		p.loadNextToken()
+		return stmt
+
 	case token.LBRACE:
+		if p.isHashLiteralStart() {
+			return p.parseExpressionStatement()
+		}
+
 		block, ok := p.parseBlockStatement()
 		if !ok {
 			p.raiseError("Could not parse block statement")
@@ -120,38 +136,123 @@ func (p *Parser) parseStatement() ast.StatementNode {
 
 	// === Edge cases === //
 	case token.RBRACE:
-		p.raiseError("Could not parse statement with token: " + string(p.currentToken.Type))
+		p.raiseError("Unexpected '}' without a matching '{'")
 		return nil
 
-	case token.SEMICOLON:
-		p.raiseError("Unexpected token: " + string(p.currentToken.Type))
+	default:
+		return p.parseExpressionStatement()
+	}
+
+}
+
+func (p *Parser) parseExpressionStatement() ast.StatementNode {
+	expression, ok := p.parseExpression(precedences.LOWEST)
+	if !ok {
+		p.raiseExpressionError()
 		return nil
+	}
+	p.loadNextToken()
+
+	if _, ok := token.AssignOperators[p.currentToken.Type]; ok {
+		return p.parseAssignment(expression)
+	}
+
+	if p.currentToken.Type != token.SEMICOLON {
+		p.raiseCurrentTokenError(token.SEMICOLON)
+		return nil
+	}
+	p.loadNextToken()
 
+	return &ast.ExpressionStatement{Expression: expression}
+}
+
+// parseAssignment parses the rest of `target = value;` from the assignment
+// operator on. Only names and index expressions can be assigned to.
+func (p *Parser) parseAssignment(target ast.ExpressionNode) ast.StatementNode {
+	switch target.(type) {
+	case *ast.IdentifierExpression, *ast.IndexExpression:
 	default:
-		p.raiseError("Unexpected token: " + string(p.currentToken.Type))
-		p.loadNextToken()
+		p.raiseError("Cannot assign to " + target.ToString())
 		return nil
 	}
 
+	operator := p.currentToken
+	p.loadNextToken()
+
+	value, ok := p.parseExpression(precedences.LOWEST)
+	if !ok {
+		p.raiseExpressionError()
+		return nil
+	}
+	p.loadNextToken()
+
+	if p.currentToken.Type != token.SEMICOLON {
+		p.raiseCurrentTokenError(token.SEMICOLON)
+		return nil
+	}
+	p.loadNextToken()
+
+	return &ast.Assignment{Target: target, Operator: operator, Value: value}
 }
 
+// isHashLiteralStart reports whether the '{' at the start of a statement
+// opens a hash literal rather than a block: either `{}` or `{ key :`. The
+// key may be any expression, so the tokens are scanned up to the first ':'
+// outside of brackets, which only a hash has, or to the ';' or '}' that
+// ends a statement or block first.
+func (p *Parser) isHashLiteralStart() bool {
+	if p.nextToken.Type == token.RBRACE {
+		return true
+	}
+
+	lx := *p.lx
+	depth := 0
+	for tok := p.nextToken; tok.Type != token.EOF; tok = lx.GetNextToken() {
+		switch tok.Type {
+		case token.LPAREN, token.LBRACKET, token.LBRACE:
+			depth++
+		case token.RPAREN, token.RBRACKET, token.RBRACE:
+			if depth == 0 {
+				return false
+			}
+			depth--
+		case token.COLON:
+			if depth == 0 {
+				return true
+			}
+		case token.SEMICOLON:
+			if depth == 0 {
+				return false
+			}
+		}
+	}
+	return false
+}
+
+// parseBlockStatement parses '{' statements '}', leaving currentToken on the
+// closing '}'. A broken statement inside the block is skipped like at the
+// top level, so the block itself still parses.
 func (p *Parser) parseBlockStatement() (*ast.BlockStatement, bool) {
 	if p.currentToken.Type != token.LBRACE {
-		p.raiseNextTokenError(token.LBRACE)
+		p.raiseCurrentTokenError(token.LBRACE)
 		return nil, false
 	}
+
+	block := &ast.BlockStatement{Token: p.currentToken}
 	p.loadNextToken()
 
-	block := &ast.BlockStatement{}
 	for p.currentToken.Type != token.RBRACE {
+		if p.currentToken.Type == token.EOF {
+			p.raiseCurrentTokenError(token.RBRACE)
+			return nil, false
+		}
+
 		// println("Parsing block statement: ", p.currentToken.Literal)
+		stmtStart := p.currentToken.Span.Start
 		statement := p.parseStatement()
 		if statement == nil {
-			p.raiseBlockStatementError(block.Statements)
-			// if len(block.Statements) > 0 {
-			// 	return block, false
-			// }
-			return nil, false
+			p.synchronize(stmtStart, true)
+			continue
 		}
 
 		block.AppendStatement(statement)
@@ -159,6 +260,7 @@ func (p *Parser) parseBlockStatement() (*ast.BlockStatement, bool) {
 			p.loadNextToken()
 		}
 	}
+	block.Rbrace = p.currentToken.Span.Start
 	// p.loadNextToken() // load token after '}'
 	// println("Finsh parsing block statement: with ", len(block.Statements), " statements")
 
@@ -166,6 +268,7 @@ func (p *Parser) parseBlockStatement() (*ast.BlockStatement, bool) {
 }
 
 func (p *Parser) parseIfElseStatement() (ast.StatementNode, bool) {
+	ifToken := p.currentToken
 	if p.nextToken.Type != token.LPAREN {
 		p.raiseNextTokenError(token.LPAREN)
 		return nil, false
@@ -184,21 +287,33 @@ func (p *Parser) parseIfElseStatement() (ast.StatementNode, bool) {
 		p.raiseError("Could not parse block statement")
 		return nil, false
 	}
-	p.loadNextToken()
 
-	if p.currentToken.Type != token.ELSE {
-		return ast.NewIfStatement(condition, block), true
+	// `elif (...)` is short for `else if (...)`
+	if p.nextToken.Type == token.ELIF {
+		p.loadNextToken()
+		elifToken := p.currentToken
+
+		elifStmt, ok := p.parseIfElseStatement()
+		if !ok {
+			p.raiseError("Could not parse elif statement")
+			return nil, false
+		}
+		return ast.NewIfElseStatement(ifToken, condition, block, elifToken, elifStmt), true
+	}
+
+	// leave currentToken on '}' when there is no else branch
+	if p.nextToken.Type != token.ELSE {
+		return ast.NewIfStatement(ifToken, condition, block), true
 	}
 	p.loadNextToken()
+	elseToken := p.currentToken
+	p.loadNextToken()
 
 	var elseBlock ast.StatementNode
 	if p.currentToken.Type == token.IF {
 		elseBlock, ok = p.parseIfElseStatement()
 		if !ok {
 			p.raiseError("Could not parse else if statement")
-			// if elseBlock != nil {
-			// 	return elseBlock, !ok
-			// }
 			return nil, false
 		}
 
@@ -209,7 +324,111 @@ func (p *Parser) parseIfElseStatement() (ast.StatementNode, bool) {
 			return nil, false
 		}
 	}
-	return ast.NewIfElseStatement(condition, block, elseBlock), true
+	return ast.NewIfElseStatement(ifToken, condition, block, elseToken, elseBlock), true
+}
+
+// parseWhileStatement parses `while (condition) { ... }`, leaving
+// currentToken on the closing '}'.
+func (p *Parser) parseWhileStatement() (*ast.WhileStatement, bool) {
+	whileToken := p.currentToken
+	if p.nextToken.Type != token.LPAREN {
+		p.raiseNextTokenError(token.LPAREN)
+		return nil, false
+	}
+	p.loadNextToken()
+
+	condition, ok := p.parseExpression(precedences.LOWEST)
+	if !ok {
+		p.raiseExpressionError()
+		return nil, false
+	}
+	p.loadNextToken()
+
+	body, ok := p.parseLoopBody()
+	if !ok {
+		return nil, false
+	}
+	return &ast.WhileStatement{whileToken, condition, body}, true
+}
+
+// parseForStatement parses `for (name in iterable) { ... }`, leaving
+// currentToken on the closing '}'.
+func (p *Parser) parseForStatement() (*ast.ForStatement, bool) {
+	forToken := p.currentToken
+	if p.nextToken.Type != token.LPAREN {
+		p.raiseNextTokenError(token.LPAREN)
+		return nil, false
+	}
+	p.loadNextToken()
+
+	if p.nextToken.Type != token.IDENT {
+		p.raiseNextTokenError(token.IDENT)
+		return nil, false
+	}
+	p.loadNextToken()
+	variable := &ast.IdentifierExpression{p.currentToken}
+
+	if p.nextToken.Type != token.IN {
+		p.raiseNextTokenError(token.IN)
+		return nil, false
+	}
+	p.loadNextToken()
+	p.loadNextToken()
+
+	iterable, ok := p.parseExpression(precedences.LOWEST)
+	if !ok {
+		p.raiseExpressionError()
+		return nil, false
+	}
+
+	if p.nextToken.Type != token.RPAREN {
+		p.raiseNextTokenError(token.RPAREN)
+		return nil, false
+	}
+	p.loadNextToken()
+	p.loadNextToken()
+
+	body, ok := p.parseLoopBody()
+	if !ok {
+		return nil, false
+	}
+	return &ast.ForStatement{forToken, variable, iterable, body}, true
+}
+
+// parseLoopBody parses the block of a loop, in which break and continue
+// are allowed.
+func (p *Parser) parseLoopBody() (*ast.BlockStatement, bool) {
+	p.loopDepth++
+	defer func() { p.loopDepth-- }()
+
+	body, ok := p.parseBlockStatement()
+	if !ok {
+		p.raiseError("Could not parse loop body")
+		return nil, false
+	}
+	return body, true
+}
+
+// parseLoopControl parses `break;` or `continue;`, which are only allowed
+// inside a loop of the same function.
+func (p *Parser) parseLoopControl() ast.StatementNode {
+	controlToken := p.currentToken
+	if p.loopDepth == 0 {
+		p.raiseError(fmt.Sprintf("'%s' outside of a loop", controlToken.Literal))
+		return nil
+	}
+
+	if p.nextToken.Type != token.SEMICOLON {
+		p.raiseNextTokenError(token.SEMICOLON)
+		return nil
+	}
+	p.loadNextToken()
+	p.loadNextToken()
+
+	if controlToken.Type == token.BREAK {
+		return &ast.BreakStatement{Token: controlToken}
+	}
+	return &ast.ContinueStatement{Token: controlToken}
 }
 
 // func (p *Parser) parse() (ast.ExpressionNode, bool) {
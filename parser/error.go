@@ -1,83 +1,231 @@
 package parser
 
 import (
+	"errors"
 	"fmt"
-	"gorilla/ast"
 	"gorilla/token"
 	"strconv"
+	"strings"
 )
 
-func (p *Parser) raiseErrorAndPanic(msg string) {
-	p.raiseError(msg)
-	panic(p.Errors)
+type ErrorKind int
+
+const (
+	ErrInvalidSyntax     ErrorKind = iota // catch-all for malformed constructs
+	ErrUnexpectedToken                    // a specific token was expected
+	ErrMissingExpression                  // an expression was expected
+	ErrInvalidLiteral                     // a literal could not be converted
+	ErrInvalidToken                       // the lexer could not read a token
+	WarnNonExhaustive                     // a match without a catch-all arm, reported as a warning
+)
+
+func (kind ErrorKind) String() string {
+	switch kind {
+	case ErrUnexpectedToken:
+		return "unexpected token"
+	case ErrMissingExpression:
+		return "missing expression"
+	case ErrInvalidLiteral:
+		return "invalid literal"
+	case ErrInvalidToken:
+		return "invalid token"
+	case WarnNonExhaustive:
+		return "non-exhaustive match"
+	default:
+		return "invalid syntax"
+	}
 }
 
-func (p *Parser) raiseError(msg string) {
-	p.Errors = append(p.Errors, "Parser error: "+msg)
+// ParseError describes a single syntax error. Expected is empty unless the
+// error is about a specific missing token.
+type ParseError struct {
+	Kind     ErrorKind
+	Expected token.TokenType
+	Actual   token.Token
+	Span     token.Span
+	Position token.Position
+	Msg      string
+	Hint     string // nullable
 }
 
-func (p *Parser) raiseParseProgramError() {
-	p.raiseError("Failed to parse program. \n[END]")
-	// if stmt == nil { // stmt is nil => failed to parse statement
+func (err *ParseError) Error() string {
+	return err.Position.String() + ": " + err.Msg
+}
+
+// ErrorList is the list of errors reported by a single parse. It implements
+// error so it can be returned as is.
+type ErrorList []*ParseError
+
+func (list ErrorList) Error() string {
+	switch len(list) {
+	case 0:
+		return "no parser errors"
+	case 1:
+		return list[0].Error()
+	}
 
-	// 	return
-	// }
+	msgs := make([]string, len(list))
+	for i, err := range list {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// Err returns list as an error, or nil when it is empty.
+func (list ErrorList) Err() error {
+	if len(list) == 0 {
+		return nil
+	}
+	return list
 }
 
-func (p *Parser) raiseParseStatementError(tokenType token.TokenType, stmt ast.StatementNode) {
-	if stmt == nil { // stmt is nil => failed to parse statement
-		p.raiseError(
-			fmt.Sprintf("Failed to parse %s statement.", tokenType),
-		)
+// Err returns the errors found so far, or nil if there are none.
+func (p *Parser) Err() error {
+	return p.Errors.Err()
+}
+
+// addError records err unless the parser is already recovering from an
+// earlier error in the same statement, in which case err is a consequence
+// of that one and only adds noise.
+func (p *Parser) addError(err *ParseError) {
+	if p.panicking {
 		return
 	}
+	p.panicking = true
+
+	// a token the lexer could not read is better described by the lexer
+	if err.Actual.Type == token.ILLEGAL {
+		if lexErr := p.lx.ErrorAt(err.Actual.Span.Start); lexErr != nil {
+			err.Kind = ErrInvalidToken
+			err.Expected = ""
+			err.Span = lexErr.Span
+			err.Msg = lexErr.Msg
+			err.Hint = ""
+		}
+	}
+
+	err.Position = p.lx.GetFile().Position(err.Span.Start)
+	p.Errors = append(p.Errors, err)
+}
+
+// addWarning records a problem that does not stop the program from being
+// run. Warnings are kept apart from errors and never cause recovery.
+func (p *Parser) addWarning(warning *ParseError) {
+	warning.Position = p.lx.GetFile().Position(warning.Span.Start)
+	p.Warnings = append(p.Warnings, warning)
+}
 
-	// missing semicolon or something
-	stmt_str := stmt.ToString()
-	if len(stmt_str) > 0 {
-		stmt_str = stmt_str[:len(stmt_str)-1]
+// synchronize skips tokens until the end of the broken statement that
+// started at stmtStart, so parsing can resume with the next one. A ';' is
+// consumed. A '}' is only consumed at the top level; inside a block it
+// closes that block. A keyword that starts a statement also ends the
+// broken one, which catches a missing ';'.
+func (p *Parser) synchronize(stmtStart token.Pos, inBlock bool) {
+	defer func() { p.panicking = false }()
+
+	for {
+		switch p.currentToken.Type {
+		case token.EOF:
+			return
+		case token.SEMICOLON:
+			p.loadNextToken()
+			return
+		case token.RBRACE:
+			if !inBlock {
+				p.loadNextToken()
+			}
+			return
+		case token.LET, token.RETURN, token.IF, token.WHILE, token.FOR, token.BREAK, token.CONTINUE:
+			if p.currentToken.Span.Start != stmtStart {
+				return
+			}
+		}
+		p.loadNextToken()
 	}
+}
 
-	p.raiseError(
-		fmt.Sprintf("Failed to parse %s statement: %s", tokenType, stmt_str),
-	)
+// raiseError reports msg at the position of the current token.
+func (p *Parser) raiseError(msg string) {
+	p.addError(&ParseError{
+		Kind:   ErrInvalidSyntax,
+		Actual: p.currentToken,
+		Span:   p.currentToken.Span,
+		Msg:    msg,
+	})
 }
 
-func (p *Parser) raiseTokenError(expectedTokenType token.TokenType) {
-	// println("Current token:", p.currentToken.Literal)
+func (p *Parser) raiseLiteralError(err error) {
+	p.addError(&ParseError{
+		Kind:   ErrInvalidLiteral,
+		Actual: p.currentToken,
+		Span:   p.currentToken.Span,
+		Msg:    fmt.Sprintf("Invalid %s literal %s", p.currentToken.Type, p.currentToken.Literal),
+		Hint:   literalHint(p.currentToken.Type, err),
+	})
+}
 
-	p.raiseError(
-		fmt.Sprintf("Expected %s", expectedTokenType),
-	)
+// literalHint explains why a number literal could not be converted,
+// without the details of strconv.
+func literalHint(tokenType token.TokenType, err error) string {
+	var numErr *strconv.NumError
+	if !errors.As(err, &numErr) {
+		return err.Error()
+	}
+	switch {
+	case numErr.Err == strconv.ErrRange && tokenType == token.FLOAT:
+		return "float literal too large"
+	case numErr.Err == strconv.ErrRange:
+		return "integer literal too large"
+	case numErr.Err == strconv.ErrSyntax:
+		return "malformed number"
+	default:
+		return numErr.Err.Error()
+	}
 }
 
-func (p *Parser) raiseNextTokenError(expectedTokenType token.TokenType) {
-	// println("Current token:", p.currentToken.Literal)
-	p.raiseError(
-		fmt.Sprintf("Expected %s token, got %s token instead",
-			expectedTokenType, p.nextToken.Type,
+func (p *Parser) raiseUnexpectedTokenError(expectedTokenType token.TokenType, actual token.Token) {
+	p.addError(&ParseError{
+		Kind:     ErrUnexpectedToken,
+		Expected: expectedTokenType,
+		Actual:   actual,
+		Span:     actual.Span,
+		Msg: fmt.Sprintf("Expected %s token, got %s token instead",
+			expectedTokenType, actual.Type,
 		),
-	)
+		Hint: getTokenHint(expectedTokenType),
+	})
+}
+
+func (p *Parser) raiseCurrentTokenError(expectedTokenType token.TokenType) {
+	p.raiseUnexpectedTokenError(expectedTokenType, p.currentToken)
+}
+
+func (p *Parser) raiseNextTokenError(expectedTokenType token.TokenType) {
+	p.raiseUnexpectedTokenError(expectedTokenType, p.nextToken)
 }
 
 func (p *Parser) raiseExpressionError() {
-	// println("Current token:", p.currentToken.Literal)
-	p.raiseError(
-		fmt.Sprintf("Expected expression, got %s token instead",
+	p.addError(&ParseError{
+		Kind:   ErrMissingExpression,
+		Actual: p.currentToken,
+		Span:   p.currentToken.Span,
+		Msg: fmt.Sprintf("Expected expression, got %s token instead",
 			p.currentToken.Type,
 		),
-	)
+	})
 }
 
-func (p *Parser) raiseBlockStatementError(statements []ast.StatementNode) {
-	// print statements
-	for i, stmt := range statements {
-		p.raiseError("Parsed block statement [" + strconv.Itoa(i) + "]: " + stmt.ToString())
-
+func getTokenHint(expectedTokenType token.TokenType) string {
+	switch expectedTokenType {
+	case token.SEMICOLON:
+		return "statements end with ';'"
+	case token.RPAREN:
+		return "check for an unclosed '('"
+	case token.RBRACE:
+		return "check for an unclosed '{'"
+	case token.ASSIGN:
+		return "let statements have the form: let <name> = <expression>;"
+	default:
+		return ""
 	}
-
-	p.raiseError(
-		"current token: " + p.currentToken.Literal +
-			" Next token: " + p.nextToken.Literal)
-
 }
@@ -4,6 +4,7 @@ import (
 	"gorilla/ast"
 	"gorilla/parser/precedences"
 	"gorilla/token"
+	"strings"
 )
 
 func (p *Parser) parseExpression(parentPrecedence int) (ast.ExpressionNode, bool) {
@@ -11,23 +12,39 @@ func (p *Parser) parseExpression(parentPrecedence int) (ast.ExpressionNode, bool
 	switch p.currentToken.Type {
 	case token.IDENT:
 		expr = &ast.IdentifierExpression{p.currentToken}
-		if p.nextToken.Type == token.LPAREN {
-			expr = p.parseFunctionCall(expr)
-		}
 
 	case token.TRUE, token.FALSE:
 		expr = &ast.BoolLiteral{p.currentToken}
 
-	case token.INT:
-		intLit, err := ast.NewIntegerLiteral(p.currentToken)
+	case token.STRING:
+		expr = &ast.StringLiteral{Token: p.currentToken}
+
+	case token.LBRACE:
+		hashLit, ok := p.parseHashLiteral()
+		if !ok {
+			return nil, false
+		}
+		expr = hashLit
+
+	case token.LBRACKET:
+		lbracket := p.currentToken
+		elements, ok := p.parseExpressionList(token.RBRACKET)
+		if !ok {
+			return nil, false
+		}
+		expr = &ast.ArrayLiteral{lbracket, elements, p.currentToken.Span.Start}
+
+	case token.INT, token.FLOAT:
+		numLit, err := newNumberLiteral(p.currentToken)
 		if err != nil {
-			p.raiseError(err.Error())
+			p.raiseLiteralError(err)
 			return nil, false
 		}
-		expr = intLit
+		expr = numLit
 
 	case token.FUNCTION:
 		// fn_definition
+		fnToken := p.currentToken
 		if p.nextToken.Type != token.LPAREN {
 			p.raiseNextTokenError(token.LPAREN)
 			return nil, false
@@ -55,7 +72,11 @@ func (p *Parser) parseExpression(parentPrecedence int) (ast.ExpressionNode, bool
 		}
 		p.loadNextToken()
 
+		// break and continue cannot leave a function
+		loopDepth := p.loopDepth
+		p.loopDepth = 0
 		body, ok := p.parseBlockStatement()
+		p.loopDepth = loopDepth
 		if !ok {
 			p.raiseError("Could not parse Function body")
 			return nil, false
@@ -65,9 +86,16 @@ func (p *Parser) parseExpression(parentPrecedence int) (ast.ExpressionNode, bool
 		}
 
 		// println("After parsing body: ", p.currentToken.Literal) // epxected to be after '}
-		expr = &ast.FunctionLiteral{signiture, body}
+		expr = &ast.FunctionLiteral{fnToken, signiture, body}
 
-	case token.LPAREN, token.BANG, token.MINUS:
+	case token.MATCH:
+		match, ok := p.parseMatch()
+		if !ok {
+			return nil, false
+		}
+		expr = match
+
+	case token.LPAREN, token.BANG, token.MINUS, token.BIT_NOT:
 		prefix, ok := p.parsePrefix()
 		if !ok {
 			return nil, false
@@ -78,18 +106,24 @@ func (p *Parser) parseExpression(parentPrecedence int) (ast.ExpressionNode, bool
 	// 	p.raiseError()
 
 	default:
-		p.raiseErrorAndPanic(
-			"Unexpected token for parseExpression: " + string(p.currentToken.Type),
-		)
+		p.raiseExpressionError()
+		return nil, false
 	}
 
 	if p.nextToken.Type == token.SEMICOLON {
 		return expr, true
 	}
 
-	// Next token is infix operator
+	// Next token is infix or postfix operator
 	for p.getNextPrecedence() > parentPrecedence {
-		expr = p.parseInfix(expr)
+		switch p.nextToken.Type {
+		case token.LPAREN:
+			expr = p.parseFunctionCall(expr)
+		case token.LBRACKET:
+			expr = p.parseIndexExpression(expr)
+		default:
+			expr = p.parseInfix(expr)
+		}
 		if expr == nil {
 			p.raiseError("Could not parse infix expression")
 			return nil, false
@@ -112,11 +146,19 @@ func (p *Parser) parseExpression(parentPrecedence int) (ast.ExpressionNode, bool
 	return expr, true
 }
 
+// newNumberLiteral converts an INT or FLOAT token.
+func newNumberLiteral(tok token.Token) (ast.ExpressionNode, error) {
+	if tok.Type == token.FLOAT {
+		return ast.NewFloatLiteral(tok)
+	}
+	return ast.NewIntegerLiteral(tok)
+}
+
 func (p *Parser) parsePrefix() (ast.ExpressionNode, bool) {
 	// println("parsePrefix on token: " + p.currentToken.Literal)
 	precedence := precedences.PREFIX
 	switch p.currentToken.Type {
-	case token.BANG, token.MINUS:
+	case token.BANG, token.MINUS, token.BIT_NOT:
 		operator := p.currentToken
 		p.loadNextToken()
 
@@ -125,22 +167,28 @@ func (p *Parser) parsePrefix() (ast.ExpressionNode, bool) {
 			p.raiseError(
 				"Could not parse expression after prefix operator " + operator.Literal,
 			)
-			return nil, !ok
+			return nil, false
 		}
 
-		// optimize negative value
-		if operator.Type == token.MINUS && operand.GetTokenType() == token.INT {
-			intLit, err := ast.NewIntegerLiteral(
+		// optimize negative value, but keep --x as a nested prefix
+		isLiteral := (operand.GetTokenType() == token.INT || operand.GetTokenType() == token.FLOAT) &&
+			!strings.HasPrefix(operand.GetTokenLiteral(), "-")
+		if operator.Type == token.MINUS && isLiteral {
+			numLit, err := newNumberLiteral(
 				token.Token{
-					Type:    token.INT,
+					Type:    operand.GetTokenType(),
 					Literal: "-" + operand.GetTokenLiteral(),
+					Span: token.Span{
+						Start: operator.Span.Start,
+						End:   operand.GetSpan().End,
+					},
 				},
 			)
 			if err != nil {
-				p.raiseError(err.Error())
+				p.raiseLiteralError(err)
 				return nil, !ok
 			}
-			return intLit, ok
+			return numLit, ok
 		}
 
 		return &ast.Prefix{operator, operand}, ok
@@ -151,7 +199,7 @@ func (p *Parser) parsePrefix() (ast.ExpressionNode, bool) {
 		inner_expression, ok := p.parseExpression(precedences.LOWEST)
 		if !ok {
 			p.raiseError("Could not parse LPAREN expression")
-			return nil, !ok
+			return nil, false
 		}
 
 		if p.nextToken.Type != token.RPAREN {
@@ -211,7 +259,7 @@ func (p *Parser) parseInfix(left ast.ExpressionNode) ast.ExpressionNode {
 			return nil
 		}
 
-	case token.ASTERISK, token.SLASH:
+	case token.ASTERISK, token.SLASH, token.PERCENT:
 		right, ok = p.parseExpression(precedence)
 		if !ok {
 			p.raiseError(" algorithmic error " + operator.Literal)
@@ -219,20 +267,25 @@ func (p *Parser) parseInfix(left ast.ExpressionNode) ast.ExpressionNode {
 
 		}
 
-	// comparison operators
-	case token.EQ, token.NOT_EQ, token.LE, token.GE:
-		right, ok = p.parseExpression(precedence)
+	case token.POWER:
+		// right associative: 2 ** 3 ** 2 is 2 ** (3 ** 2)
+		right, ok = p.parseExpression(precedence - 1)
 		if !ok {
+			p.raiseError(" algorithmic error " + operator.Literal)
 			return nil
 		}
 
-	case token.LT, token.GT:
+	// bitwise operators
+	case token.BIT_AND, token.BIT_OR, token.BIT_XOR, token.SHIFT_LEFT, token.SHIFT_RIGHT:
 		right, ok = p.parseExpression(precedence)
 		if !ok {
-			p.raiseError(" comparison error " + operator.Literal)
 			return nil
 		}
 
+	// comparison operators
+	case token.EQ, token.NOT_EQ, token.LT, token.GT, token.LE, token.GE:
+		return p.parseComparison(left, operator, precedence)
+
 	// logical operators
 	case token.AND, token.OR:
 		right, ok = p.parseExpression(precedence)
@@ -240,6 +293,7 @@ func (p *Parser) parseInfix(left ast.ExpressionNode) ast.ExpressionNode {
 			p.raiseError("Could not parse logical expression")
 			return nil
 		}
+		return &ast.Logical{operator, left, right}
 
 	// case token.IF:
 	// 	right, ok = p.parseExpression(precedence)
@@ -257,6 +311,34 @@ func (p *Parser) parseInfix(left ast.ExpressionNode) ast.ExpressionNode {
 	return &ast.Infix{operator, left, right}
 }
 
+// parseComparison parses the right operand of a comparison, starting on
+// its first token. Further operators of the same precedence chain with it,
+// as in Python: `a < b <= c` is a Comparison, not `(a < b) <= c`.
+func (p *Parser) parseComparison(left ast.ExpressionNode, operator token.Token, precedence int) ast.ExpressionNode {
+	operands := []ast.ExpressionNode{left}
+	operators := []token.Token{operator}
+
+	for {
+		right, ok := p.parseExpression(precedence)
+		if !ok {
+			return nil
+		}
+		operands = append(operands, right)
+
+		if precedences.Precedence[p.nextToken.Type] != precedence {
+			break
+		}
+		p.loadNextToken()
+		operators = append(operators, p.currentToken)
+		p.loadNextToken()
+	}
+
+	if len(operators) == 1 {
+		return &ast.Infix{operators[0], left, operands[1]}
+	}
+	return &ast.Comparison{operands, operators}
+}
+
 func (p *Parser) parseIfElseExpression(left ast.ExpressionNode) (*ast.Trinary, bool) {
 	if left == nil {
 		p.raiseError("Left operand is nil")
@@ -265,7 +347,7 @@ func (p *Parser) parseIfElseExpression(left ast.ExpressionNode) (*ast.Trinary, b
 	p.loadNextToken()
 
 	if p.currentToken.Type != token.IF {
-		p.raiseTokenError(token.IF)
+		p.raiseCurrentTokenError(token.IF)
 		return nil, false
 	}
 	p.loadNextToken()
@@ -278,7 +360,8 @@ func (p *Parser) parseIfElseExpression(left ast.ExpressionNode) (*ast.Trinary, b
 	p.loadNextToken()
 
 	if p.currentToken.Type != token.ELSE {
-		p.raiseTokenError(token.ELSE)
+		p.raiseCurrentTokenError(token.ELSE)
+		return nil, false
 	}
 	p.loadNextToken()
 
@@ -292,37 +375,126 @@ func (p *Parser) parseIfElseExpression(left ast.ExpressionNode) (*ast.Trinary, b
 
 }
 
-func (p *Parser) parseFunctionCall(expr ast.ExpressionNode) *ast.FunctionCall {
-	functionIdentifier, ok := expr.(*ast.IdentifierExpression)
+// parseFunctionCall parses the postfix call callee(args...), leaving
+// currentToken on ')'. A trailing comma is allowed.
+func (p *Parser) parseFunctionCall(callee ast.ExpressionNode) ast.ExpressionNode {
+	p.loadNextToken()
+	lparen := p.currentToken
+
+	arguments, ok := p.parseExpressionList(token.RPAREN)
 	if !ok {
-		p.raiseError("ParseFunctionCall: expected Identifeier")
 		return nil
 	}
+
+	return &ast.FunctionCall{lparen, callee, arguments, p.currentToken.Span.Start}
+}
+
+// parseExpressionList parses comma separated expressions up to the end
+// token, starting on the opening token and leaving currentToken on end. A
+// trailing comma is allowed.
+func (p *Parser) parseExpressionList(end token.TokenType) ([]ast.ExpressionNode, bool) {
+	list := []ast.ExpressionNode{}
 	p.loadNextToken()
 
-	if p.currentToken.Type != token.LPAREN {
-		p.raiseTokenError(token.LPAREN)
+	for p.currentToken.Type != end {
+		expr, ok := p.parseExpression(precedences.LOWEST)
+		if !ok {
+			return nil, false
+		}
+		list = append(list, expr)
+		p.loadNextToken()
+
+		if p.currentToken.Type == token.COMMA {
+			p.loadNextToken()
+		} else if p.currentToken.Type != end {
+			p.raiseCurrentTokenError(end)
+			return nil, false
+		}
 	}
+
+	return list, true
+}
+
+// parseHashLiteral parses {key: value, ...}, starting on '{' and leaving
+// currentToken on '}'. A trailing comma is allowed.
+func (p *Parser) parseHashLiteral() (*ast.HashLiteral, bool) {
+	hashLit := &ast.HashLiteral{Token: p.currentToken}
 	p.loadNextToken()
 
-	// function call
-	arguments := []ast.ExpressionNode{}
-	for p.currentToken.Type != token.RPAREN {
-		arg, ok := p.parseExpression(precedences.CALL)
+	for p.currentToken.Type != token.RBRACE {
+		key, ok := p.parseExpression(precedences.LOWEST)
 		if !ok {
-			p.raiseError("Could not parse function call argument")
-			return nil
+			return nil, false
+		}
+		p.loadNextToken()
+
+		if p.currentToken.Type != token.COLON {
+			p.raiseCurrentTokenError(token.COLON)
+			return nil, false
+		}
+		p.loadNextToken()
+
+		value, ok := p.parseExpression(precedences.LOWEST)
+		if !ok {
+			return nil, false
 		}
-		arguments = append(arguments, arg)
 		p.loadNextToken()
 
+		hashLit.Pairs = append(hashLit.Pairs, ast.HashPair{Key: key, Value: value})
+
 		if p.currentToken.Type == token.COMMA {
 			p.loadNextToken()
+		} else if p.currentToken.Type != token.RBRACE {
+			p.raiseCurrentTokenError(token.RBRACE)
+			return nil, false
 		}
 	}
-	// p.loadNextToken()
 
-	return &ast.FunctionCall{*functionIdentifier, arguments}
+	hashLit.Rbrace = p.currentToken.Span.Start
+	return hashLit, true
+}
+
+// parseIndexExpression parses left[index] or the slice left[start:end],
+// where start and end are optional. currentToken is left on ']'.
+func (p *Parser) parseIndexExpression(left ast.ExpressionNode) ast.ExpressionNode {
+	p.loadNextToken()
+	p.loadNextToken()
+
+	var start ast.ExpressionNode
+	if p.currentToken.Type != token.COLON {
+		index, ok := p.parseExpression(precedences.LOWEST)
+		if !ok {
+			return nil
+		}
+		p.loadNextToken()
+
+		if p.currentToken.Type == token.RBRACKET {
+			return &ast.IndexExpression{left, index, p.currentToken.Span.Start}
+		}
+		start = index
+	}
+
+	if p.currentToken.Type != token.COLON {
+		p.raiseCurrentTokenError(token.RBRACKET)
+		return nil
+	}
+	p.loadNextToken()
+
+	var end ast.ExpressionNode
+	if p.currentToken.Type != token.RBRACKET {
+		var ok bool
+		end, ok = p.parseExpression(precedences.LOWEST)
+		if !ok {
+			return nil
+		}
+		p.loadNextToken()
+	}
+
+	if p.currentToken.Type != token.RBRACKET {
+		p.raiseCurrentTokenError(token.RBRACKET)
+		return nil
+	}
+	return &ast.SliceExpression{left, start, end, p.currentToken.Span.Start}
 }
 
 func (p *Parser) getCurrentPrecedence() int {
@@ -334,11 +506,11 @@ func (p *Parser) getCurrentPrecedence() int {
 
 func (p *Parser) getNextPrecedence() int {
 	if p.nextToken.Type == token.SEMICOLON {
-		p.raiseError("Unexpected semicolon;")
 		return precedences.LOWEST
 	}
 
-	nextIsPrefix := (p.nextToken.Type == token.MINUS || p.nextToken.Type == token.BANG)
+	nextIsPrefix := (p.nextToken.Type == token.MINUS || p.nextToken.Type == token.BANG ||
+		p.nextToken.Type == token.BIT_NOT)
 	if nextIsPrefix && p.getCurrentPrecedence() > precedences.LOWEST {
 		return precedences.PREFIX
 	}
@@ -0,0 +1,253 @@
+package parser
+
+import (
+	"fmt"
+	"gorilla/ast"
+	"gorilla/parser/precedences"
+	"gorilla/token"
+)
+
+// parseMatch parses `match (value) { pattern => body, ... }`, starting on
+// 'match' and leaving currentToken on '}'. A trailing comma is allowed.
+func (p *Parser) parseMatch() (*ast.Match, bool) {
+	match := &ast.Match{Token: p.currentToken}
+	if p.nextToken.Type != token.LPAREN {
+		p.raiseNextTokenError(token.LPAREN)
+		return nil, false
+	}
+	p.loadNextToken()
+
+	value, ok := p.parseExpression(precedences.LOWEST)
+	if !ok {
+		return nil, false
+	}
+	match.Value = value
+
+	if p.nextToken.Type != token.LBRACE {
+		p.raiseNextTokenError(token.LBRACE)
+		return nil, false
+	}
+	p.loadNextToken()
+	p.loadNextToken()
+
+	for p.currentToken.Type != token.RBRACE {
+		arm, ok := p.parseMatchArm()
+		if !ok {
+			return nil, false
+		}
+		match.Arms = append(match.Arms, arm)
+		p.loadNextToken()
+
+		if p.currentToken.Type == token.COMMA {
+			p.loadNextToken()
+		} else if p.currentToken.Type != token.RBRACE {
+			p.raiseCurrentTokenError(token.RBRACE)
+			return nil, false
+		}
+	}
+	match.Rbrace = p.currentToken.Span.Start
+
+	if !match.IsExhaustive() {
+		p.addWarning(&ParseError{
+			Kind:   WarnNonExhaustive,
+			Actual: match.Token,
+			Span:   match.GetSpan(),
+			Msg:    "match has no wildcard arm, an unmatched value is a runtime error",
+			Hint:   "add a `_ => ...` arm",
+		})
+	}
+	return match, true
+}
+
+// parseMatchArm parses `pattern => body` or `pattern if guard => body`,
+// leaving currentToken on the last token of the body.
+func (p *Parser) parseMatchArm() (*ast.MatchArm, bool) {
+	pattern, ok := p.parsePattern()
+	if !ok {
+		return nil, false
+	}
+	if !p.checkBindings(pattern) {
+		return nil, false
+	}
+	arm := &ast.MatchArm{Pattern: pattern}
+	p.loadNextToken()
+
+	if p.currentToken.Type == token.IF {
+		p.loadNextToken()
+		guard, ok := p.parseExpression(precedences.LOWEST)
+		if !ok {
+			return nil, false
+		}
+		arm.Guard = guard
+		p.loadNextToken()
+	}
+
+	if p.currentToken.Type != token.ARROW {
+		p.raiseCurrentTokenError(token.ARROW)
+		return nil, false
+	}
+	p.loadNextToken()
+
+	body, ok := p.parseExpression(precedences.LOWEST)
+	if !ok {
+		return nil, false
+	}
+	arm.Body = body
+	return arm, true
+}
+
+// checkBindings reports a name bound twice by the same pattern.
+func (p *Parser) checkBindings(pattern ast.Pattern) bool {
+	seen := map[string]bool{}
+	for _, ident := range ast.PatternBindings(pattern) {
+		if seen[ident.GetName()] {
+			p.addError(&ParseError{
+				Kind:   ErrInvalidSyntax,
+				Actual: ident.Token,
+				Span:   ident.GetSpan(),
+				Msg:    fmt.Sprintf("%s is bound more than once in the same pattern", ident.GetName()),
+			})
+			return false
+		}
+		seen[ident.GetName()] = true
+	}
+	return true
+}
+
+// parsePattern parses a wildcard, a binding, a literal, or an array or hash
+// of patterns, leaving currentToken on its last token.
+func (p *Parser) parsePattern() (ast.Pattern, bool) {
+	switch p.currentToken.Type {
+	case token.IDENT:
+		if p.currentToken.Literal == "_" {
+			return &ast.WildcardPattern{Token: p.currentToken}, true
+		}
+		return &ast.BindingPattern{Identifier: &ast.IdentifierExpression{p.currentToken}}, true
+
+	case token.INT, token.FLOAT, token.STRING, token.TRUE, token.FALSE, token.MINUS:
+		literal, ok := p.parsePatternLiteral()
+		if !ok {
+			return nil, false
+		}
+		return &ast.LiteralPattern{Value: literal}, true
+
+	case token.LBRACKET:
+		return p.parseArrayPattern()
+
+	case token.LBRACE:
+		return p.parseHashPattern()
+
+	default:
+		p.raiseError(fmt.Sprintf("Expected pattern, got %s token instead", p.currentToken.Type))
+		return nil, false
+	}
+}
+
+// parsePatternLiteral parses a number, string or bool literal. Numbers may
+// be negative.
+func (p *Parser) parsePatternLiteral() (ast.ExpressionNode, bool) {
+	switch p.currentToken.Type {
+	case token.TRUE, token.FALSE:
+		return &ast.BoolLiteral{p.currentToken}, true
+
+	case token.STRING:
+		return &ast.StringLiteral{Token: p.currentToken}, true
+
+	case token.INT, token.FLOAT:
+		numLit, err := newNumberLiteral(p.currentToken)
+		if err != nil {
+			p.raiseLiteralError(err)
+			return nil, false
+		}
+		return numLit, true
+
+	case token.MINUS:
+		if p.nextToken.Type != token.INT && p.nextToken.Type != token.FLOAT {
+			p.raiseNextTokenError(token.INT)
+			return nil, false
+		}
+		minus := p.currentToken
+		p.loadNextToken()
+
+		numLit, err := newNumberLiteral(token.Token{
+			Type:    p.currentToken.Type,
+			Literal: "-" + p.currentToken.Literal,
+			Span:    token.Span{Start: minus.Span.Start, End: p.currentToken.Span.End},
+		})
+		if err != nil {
+			p.raiseLiteralError(err)
+			return nil, false
+		}
+		return numLit, true
+
+	default:
+		p.raiseError(fmt.Sprintf("Expected literal, got %s token instead", p.currentToken.Type))
+		return nil, false
+	}
+}
+
+// parseArrayPattern parses [pattern, ...], starting on '[' and leaving
+// currentToken on ']'. A trailing comma is allowed.
+func (p *Parser) parseArrayPattern() (*ast.ArrayPattern, bool) {
+	arrPattern := &ast.ArrayPattern{Token: p.currentToken, Elements: []ast.Pattern{}}
+	p.loadNextToken()
+
+	for p.currentToken.Type != token.RBRACKET {
+		element, ok := p.parsePattern()
+		if !ok {
+			return nil, false
+		}
+		arrPattern.Elements = append(arrPattern.Elements, element)
+		p.loadNextToken()
+
+		if p.currentToken.Type == token.COMMA {
+			p.loadNextToken()
+		} else if p.currentToken.Type != token.RBRACKET {
+			p.raiseCurrentTokenError(token.RBRACKET)
+			return nil, false
+		}
+	}
+
+	arrPattern.Rbracket = p.currentToken.Span.Start
+	return arrPattern, true
+}
+
+// parseHashPattern parses {key: pattern, ...}, where every key is a
+// literal, starting on '{' and leaving currentToken on '}'. A trailing comma
+// is allowed.
+func (p *Parser) parseHashPattern() (*ast.HashPattern, bool) {
+	hashPattern := &ast.HashPattern{Token: p.currentToken}
+	p.loadNextToken()
+
+	for p.currentToken.Type != token.RBRACE {
+		key, ok := p.parsePatternLiteral()
+		if !ok {
+			return nil, false
+		}
+		p.loadNextToken()
+
+		if p.currentToken.Type != token.COLON {
+			p.raiseCurrentTokenError(token.COLON)
+			return nil, false
+		}
+		p.loadNextToken()
+
+		value, ok := p.parsePattern()
+		if !ok {
+			return nil, false
+		}
+		p.loadNextToken()
+
+		hashPattern.Pairs = append(hashPattern.Pairs, ast.HashPatternPair{Key: key, Value: value})
+
+		if p.currentToken.Type == token.COMMA {
+			p.loadNextToken()
+		} else if p.currentToken.Type != token.RBRACE {
+			p.raiseCurrentTokenError(token.RBRACE)
+			return nil, false
+		}
+	}
+
+	hashPattern.Rbrace = p.currentToken.Span.Start
+	return hashPattern, true
+}
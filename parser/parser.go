@@ -11,11 +11,14 @@ type Parser struct {
 	currentToken token.Token
 	nextToken    token.Token
 
-	Errors []string
+	Errors    ErrorList
+	Warnings  ErrorList
+	panicking bool // set after an error until the parser has synchronized
+	loopDepth int  // the number of loops around the current statement, reset in a function body
 }
 
 func NewParser(lx *lexer.Lexer) *Parser {
-	p := &Parser{lx: lx, Errors: []string{}}
+	p := &Parser{lx: lx, Errors: ErrorList{}, Warnings: ErrorList{}}
 	// read two tokens, so currentToken and nextToken are both set
 	p.loadNextToken()
 	p.loadNextToken()
@@ -49,9 +52,3 @@ func (p *Parser) reset() {
 	p.loadNextToken()
 	p.loadNextToken()
 }
-
-func (p *Parser) skipToSemicolon() {
-	for p.currentToken.Type != token.SEMICOLON {
-		p.loadNextToken()
-	}
-}
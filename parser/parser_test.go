@@ -5,6 +5,8 @@ import (
 	"gorilla/expected"
 	"gorilla/lexer"
 	"gorilla/token"
+	"strconv"
+	"strings"
 	"testing"
 )
 
@@ -64,11 +66,59 @@ func TestPrefixExpressions(t *testing.T) {
 	})
 }
 
+func TestNumberLiterals(t *testing.T) {
+	testParseProgram(t, `
+		let pi = 3.14;
+		let half = -.5;
+		let big = 1e9;
+		return 1.5 * 2;
+	`, []expected.Node{
+		&expected.LetStatement{"pi", &expected.FloatLiteral{3.14}},
+		&expected.LetStatement{"half", &expected.FloatLiteral{-0.5}},
+		&expected.LetStatement{"big", &expected.FloatLiteral{1e9}},
+		&expected.ReturnStatement{
+			&expected.Infix{token.ASTERISK, &expected.FloatLiteral{1.5}, expected.NewIntegerLiteral(2)},
+		},
+	})
+
+	// integers too large for int64 are still valid literals
+	testParseProgram(t, "let big = 9223372036854775808;", []expected.Node{
+		&expected.LetStatement{"big", &expected.SkipNode{}},
+	})
+
+	invalidTests := []struct {
+		input string
+		hint  string
+	}{
+		{"0b12;", "malformed number"},
+		{"0x;", "malformed number"},
+		{"1__0;", "malformed number"},
+		{"1e400;", "float literal too large"},
+	}
+	for _, tt := range invalidTests {
+		p := NewParser(lexer.NewLexer(tt.input))
+		if _, ok := p.ParseProgram(); ok {
+			t.Errorf("Expected ParseProgram to fail on %q", tt.input)
+			continue
+		}
+		if p.Errors[0].Kind != ErrInvalidLiteral {
+			t.Errorf("%q: expected %s. got %s", tt.input, ErrInvalidLiteral, p.Errors[0].Kind)
+		}
+		if p.Errors[0].Hint != tt.hint {
+			t.Errorf("%q: expected hint %q. got %q", tt.input, tt.hint, p.Errors[0].Hint)
+		}
+	}
+
+	if hint := literalHint(token.INT, &strconv.NumError{Func: "ParseInt", Num: "1", Err: strconv.ErrRange}); hint != "integer literal too large" {
+		t.Errorf("Expected the integer range hint. got %q", hint)
+	}
+}
+
 func TestInfixExpressions(t *testing.T) {
 
 	testParseProgram(t, `
-		return 3 < 5 == True;
-		let a = 5 < 4 != 3 > 4;
+		return (3 < 5) == True;
+		let a = (5 < 4) != (3 > 4);
 		return 3 + 4 * 5 == 3 * 1 + 4 * 5;
 	`, []expected.Node{
 		&expected.ReturnStatement{
@@ -127,6 +177,52 @@ func TestInfixExpressions(t *testing.T) {
 	})
 }
 
+func TestOperatorPrecedence(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"-2 ** 2;", "(- (2 ** 2))"},
+		{"2 ** 3 ** 2;", "(2 ** (3 ** 2))"},
+		{"2 ** -1;", "(2 ** -1)"},
+		{"-x ** 2 * 3;", "((- (x ** 2)) * 3)"},
+		{"a % b * c;", "((a % b) * c)"},
+		{"1 + 2 << 3;", "((1 + 2) << 3)"},
+		{"a & b | c ^ d;", "((a & b) | (c ^ d))"},
+		{"a | b & c << 1;", "(a | (b & (c << 1)))"},
+		{"a >> 1 == b & 1;", "((a >> 1) == (b & 1))"},
+		{"~a & ~b;", "((~ a) & (~ b))"},
+		{"a < b | c;", "(a < (b | c))"},
+		{"a == b && c == d;", "((a == b) && (c == d))"},
+		{"a || b && c;", "(a || (b && c))"},
+		{"a && b || c && d;", "((a && b) || (c && d))"},
+		{"a <= b == c >= d;", "(a <= b == c >= d)"},
+		{"!a && -b < c;", "((! a) && ((- b) < c))"},
+		{"a || b if c else d;", "(a || b if c else d)"},
+		{"0 <= i < n;", "(0 <= i < n)"},
+		{"a < b > c <= d >= e;", "(a < b > c <= d >= e)"},
+		{"a == b != c;", "(a == b != c)"},
+		// equality chains with the other comparisons, as in Python
+		{"a < b == c < d;", "(a < b == c < d)"},
+		{"1 == 1 < 2;", "(1 == 1 < 2)"},
+		{"(a < b) == (c < d);", "((a < b) == (c < d))"},
+		{"a < b + 1 < c && d;", "((a < (b + 1) < c) && d)"},
+	}
+
+	for _, tt := range tests {
+		p := NewParser(lexer.NewLexer(tt.input))
+		prog, ok := p.ParseProgram()
+		if !ok {
+			t.Errorf("%q: %s", tt.input, p.Err())
+			continue
+		}
+		stmt := prog.Statements[0].(*ast.ExpressionStatement)
+		if stmt.Expression.ToString() != tt.expected {
+			t.Errorf("%q: expected %s. got %s", tt.input, tt.expected, stmt.Expression.ToString())
+		}
+	}
+}
+
 func TestGroupedExpressions(t *testing.T) {
 	testParseProgram(t, `
 		let x = (5 + 5) * 2;
@@ -167,7 +263,7 @@ func TestBlockStatements(t *testing.T) {
 		let x = 5;
 		{
 			{
-				let a = 5 < 4 != 3 > 4;
+				let a = (5 < 4) != (3 > 4);
 				return !x; 
 			}
 			return (x > 1) && !a;
@@ -199,7 +295,7 @@ func TestBlockStatements(t *testing.T) {
 				},
 			),
 			&expected.ReturnStatement{
-				&expected.Infix{
+				&expected.Logical{
 					token.AND,
 					&expected.Infix{
 						token.GT,
@@ -258,6 +354,62 @@ func TestIfElseStatements(t *testing.T) {
 	})
 }
 
+func TestElifStatements(t *testing.T) {
+	testParseProgram(t, `
+	if (x == y) {
+		let x = 5;
+	} elif (x > y) {
+		let x = 6;
+	} else {
+		let x = 10;
+	}
+	`, []expected.Node{
+		&expected.IfStatement{
+			&expected.Infix{
+				token.EQ,
+				&expected.Identifier{Name: "x"},
+				&expected.Identifier{Name: "y"},
+			},
+			expected.NewBlockStatement(
+				&expected.LetStatement{"x", expected.NewIntegerLiteral(5)},
+			),
+			expected.NewElseIfStatement(
+				&expected.Infix{
+					token.GT,
+					&expected.Identifier{Name: "x"},
+					&expected.Identifier{Name: "y"},
+				},
+				expected.NewBlockStatement(
+					&expected.LetStatement{"x", expected.NewIntegerLiteral(6)},
+				),
+				&expected.ElseStatement{
+					Statement: expected.NewBlockStatement(
+						&expected.LetStatement{"x", expected.NewIntegerLiteral(10)},
+					),
+				},
+			),
+		},
+	})
+}
+
+func TestIfStatementWithoutElse(t *testing.T) {
+	testParseProgram(t, `
+	if (x) {
+		let y = 1;
+	}
+	return y;
+	`, []expected.Node{
+		&expected.IfStatement{
+			&expected.Identifier{Name: "x"},
+			expected.NewBlockStatement(
+				&expected.LetStatement{"y", expected.NewIntegerLiteral(1)},
+			),
+			nil,
+		},
+		&expected.ReturnStatement{&expected.Identifier{Name: "y"}},
+	})
+}
+
 func TestIfElseExpressions(t *testing.T) {
 	testParseProgram(t, `
 	let x = True;
@@ -320,6 +472,249 @@ func TestIfElseExpressions(t *testing.T) {
 
 }
 
+func TestLoopStatements(t *testing.T) {
+	testParseProgram(t, `
+	while (x < 10) {
+		if (x == 5) { break; }
+		let x = x + 1;
+	}
+	for (item in [1, 2]) {
+		continue;
+	}
+	`, []expected.Node{
+		&expected.WhileStatement{
+			&expected.Infix{
+				token.LT,
+				&expected.Identifier{Name: "x"},
+				expected.NewIntegerLiteral(10),
+			},
+			expected.NewBlockStatement(
+				&expected.IfStatement{
+					&expected.Infix{
+						token.EQ,
+						&expected.Identifier{Name: "x"},
+						expected.NewIntegerLiteral(5),
+					},
+					expected.NewBlockStatement(&expected.BreakStatement{}),
+					nil,
+				},
+				&expected.LetStatement{"x",
+					&expected.Infix{
+						token.PLUS,
+						&expected.Identifier{Name: "x"},
+						expected.NewIntegerLiteral(1),
+					},
+				},
+			),
+		},
+		&expected.ForStatement{
+			"item",
+			&expected.ArrayLiteral{[]expected.ExpressionNode{
+				expected.NewIntegerLiteral(1),
+				expected.NewIntegerLiteral(2),
+			}},
+			expected.NewBlockStatement(&expected.ContinueStatement{}),
+		},
+	})
+}
+
+func TestLoopControlOutsideLoop(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"break;", "1:1: 'break' outside of a loop"},
+		{"if (x) { continue; }", "1:10: 'continue' outside of a loop"},
+		{"while (x) { let f = fn() { break; }; }", "1:28: 'break' outside of a loop"},
+	}
+
+	for _, tt := range tests {
+		p := NewParser(lexer.NewLexer(tt.input))
+		if _, ok := p.ParseProgram(); ok {
+			t.Errorf("Expected ParseProgram to fail on %q", tt.input)
+			continue
+		}
+		if p.Errors[0].Error() != tt.expected {
+			t.Errorf("%s - expected %q. got %q", tt.input, tt.expected, p.Errors[0].Error())
+		}
+	}
+}
+
+func TestAssignments(t *testing.T) {
+	testParseProgram(t, `
+	x = 5;
+	total += x * 2;
+	xs[0] -= 1;
+	h["a"] = [x];
+	`, []expected.Node{
+		&expected.Assignment{
+			&expected.Identifier{Name: "x"},
+			token.ASSIGN,
+			expected.NewIntegerLiteral(5),
+		},
+		&expected.Assignment{
+			&expected.Identifier{Name: "total"},
+			token.PLUS_ASSIGN,
+			&expected.Infix{
+				token.ASTERISK,
+				&expected.Identifier{Name: "x"},
+				expected.NewIntegerLiteral(2),
+			},
+		},
+		&expected.Assignment{
+			&expected.IndexExpression{
+				&expected.Identifier{Name: "xs"},
+				expected.NewIntegerLiteral(0),
+			},
+			token.MINUS_ASSIGN,
+			expected.NewIntegerLiteral(1),
+		},
+		&expected.Assignment{
+			&expected.IndexExpression{
+				&expected.Identifier{Name: "h"},
+				&expected.StringLiteral{"a"},
+			},
+			token.ASSIGN,
+			&expected.ArrayLiteral{[]expected.ExpressionNode{&expected.Identifier{Name: "x"}}},
+		},
+	})
+}
+
+func TestAssignmentErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"1 = 2;", "1:3: Cannot assign to 1"},
+		{"f() += 1;", "1:5: Cannot assign to f()"},
+		{"xs[1:] = [];", "1:8: Cannot assign to (xs[1:])"},
+		{"x = ;", "1:5: Expected expression, got ; token instead"},
+	}
+
+	for _, tt := range tests {
+		p := NewParser(lexer.NewLexer(tt.input))
+		if _, ok := p.ParseProgram(); ok {
+			t.Errorf("Expected ParseProgram to fail on %q", tt.input)
+			continue
+		}
+		if p.Errors[0].Error() != tt.expected {
+			t.Errorf("%s - expected %q. got %q", tt.input, tt.expected, p.Errors[0].Error())
+		}
+	}
+}
+
+func TestMatchExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		warnings int
+	}{
+		{"match (x) { 1 => a, _ => b };", "match (x) { 1 => a, _ => b }", 0},
+		{"match (x + 1) { -1 => \"neg\", 2.5 => True, n => n, };", "match ((x + 1)) { -1 => \"neg\", 2.5 => True, n => n }", 0},
+		{"match (x) { [a, _, [b]] if a > b => a - b, _ => 0 };", "match (x) { [a, _, [b]] if (a > b) => (a - b), _ => 0 }", 0},
+		{"match (x) { {\"k\": [v], 1: True} => v, [] => 0 };", "match (x) { {\"k\": [v], 1: True} => v, [] => 0 }", 1},
+		{"match (x) { n if n > 0 => 1 if n > 9 else 2 };", "match (x) { n if (n > 0) => 1 if (n > 9) else 2 }", 1},
+		{"let y = match (x) { False => 0, _ => 1 } + 1;", "let y = (match (x) { False => 0, _ => 1 } + 1);", 0},
+	}
+
+	for _, tt := range tests {
+		p := NewParser(lexer.NewLexer(tt.input))
+		prog, ok := p.ParseProgram()
+		if !ok {
+			t.Errorf("%q: %s", tt.input, p.Err())
+			continue
+		}
+		var actual string
+		if stmt, ok := prog.Statements[0].(*ast.ExpressionStatement); ok {
+			actual = stmt.Expression.ToString()
+		} else {
+			actual = prog.Statements[0].ToString()
+		}
+		if actual != tt.expected {
+			t.Errorf("%q: expected %s. got %s", tt.input, tt.expected, actual)
+		}
+		if len(p.Warnings) != tt.warnings {
+			t.Errorf("%q: expected %d warnings. got %d", tt.input, tt.warnings, len(p.Warnings))
+		}
+	}
+}
+
+func TestMatchPatterns(t *testing.T) {
+	p := NewParser(lexer.NewLexer(`match (x) { [1, a, _] => 0, {"k": b} => 1, c => 2 };`))
+	prog, ok := p.ParseProgram()
+	if !ok {
+		t.Fatal(p.Err())
+	}
+	match := prog.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.Match)
+
+	arrPattern, ok := match.Arms[0].Pattern.(*ast.ArrayPattern)
+	if !ok || len(arrPattern.Elements) != 3 {
+		t.Fatalf("Expected an array pattern of 3 elements. got %s", match.Arms[0].Pattern.ToString())
+	}
+	if _, ok := arrPattern.Elements[0].(*ast.LiteralPattern); !ok {
+		t.Errorf("Expected a literal pattern. got %T", arrPattern.Elements[0])
+	}
+	if _, ok := arrPattern.Elements[1].(*ast.BindingPattern); !ok {
+		t.Errorf("Expected a binding pattern. got %T", arrPattern.Elements[1])
+	}
+	if _, ok := arrPattern.Elements[2].(*ast.WildcardPattern); !ok {
+		t.Errorf("Expected a wildcard pattern. got %T", arrPattern.Elements[2])
+	}
+	if _, ok := match.Arms[1].Pattern.(*ast.HashPattern); !ok {
+		t.Errorf("Expected a hash pattern. got %T", match.Arms[1].Pattern)
+	}
+	if !match.IsExhaustive() {
+		t.Errorf("Expected the binding arm to make the match exhaustive")
+	}
+}
+
+func TestMatchErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"match (x) { [a, a] => a };", "1:17: a is bound more than once in the same pattern"},
+		{"match (x) { 1 -> 2 };", "1:15: Expected => token, got - token instead"},
+		{"match (x) { a + 1 => 2 };", "1:15: Expected => token, got + token instead"},
+		{"match (x) { (a) => 2 };", "1:13: Expected pattern, got ( token instead"},
+		{"match (x) { {k: 1} => 2 };", "1:14: Expected literal, got IDENT token instead"},
+		{"match (x) { 1 => 2 3 => 4 };", "1:20: Expected } token, got INT token instead"},
+		{"match x { _ => 1 };", "1:7: Expected ( token, got IDENT token instead"},
+	}
+
+	for _, tt := range tests {
+		p := NewParser(lexer.NewLexer(tt.input))
+		if _, ok := p.ParseProgram(); ok {
+			t.Errorf("Expected ParseProgram to fail on %q", tt.input)
+			continue
+		}
+		if p.Errors[0].Error() != tt.expected {
+			t.Errorf("%s - expected %q. got %q", tt.input, tt.expected, p.Errors[0].Error())
+		}
+	}
+}
+
+func TestNonExhaustiveMatchWarning(t *testing.T) {
+	p := NewParser(lexer.NewLexer("let y = match (x) {\n\t1 => 2,\n\tn if n > 1 => n,\n};"))
+	if _, ok := p.ParseProgram(); !ok {
+		t.Fatal(p.Err())
+	}
+	if len(p.Warnings) != 1 {
+		t.Fatalf("Expected 1 warning. got %d", len(p.Warnings))
+	}
+
+	warning := p.Warnings[0]
+	if warning.Kind != WarnNonExhaustive {
+		t.Errorf("Expected a %s warning. got %s", WarnNonExhaustive, warning.Kind)
+	}
+	if warning.Error() != "1:9: match has no wildcard arm, an unmatched value is a runtime error" {
+		t.Errorf("Unexpected warning %q", warning.Error())
+	}
+	if warning.Hint == "" {
+		t.Errorf("Expected the warning to have a hint")
+	}
+}
+
 func TestFunctionDeclarations(t *testing.T) {
 	testParseProgram(t, `
 		let add = fn(x, y) {
@@ -413,7 +808,7 @@ func TestFunctionCalls(t *testing.T) {
 		},
 		&expected.ReturnStatement{
 			&expected.FunctionCall{
-				expected.Identifier{"add"},
+				&expected.Identifier{"add"},
 				[]expected.ExpressionNode{
 					expected.NewIntegerLiteral(5),
 					expected.NewIntegerLiteral(5),
@@ -421,6 +816,472 @@ func TestFunctionCalls(t *testing.T) {
 		},
 	},
 	)
+
+	testParseProgram(t, `
+		return add(n - 1, 2 * n);
+	`, []expected.Node{
+		&expected.ReturnStatement{
+			&expected.FunctionCall{
+				&expected.Identifier{"add"},
+				[]expected.ExpressionNode{
+					&expected.Infix{
+						token.MINUS,
+						&expected.Identifier{Name: "n"},
+						expected.NewIntegerLiteral(1),
+					},
+					&expected.Infix{
+						token.ASTERISK,
+						expected.NewIntegerLiteral(2),
+						&expected.Identifier{Name: "n"},
+					},
+				}},
+		},
+	})
+}
+
+func TestCallExpressions(t *testing.T) {
+	testParseProgram(t, `
+		fn(x) { return x; }(5);
+		makeAdder(1)(2);
+		fns[0](a,);
+		-f(1);
+		(g)();
+	`, []expected.Node{
+		&expected.ExpressionStatement{
+			&expected.FunctionCall{
+				&expected.FunctionLiteral{
+					Signiture: []expected.Identifier{{"x"}},
+					Body: expected.NewBlockStatement(
+						&expected.ReturnStatement{&expected.Identifier{Name: "x"}},
+					),
+				},
+				[]expected.ExpressionNode{expected.NewIntegerLiteral(5)},
+			},
+		},
+		&expected.ExpressionStatement{
+			&expected.FunctionCall{
+				&expected.FunctionCall{
+					&expected.Identifier{"makeAdder"},
+					[]expected.ExpressionNode{expected.NewIntegerLiteral(1)},
+				},
+				[]expected.ExpressionNode{expected.NewIntegerLiteral(2)},
+			},
+		},
+		&expected.ExpressionStatement{
+			&expected.FunctionCall{
+				&expected.IndexExpression{
+					&expected.Identifier{"fns"},
+					expected.NewIntegerLiteral(0),
+				},
+				[]expected.ExpressionNode{&expected.Identifier{"a"}},
+			},
+		},
+		// calls bind tighter than prefix operators
+		&expected.ExpressionStatement{
+			&expected.Prefix{token.MINUS,
+				&expected.FunctionCall{
+					&expected.Identifier{"f"},
+					[]expected.ExpressionNode{expected.NewIntegerLiteral(1)},
+				},
+			},
+		},
+		&expected.ExpressionStatement{
+			&expected.FunctionCall{&expected.Identifier{"g"}, []expected.ExpressionNode{}},
+		},
+	})
+}
+
+func TestExpressionStatements(t *testing.T) {
+	testParseProgram(t, `
+		x;
+		add(1, 2);
+		-x + 1;
+		fn(a) { a; };
+		{ y * 2; }
+	`, []expected.Node{
+		&expected.ExpressionStatement{&expected.Identifier{Name: "x"}},
+		&expected.ExpressionStatement{
+			&expected.FunctionCall{
+				&expected.Identifier{"add"},
+				[]expected.ExpressionNode{
+					expected.NewIntegerLiteral(1),
+					expected.NewIntegerLiteral(2),
+				}},
+		},
+		&expected.ExpressionStatement{
+			&expected.Infix{
+				token.PLUS,
+				&expected.Prefix{token.MINUS, &expected.Identifier{Name: "x"}},
+				expected.NewIntegerLiteral(1),
+			},
+		},
+		&expected.ExpressionStatement{
+			&expected.FunctionLiteral{
+				Signiture: []expected.Identifier{{"a"}},
+				Body: expected.NewBlockStatement(
+					&expected.ExpressionStatement{&expected.Identifier{Name: "a"}},
+				),
+			},
+		},
+		expected.NewBlockStatement(
+			&expected.ExpressionStatement{
+				&expected.Infix{
+					token.ASTERISK,
+					&expected.Identifier{Name: "y"},
+					expected.NewIntegerLiteral(2),
+				},
+			},
+		),
+	})
+}
+
+func TestStringLiterals(t *testing.T) {
+	testParseProgram(t, `
+		let s = "hello\tworld";
+		"a" + s;
+	`, []expected.Node{
+		&expected.LetStatement{"s", &expected.StringLiteral{"hello\tworld"}},
+		&expected.ExpressionStatement{
+			&expected.Infix{
+				token.PLUS,
+				&expected.StringLiteral{"a"},
+				&expected.Identifier{Name: "s"},
+			},
+		},
+	})
+}
+
+func TestArrayLiterals(t *testing.T) {
+	testParseProgram(t, `
+		[];
+		[1, 2 * 2, "three",];
+	`, []expected.Node{
+		&expected.ExpressionStatement{&expected.ArrayLiteral{[]expected.ExpressionNode{}}},
+		&expected.ExpressionStatement{
+			&expected.ArrayLiteral{[]expected.ExpressionNode{
+				expected.NewIntegerLiteral(1),
+				&expected.Infix{
+					token.ASTERISK,
+					expected.NewIntegerLiteral(2),
+					expected.NewIntegerLiteral(2),
+				},
+				&expected.StringLiteral{"three"},
+			}},
+		},
+	})
+}
+
+func TestIndexExpressions(t *testing.T) {
+	testParseProgram(t, `
+		arr[1 + 1];
+		-arr[0] * 2;
+		[1, 2][0][1];
+		s[1:];
+		s[:-1];
+		s[a:b];
+	`, []expected.Node{
+		&expected.ExpressionStatement{
+			&expected.IndexExpression{
+				&expected.Identifier{Name: "arr"},
+				&expected.Infix{
+					token.PLUS,
+					expected.NewIntegerLiteral(1),
+					expected.NewIntegerLiteral(1),
+				},
+			},
+		},
+		&expected.ExpressionStatement{
+			&expected.Infix{
+				token.ASTERISK,
+				&expected.Prefix{
+					token.MINUS,
+					&expected.IndexExpression{
+						&expected.Identifier{Name: "arr"},
+						expected.NewIntegerLiteral(0),
+					},
+				},
+				expected.NewIntegerLiteral(2),
+			},
+		},
+		&expected.ExpressionStatement{
+			&expected.IndexExpression{
+				&expected.IndexExpression{
+					&expected.ArrayLiteral{[]expected.ExpressionNode{
+						expected.NewIntegerLiteral(1),
+						expected.NewIntegerLiteral(2),
+					}},
+					expected.NewIntegerLiteral(0),
+				},
+				expected.NewIntegerLiteral(1),
+			},
+		},
+		&expected.ExpressionStatement{
+			&expected.SliceExpression{&expected.Identifier{Name: "s"}, expected.NewIntegerLiteral(1), nil},
+		},
+		&expected.ExpressionStatement{
+			&expected.SliceExpression{&expected.Identifier{Name: "s"}, nil, expected.NewIntegerLiteral(-1)},
+		},
+		&expected.ExpressionStatement{
+			&expected.SliceExpression{
+				&expected.Identifier{Name: "s"},
+				&expected.Identifier{Name: "a"},
+				&expected.Identifier{Name: "b"},
+			},
+		},
+	})
+}
+
+func TestHashLiterals(t *testing.T) {
+	testParseProgram(t, `
+		let empty = {};
+		let h = {"a": 1, 2: True, key: 1 + 1,};
+		f({"x": [1]})["x"];
+		{ "not a hash"; }
+		{"a": 1}["a"];
+		{};
+		{x + 1: 2}[k];
+		{"a" + "b": 1};
+		{ s[1:2]; {"a": 1}; }
+	`, []expected.Node{
+		&expected.LetStatement{"empty", &expected.HashLiteral{}},
+		&expected.LetStatement{"h",
+			&expected.HashLiteral{[]expected.HashPair{
+				{&expected.StringLiteral{"a"}, expected.NewIntegerLiteral(1)},
+				{expected.NewIntegerLiteral(2), expected.NewBoolLiteral(true)},
+				{&expected.Identifier{Name: "key"}, &expected.Infix{
+					token.PLUS,
+					expected.NewIntegerLiteral(1),
+					expected.NewIntegerLiteral(1),
+				}},
+			}},
+		},
+		&expected.ExpressionStatement{
+			&expected.IndexExpression{
+				&expected.FunctionCall{
+					&expected.Identifier{"f"},
+					[]expected.ExpressionNode{
+						&expected.HashLiteral{[]expected.HashPair{
+							{&expected.StringLiteral{"x"}, &expected.ArrayLiteral{[]expected.ExpressionNode{
+								expected.NewIntegerLiteral(1),
+							}}},
+						}},
+					}},
+				&expected.StringLiteral{"x"},
+			},
+		},
+		expected.NewBlockStatement(
+			&expected.ExpressionStatement{&expected.StringLiteral{"not a hash"}},
+		),
+		&expected.ExpressionStatement{
+			&expected.IndexExpression{
+				&expected.HashLiteral{[]expected.HashPair{
+					{&expected.StringLiteral{"a"}, expected.NewIntegerLiteral(1)},
+				}},
+				&expected.StringLiteral{"a"},
+			},
+		},
+		&expected.ExpressionStatement{&expected.HashLiteral{}},
+		// keys of any expression, not just of one token
+		&expected.ExpressionStatement{
+			&expected.IndexExpression{
+				&expected.HashLiteral{[]expected.HashPair{
+					{&expected.Infix{
+						token.PLUS,
+						&expected.Identifier{Name: "x"},
+						expected.NewIntegerLiteral(1),
+					}, expected.NewIntegerLiteral(2)},
+				}},
+				&expected.Identifier{Name: "k"},
+			},
+		},
+		&expected.ExpressionStatement{
+			&expected.HashLiteral{[]expected.HashPair{
+				{&expected.Infix{
+					token.PLUS,
+					&expected.StringLiteral{"a"},
+					&expected.StringLiteral{"b"},
+				}, expected.NewIntegerLiteral(1)},
+			}},
+		},
+		// a ':' inside brackets or a nested hash does not make a block a hash
+		expected.NewBlockStatement(
+			&expected.ExpressionStatement{
+				&expected.SliceExpression{
+					&expected.Identifier{Name: "s"},
+					expected.NewIntegerLiteral(1),
+					expected.NewIntegerLiteral(2),
+				},
+			},
+			&expected.ExpressionStatement{
+				&expected.HashLiteral{[]expected.HashPair{
+					{&expected.StringLiteral{"a"}, expected.NewIntegerLiteral(1)},
+				}},
+			},
+		),
+	})
+}
+
+func TestParserErrorPositions(t *testing.T) {
+	input := "let x = 5;\nlet y 10;\n"
+	file := token.NewFileSet().AddFile("main.gor", len(input))
+	p := NewParser(lexer.NewFileLexer(file, input))
+
+	_, ok := p.ParseProgram()
+	if ok {
+		t.Fatalf("Expected ParseProgram to fail on %q", input)
+	}
+
+	expectedPrefix := "main.gor:2:7: "
+	if len(p.Errors) == 0 || !strings.HasPrefix(p.Errors[0].Error(), expectedPrefix) {
+		t.Errorf("Expected first error to start with %q. got %q", expectedPrefix, p.Errors)
+	}
+}
+
+func TestParserErrorRecovery(t *testing.T) {
+	input := `let x = 5
+let y = );
+return 1 +;
+let z = 3;
+{ let a = ; }
+`
+	p := NewParser(lexer.NewLexer(input))
+
+	prog, ok := p.ParseProgram()
+	if ok {
+		t.Fatalf("Expected ParseProgram to fail on %q", input)
+	}
+
+	tests := []struct {
+		kind     ErrorKind
+		position string
+	}{
+		{ErrUnexpectedToken, "2:1"},
+		{ErrMissingExpression, "2:9"},
+		{ErrMissingExpression, "3:11"},
+		{ErrMissingExpression, "5:11"},
+	}
+
+	if len(p.Errors) != len(tests) {
+		t.Fatalf("Expected %d errors. got %d:\n%s", len(tests), len(p.Errors), p.Err())
+	}
+	for i, tt := range tests {
+		err := p.Errors[i]
+		if err.Kind != tt.kind || err.Position.String() != tt.position {
+			t.Errorf("errors[%d] - expected %s at %s. got %s at %s",
+				i, tt.kind, tt.position, err.Kind, err.Position,
+			)
+		}
+	}
+
+	if p.Errors[0].Expected != token.SEMICOLON || p.Errors[0].Actual.Type != token.LET {
+		t.Errorf("errors[0] - expected ; instead of let. got %s instead of %s",
+			p.Errors[0].Expected, p.Errors[0].Actual.Type,
+		)
+	}
+
+	// the valid statements around the errors are still parsed
+	if len(prog.Statements) != 2 {
+		t.Fatalf("Expected 2 statements. got %d", len(prog.Statements))
+	}
+	(&expected.LetStatement{"z", expected.NewIntegerLiteral(3)}).Test(t, prog.Statements[0])
+	expected.NewBlockStatement().Test(t, prog.Statements[1])
+
+	var err error = p.Errors
+	if err.Error() != p.Err().Error() {
+		t.Errorf("Expected ErrorList to be usable as an error")
+	}
+}
+
+func TestComments(t *testing.T) {
+	testParseProgram(t, `
+		// the answer
+		let x = /* not /* 41 */ */ 42; // done
+	`, []expected.Node{
+		&expected.LetStatement{"x", expected.NewIntegerLiteral(42)},
+	})
+}
+
+func TestUnterminatedCommentError(t *testing.T) {
+	input := "let x = 1 +\n/* open"
+	p := NewParser(lexer.NewLexer(input))
+
+	if _, ok := p.ParseProgram(); ok {
+		t.Fatalf("Expected ParseProgram to fail on %q", input)
+	}
+	if len(p.Errors) != 1 {
+		t.Fatalf("Expected 1 error. got %d:\n%s", len(p.Errors), p.Err())
+	}
+	err := p.Errors[0]
+	expectedMsg := "2:1: Unterminated block comment, expected */"
+	if err.Kind != ErrInvalidToken || err.Error() != expectedMsg {
+		t.Errorf("Expected %s %q. got %s %q", ErrInvalidToken, expectedMsg, err.Kind, err.Error())
+	}
+}
+
+func TestStringError(t *testing.T) {
+	tests := []struct {
+		input       string
+		expectedMsg string
+	}{
+		{`print("a\q");`, `1:7: Invalid escape sequence \q`},
+		{`print("\u{110000}");`, `1:7: Invalid escape sequence \u{110000}`},
+		{`print("open);`, `1:7: Unterminated string, expected "`},
+	}
+
+	for _, tt := range tests {
+		p := NewParser(lexer.NewLexer(tt.input))
+		if _, ok := p.ParseProgram(); ok {
+			t.Fatalf("Expected ParseProgram to fail on %q", tt.input)
+		}
+		err := p.Errors[0]
+		if err.Kind != ErrInvalidToken || err.Error() != tt.expectedMsg {
+			t.Errorf("%s: expected %s %q. got %s %q", tt.input, ErrInvalidToken, tt.expectedMsg, err.Kind, err.Error())
+		}
+	}
+}
+
+func TestNodeSpans(t *testing.T) {
+	input := "let add = fn(a, b) {\n\treturn a + b;\n};\nreturn add(1, -2);"
+	lx := lexer.NewLexer(input)
+	p := NewParser(lx)
+
+	prog, ok := p.ParseProgram()
+	if !ok {
+		raiseParserErrors(t, prog.Statements, p.Errors)
+		return
+	}
+
+	letStmt := prog.Statements[0].(*ast.LetStatement)
+	fnLit := letStmt.Expression.(*ast.FunctionLiteral)
+	returnStmt := fnLit.Body.Statements[0].(*ast.ReturnStatement)
+	fnCall := prog.Statements[1].(*ast.ReturnStatement).ReturnValue.(*ast.FunctionCall)
+
+	tests := []struct {
+		node     ast.Node
+		expected string
+	}{
+		{letStmt, "let add = fn(a, b) {\n\treturn a + b;\n}"},
+		{fnLit, "fn(a, b) {\n\treturn a + b;\n}"},
+		{fnLit.Body, "{\n\treturn a + b;\n}"},
+		{returnStmt, "return a + b"},
+		{returnStmt.ReturnValue, "a + b"},
+		{fnCall, "add(1, -2)"},
+		{fnCall.Arguments[1], "-2"},
+	}
+
+	file := lx.GetFile()
+	for i, tt := range tests {
+		span := tt.node.GetSpan()
+		source := input[file.Offset(span.Start):file.Offset(span.End)]
+		if source != tt.expected {
+			t.Errorf("tests[%d] - wrong span. expected=%q, got %q", i, tt.expected, source)
+		}
+	}
+
+	position := file.Position(returnStmt.GetSpan().Start)
+	if position.Line != 2 || position.Column != 2 {
+		t.Errorf("Expected return statement at 2:2. got %s", position)
+	}
 }
 
 func TestParser(t *testing.T) {
@@ -465,7 +1326,7 @@ func testParseProgram(t *testing.T,
 
 }
 
-func raiseParserErrors(t *testing.T, stmts []ast.StatementNode, errors []string) {
+func raiseParserErrors(t *testing.T, stmts []ast.StatementNode, errors ErrorList) {
 	// print statements
 	for _, stmt := range stmts {
 		t.Logf("Parsed statement: %q", stmt.ToString())
@@ -476,8 +1337,8 @@ func raiseParserErrors(t *testing.T, stmts []ast.StatementNode, errors []string)
 		return
 	}
 
-	for _, msg := range errors {
-		t.Error(msg)
+	for _, err := range errors {
+		t.Error(err)
 	}
 	// t.FailNow()
 }
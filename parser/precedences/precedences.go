@@ -6,29 +6,47 @@ const (
 	_ int = iota
 	LOWEST
 	// TRINARY
-	EQUALS  // ==
-	COMPARE // > or <
+	OR      // ||
+	AND     // &&
+	COMPARE // ==, !=, <, >, <= or >=, which chain with each other
+	BIT_OR  // |
+	BIT_XOR // ^
+	BIT_AND // &
+	SHIFT   // << or >>
 	SUM     // +
-	PRODUCT // *
-	PREFIX  // -X or !X
-	CALL    // myFunction(X)
+	PRODUCT // * or %
+	PREFIX  // -X or !X or ~X
+	POWER   // **, binds tighter than a prefix operator on its left: -2 ** 2 == -4
+	CALL    // myFunction(X) or array[X]
 )
 
 var Precedence = map[token.TokenType]int{
 	// token.IF:       TRINARY,
 	// token.ELSE:     TRINARY,
-	token.EQ:       EQUALS,
-	token.NOT_EQ:   EQUALS,
-	token.LE:       EQUALS,
-	token.GE:       EQUALS,
+	token.OR:       OR,
+	token.AND:      AND,
+	token.EQ:       COMPARE,
+	token.NOT_EQ:   COMPARE,
+	token.LE:       COMPARE,
+	token.GE:       COMPARE,
 	token.LT:       COMPARE,
 	token.GT:       COMPARE,
 	token.PLUS:     SUM,
 	token.MINUS:    SUM,
 	token.ASTERISK: PRODUCT,
 	token.SLASH:    PRODUCT,
+	token.PERCENT:  PRODUCT,
+	token.POWER:    POWER,
+
+	token.BIT_OR:      BIT_OR,
+	token.BIT_XOR:     BIT_XOR,
+	token.BIT_AND:     BIT_AND,
+	token.SHIFT_LEFT:  SHIFT,
+	token.SHIFT_RIGHT: SHIFT,
+
 	// token.BANG:     PREFIX,
 	// token.MINUS:    PREFIX,
-	token.AND: PRODUCT,
-	token.OR:  PRODUCT,
+
+	token.LPAREN:   CALL,
+	token.LBRACKET: CALL,
 }
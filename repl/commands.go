@@ -0,0 +1,141 @@
+package repl
+
+import (
+	"fmt"
+	"gorilla/ast"
+	"gorilla/lexer"
+	"gorilla/parser"
+	"gorilla/token"
+	"io"
+	"strings"
+	"time"
+)
+
+// metaCommand is a REPL command starting with ':', run instead of being
+// evaluated as code.
+type metaCommand struct {
+	name string
+	args string
+	help string
+	run  func(out io.Writer, session *Session, arg string)
+}
+
+var metaCommands []metaCommand
+
+func init() {
+	// assigned in init because :help refers to metaCommands
+	metaCommands = []metaCommand{
+		{":tokens", "<src>", "print the tokens of src", runTokens},
+		{":ast", "<src>", "print the syntax tree of src", runAst},
+		{":env", "", "list the bindings of the session", runEnv},
+		{":load", "<file>", "evaluate file in the session", runLoad},
+		{":save", "<file>", "write the definitions of the session to file", runSave},
+		{":time", "<expr>", "evaluate expr and report how long it took", runTime},
+		{CANCEL, "", "discard the pending lines of an incomplete input", nil},
+		{RESET, "", "clear the session", runReset},
+		{":help", "", "list the commands", runHelp},
+	}
+}
+
+// isMetaCommand reports whether line is a meta-command rather than code.
+func isMetaCommand(line string) bool {
+	return strings.HasPrefix(strings.TrimSpace(line), ":")
+}
+
+// runMetaCommand runs the meta-command on line.
+func runMetaCommand(out io.Writer, session *Session, line string) {
+	name, arg, _ := strings.Cut(strings.TrimSpace(line), " ")
+	arg = strings.TrimSpace(arg)
+
+	for _, cmd := range metaCommands {
+		if cmd.name != name {
+			continue
+		}
+		if cmd.args != "" && arg == "" {
+			fmt.Fprintf(out, "usage: %s %s\n", cmd.name, cmd.args)
+			return
+		}
+		// :cancel has nothing to do without pending lines
+		if cmd.run != nil {
+			cmd.run(out, session, arg)
+		}
+		return
+	}
+	fmt.Fprintf(out, "unknown command %s, see :help\n", name)
+}
+
+func runTokens(out io.Writer, session *Session, src string) {
+	file := token.NewFileSet().AddFile("", len(src))
+	lx := lexer.NewFileLexer(file, src)
+	for {
+		tok := lx.GetNextToken()
+		position := file.Position(tok.Span.Start)
+		fmt.Fprintf(out, "%d:%d\t%s\t%q\n", position.Line, position.Column, tok.Type, tok.Literal)
+		if tok.Type == token.EOF {
+			return
+		}
+	}
+}
+
+func runAst(out io.Writer, session *Session, src string) {
+	file := token.NewFileSet().AddFile("", len(src))
+	p := parser.NewParser(lexer.NewFileLexer(file, src))
+	prog, ok := p.ParseProgram()
+	if !ok {
+		printParserErrors(out, nil, p.Errors)
+		return
+	}
+	for _, stmt := range prog.Statements {
+		ast.Fprint(out, file, stmt)
+	}
+}
+
+func runEnv(out io.Writer, session *Session, _ string) {
+	for _, name := range session.env.Names() {
+		value, _ := session.env.Get(name)
+		fmt.Fprintf(out, "%s = %s\n", name, value.Inspect())
+	}
+}
+
+func runLoad(out io.Writer, session *Session, path string) {
+	result, errors, err := session.Load(path)
+	if err != nil {
+		fmt.Fprintln(out, err)
+		return
+	}
+	printResult(out, session, result, errors)
+}
+
+func runSave(out io.Writer, session *Session, path string) {
+	if err := session.Save(path); err != nil {
+		fmt.Fprintln(out, err)
+		return
+	}
+	fmt.Fprintf(out, "saved %d definitions to %s\n", len(session.Definitions()), path)
+}
+
+func runTime(out io.Writer, session *Session, src string) {
+	if !strings.HasSuffix(src, ";") {
+		src += ";"
+	}
+
+	start := time.Now()
+	result, errors := session.Eval(src)
+	elapsed := time.Since(start)
+
+	printResult(out, session, result, errors)
+	if errors == nil {
+		fmt.Fprintf(out, "time: %s\n", elapsed)
+	}
+}
+
+func runReset(out io.Writer, session *Session, _ string) {
+	session.Reset()
+}
+
+func runHelp(out io.Writer, session *Session, _ string) {
+	for _, cmd := range metaCommands {
+		usage := strings.TrimSpace(cmd.name + " " + cmd.args)
+		fmt.Fprintf(out, "  %-16s %s\n", usage, cmd.help)
+	}
+}
@@ -0,0 +1,154 @@
+package repl
+
+import (
+	"gorilla/lexer"
+	"gorilla/parser/precedences"
+	"gorilla/token"
+	"strings"
+)
+
+// continuationTokens cannot end a complete input: they are always followed
+// by an operand, a name or a block. Infix operators are found through
+// precedences.Precedence.
+var continuationTokens = map[token.TokenType]bool{
+	token.ASSIGN:   true,
+	token.BANG:     true,
+	token.BIT_NOT:  true,
+	token.COMMA:    true,
+	token.COLON:    true,
+	token.LET:      true,
+	token.RETURN:   true,
+	token.IF:       true,
+	token.WHILE:    true,
+	token.FOR:      true,
+	token.IN:       true,
+	token.ELSE:     true,
+	token.ELIF:     true,
+	token.FUNCTION: true,
+	token.MATCH:    true,
+	token.ARROW:    true,
+
+	token.PLUS_ASSIGN:     true,
+	token.MINUS_ASSIGN:    true,
+	token.ASTERISK_ASSIGN: true,
+	token.SLASH_ASSIGN:    true,
+}
+
+// headerKeywords are followed by a parenthesized header and a block.
+var headerKeywords = map[token.TokenType]bool{
+	token.IF:       true,
+	token.ELIF:     true,
+	token.WHILE:    true,
+	token.FOR:      true,
+	token.FUNCTION: true,
+	token.MATCH:    true,
+}
+
+// isIncomplete reports whether input needs more lines before it can be
+// parsed: a bracket is still open, a string or block comment is
+// unterminated, it ends with an operator, or it ends with the header of an
+// `if`, `elif`, `while`, `for`, `fn` or `match` that has no block yet. Anything else is
+// submitted, so real syntax errors are still reported by the parser.
+func isIncomplete(input string) bool {
+	lx := lexer.NewLexer(input)
+
+	// the open brackets, and for '(' whether it is the header of a block
+	var open []token.TokenType
+	var headers []bool
+
+	previous := token.Token{Type: token.SEMICOLON}
+	closedHeader := false
+
+	for tok := lx.GetNextToken(); tok.Type != token.EOF; tok = lx.GetNextToken() {
+		closedHeader = false
+
+		switch tok.Type {
+		case token.LPAREN, token.LBRACE, token.LBRACKET:
+			open = append(open, tok.Type)
+			isHeader := tok.Type == token.LPAREN &&
+				headerKeywords[previous.Type]
+			headers = append(headers, isHeader)
+
+		case token.RPAREN, token.RBRACE, token.RBRACKET:
+			if len(open) == 0 {
+				// unbalanced, let the parser report it
+				return false
+			}
+			closedHeader = headers[len(headers)-1]
+			open = open[:len(open)-1]
+			headers = headers[:len(headers)-1]
+
+		case token.ILLEGAL:
+			isUnterminatedString := strings.HasPrefix(tok.Literal, `"`) &&
+				lx.GetFile().Offset(tok.Span.End) >= len(input)
+			if isUnterminatedString {
+				return true
+			}
+			// an unterminated block comment always runs to the end
+			if strings.HasPrefix(tok.Literal, "/*") {
+				return true
+			}
+		}
+
+		previous = tok
+	}
+
+	if len(open) > 0 || closedHeader {
+		return true
+	}
+	if _, isInfix := precedences.Precedence[previous.Type]; isInfix {
+		return true
+	}
+	return continuationTokens[previous.Type]
+}
+
+// endsWithIfBlock reports whether input ends with the block of an `if` or
+// `elif`, which an `else` or `elif` on the next line would continue. Such an
+// input is only submitted once the next line shows it does not.
+func endsWithIfBlock(input string) bool {
+	lx := lexer.NewLexer(input)
+
+	// the open brackets, and whether each is the header or block of an if
+	var open []token.TokenType
+	var ifBrackets []bool
+
+	previous := token.Token{Type: token.SEMICOLON}
+	closedIfHeader := false
+	closedIfBlock := false
+
+	for tok := lx.GetNextToken(); tok.Type != token.EOF; tok = lx.GetNextToken() {
+		isIfBracket := false
+		switch tok.Type {
+		case token.LPAREN:
+			isIfBracket = previous.Type == token.IF || previous.Type == token.ELIF
+		case token.LBRACE:
+			isIfBracket = closedIfHeader
+		}
+		closedIfHeader, closedIfBlock = false, false
+
+		switch tok.Type {
+		case token.LPAREN, token.LBRACE, token.LBRACKET:
+			open = append(open, tok.Type)
+			ifBrackets = append(ifBrackets, isIfBracket)
+
+		case token.RPAREN, token.RBRACE, token.RBRACKET:
+			if len(open) == 0 {
+				return false
+			}
+			isIf := ifBrackets[len(ifBrackets)-1]
+			closedIfHeader = isIf && tok.Type == token.RPAREN
+			closedIfBlock = isIf && tok.Type == token.RBRACE && len(open) == 1
+			open = open[:len(open)-1]
+			ifBrackets = ifBrackets[:len(ifBrackets)-1]
+		}
+
+		previous = tok
+	}
+	return closedIfBlock
+}
+
+// startsWithElse reports whether line continues an if statement.
+func startsWithElse(line string) bool {
+	tok := lexer.NewLexer(line).GetNextToken()
+	return tok.Type == token.ELSE || tok.Type == token.ELIF
+}
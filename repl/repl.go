@@ -2,54 +2,117 @@ package repl
 
 import (
 	"bufio"
-	"fmt"
 	"gorilla/ast"
-	"gorilla/lexer"
 	"gorilla/object"
 	"gorilla/parser"
-	"gorilla/token"
 	"io"
+	"strings"
 )
 
-const PROMPT = ">>"
+const (
+	PROMPT              = ">>"
+	CONTINUATION_PROMPT = ".."
 
+	// CANCEL discards the pending lines of an incomplete input.
+	CANCEL = ":cancel"
+	// RESET clears the session.
+	RESET = ":reset"
+)
+
+// Start reads inputs from in and writes their results to out. An input
+// that is not complete yet, like a function body still missing its '}',
+// continues on the next line until it is complete or cancelled. An input
+// ending with the block of an if waits for the next line too, which may
+// start with else or elif; an empty line submits it as it is. Inputs
+// share one Session, so later inputs see the bindings of earlier ones.
+// Lines starting with ':' are meta-commands, listed by :help.
 func Start(in io.Reader, out io.Writer) {
 	scanner := bufio.NewScanner(in)
+	session := NewSession()
 
+	pending := ""
 	for {
-		fmt.Print(PROMPT)
+		if pending == "" {
+			io.WriteString(out, PROMPT)
+		} else {
+			io.WriteString(out, CONTINUATION_PROMPT)
+		}
 
 		ok := scanner.Scan()
 		if !ok {
+			// submit what is left so its errors are still reported
+			if strings.TrimSpace(pending) != "" {
+				io.WriteString(out, "\n")
+				evalInput(out, session, pending)
+			}
 			return
 		}
 
-		newLine := scanner.Text()
-		lx := lexer.NewLexer(newLine)
-		p := parser.NewParser(lx)
+		line := scanner.Text()
+		if pending != "" && strings.TrimSpace(line) == CANCEL {
+			pending = ""
+			continue
+		}
+		if pending != "" && endsWithIfBlock(pending) && !startsWithElse(line) {
+			// the if statement is over; run it, then read line on its own
+			evalInput(out, session, pending)
+			pending = ""
+			if strings.TrimSpace(line) == "" {
+				continue
+			}
+		}
+		if pending == "" && isMetaCommand(line) {
+			runMetaCommand(out, session, line)
+			continue
+		}
 
-		prog, ok := p.ParseProgram()
-		if !ok {
-			printParserErrors(out, prog.Statements, p.Errors)
+		input := pending + line + "\n"
+		if isIncomplete(input) || endsWithIfBlock(input) {
+			pending = input
 			continue
 		}
+		pending = ""
 
-		// print statements
-		io.WriteString(out, "Parsed Program:\n")
-		for _, stmt := range prog.Statements {
-			io.WriteString(out, stmt.ToString())
+		evalInput(out, session, input)
+	}
+}
+
+// evalInput evaluates one complete input in session and prints its value.
+func evalInput(out io.Writer, session *Session, input string) {
+	result, errors := session.Eval(input)
+	printResult(out, session, result, errors)
+}
+
+// printResult prints the value or the errors of an input evaluated in
+// session.
+func printResult(out io.Writer, session *Session, result object.Object, errors parser.ErrorList) {
+	for _, warning := range session.Warnings() {
+		io.WriteString(out, "\tParser warning: "+warning.Error()+"\n")
+		if warning.Hint != "" {
+			io.WriteString(out, "\t\thint: "+warning.Hint+"\n")
 		}
-		io.WriteString(out, "\n[END]\n")
+	}
+
+	if errors != nil {
+		printParserErrors(out, nil, errors)
+		return
+	}
 
-		// for tok := lx.GetNextToken(); tok.Type != token.EOF; {
-		// 	fmt.Printf("%+v\n", tok)
-		// 	tok = lx.GetNextToken()
-		// }
+	// like Python, the value of the last statement is echoed unless it
+	// is None, so `let x = 5;` prints nothing and `x + 1;` prints 6
+	if result.GetType() == object.NONE {
+		return
+	}
 
+	if errObj, ok := result.(*object.Error); ok {
+		position := session.Position(errObj.Span.Start)
+		io.WriteString(out, position.String()+": ")
 	}
+	io.WriteString(out, result.Inspect())
+	io.WriteString(out, "\n")
 }
 
-func printParserErrors(out io.Writer, stmts []ast.StatementNode, errors []string) {
+func printParserErrors(out io.Writer, stmts []ast.StatementNode, errors parser.ErrorList) {
 	if out == nil {
 		panic("Cannot print Parser errors: nil writer")
 	}
@@ -64,60 +127,12 @@ func printParserErrors(out io.Writer, stmts []ast.StatementNode, errors []string
 		return
 	}
 
-	for _, msg := range errors {
-		io.WriteString(out, "\t"+msg+"\n")
+	for _, err := range errors {
+		io.WriteString(out, "\tParser error: "+err.Error()+"\n")
+		if err.Hint != "" {
+			io.WriteString(out, "\t\thint: "+err.Hint+"\n")
+		}
 	}
 	// io.WriteString(out, "[END]\n")
 	// t.FailNow()
 }
-
-func evalExpression(expr ast.ExpressionNode) object.Object {
-	switch node := expr.(type) {
-	case *ast.BoolLiteral:
-		return &object.Bool{node.GetValue()}
-
-	case *ast.IntegerLiteral:
-		return &object.Int{node.GetValue()}
-
-	case *ast.Infix:
-		left := evalExpression(node.Left)
-		right := evalExpression(node.Right)
-		switch node.GetOperatorType() {
-		case token.EQ:
-			return &object.Bool{objIsEqual(left, right)}
-		default:
-			panic("")
-		}
-
-	default:
-		return &object.None{}
-	}
-}
-
-func objIsEqual(left object.Object, right object.Object) bool {
-	if left == nil || right == nil {
-		panic("Cannot compare nil objects")
-	}
-
-	if left.GetType() != right.GetType() {
-		return false
-	}
-
-	switch left.GetType() {
-	case object.BOOL:
-		leftBool := left.(*object.Bool)
-		rightBool := right.(*object.Bool)
-		return leftBool.Value == rightBool.Value
-
-	case object.INT:
-		leftInt := left.(*object.Int)
-		rightInt := right.(*object.Int)
-		return leftInt.Value == rightInt.Value
-
-	case object.NONE:
-		return true
-
-	default:
-		return false
-	}
-}
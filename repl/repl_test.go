@@ -1,67 +1,329 @@
 package repl
 
 import (
-	"gorilla/ast"
-	"gorilla/lexer"
-	"gorilla/object"
-	"gorilla/parser"
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
 func TestEvalProgram(t *testing.T) {
-	testEvalProgram(t, `
+	testReplOutput(t, `
 		return 5;
-	`, []object.Object{
-		&object.Int{Value: 5},
+	`, []string{
+		"5",
 	})
 }
 
-func testEvalProgram(t *testing.T,
-	testInput string, expectedObjects []object.Object,
-) {
-	lx := lexer.NewLexer(testInput)
-	p := parser.NewParser(lx)
+func TestEvalExpressionStatements(t *testing.T) {
+	testReplOutput(t, `
+		let x = 5;
+		let x = 5; x + 1;
+		let f = fn(a) { return a * 2; }; f(4);
+		let noop = fn() { }; noop();
+	`, []string{
+		"6",
+		"8",
+	})
+}
+
+func TestMultiLineInput(t *testing.T) {
+	testReplOutput(t, `
+		let add = fn(a, b) {
+			return a + b;
+		}; add(
+			1,
+			2
+		);
+		if (1 > 2)
+		{ 1; } else {
+			"two";
+		}
+		1 +
+		1;
+		"multi
+		line";
+		let broken = fn() {
+		:cancel
+		[1, 2
+	`, []string{
+		"3",
+		`"two"`,
+		"2",
+		`"multi\n\t\tline"`,
+		// the input left pending at EOF is still submitted
+		"\tParser error: 1:9: Expected ] token, got EOF token instead",
+	})
+}
+
+func TestElseOnNextLine(t *testing.T) {
+	testReplOutput(t, `
+		if (False) { 1; }
+		else { 2; }
+		if (False) { 3; }
+		elif (True) { 4; }
+		5;
+		if (True) { 6; }
+
+		:nope
+		if (True) { 7; }
+	`, []string{"2", "4", "5", "6", "unknown command :nope, see :help", "7"})
+}
+
+func TestParserWarnings(t *testing.T) {
+	testReplOutput(t, `
+		match (1) { 1 => "one" };
+		match (2) { 1 => "one", _ => "other" };
+	`, []string{
+		"\tParser warning: 1:1: match has no wildcard arm, an unmatched value is a runtime error",
+		"\t\thint: add a `_ => ...` arm",
+		`"one"`,
+		`"other"`,
+	})
+}
+
+func TestSessionState(t *testing.T) {
+	testReplOutput(t, `
+		let x = 5;
+		x + 1;
+		_ * 2;
+		_1 + _2;
+		let double = fn(n) {
+			return n * 2 / 0;
+		};
+		double(x);
+		:reset
+		x;
+		_;
+	`, []string{
+		"6",
+		"12",
+		"18",
+		// the error is located in the input that defined double
+		"2:11: Runtime error: division by zero",
+		"1:3: Runtime error: identifier not found: x",
+		"1:3: Runtime error: identifier not found: _",
+	})
+}
+
+func TestSessionDefinitions(t *testing.T) {
+	session := NewSession()
+	inputs := []string{
+		"let x = 1; let y = [x, 2];",
+		"let f = fn(a) {\n\treturn a;\n};",
+		"let x = 3;",
+		"let z = 1 / 0;",
+		"let broken = ;",
+		"f(x);",
+		// the definitions made before an input fails are kept
+		"let w = 4; let g = fn() { return w; }; 1 / 0; let v = 5;",
+	}
+	for _, input := range inputs {
+		session.Eval(input)
+	}
+
+	expected := []Definition{
+		{"y", "let y = [x, 2];"},
+		{"f", "let f = fn(a) {\n\treturn a;\n};"},
+		{"x", "let x = 3;"},
+		{"w", "let w = 4;"},
+		{"g", "let g = fn() { return w; };"},
+	}
+	definitions := session.Definitions()
+	if len(definitions) != len(expected) {
+		t.Fatalf("Expected %d definitions. got %v", len(expected), definitions)
+	}
+	for i, definition := range expected {
+		if definitions[i] != definition {
+			t.Errorf("definitions[%d] - expected %v. got %v", i, definition, definitions[i])
+		}
+	}
+
+	history := session.History()
+	if len(history) != 1 || history[0].Inspect() != "3" {
+		t.Errorf("Expected history [3]. got %v", history)
+	}
+
+	// data is saved with its current value, functions with their source
+	session.Eval(`x = 5; y[0] = "a"; let big = 1e308 * 10.0;`)
+	path := filepath.Join(t.TempDir(), "session.gor")
+	if err := session.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedSave := "let y = [\"a\", 2];\nlet f = fn(a) {\n\treturn a;\n};\nlet x = 5;\nlet w = 4;\n" +
+		"let g = fn() { return w; };\nlet big = 1e308 * 10.0;\n"
+	if string(data) != expectedSave {
+		t.Errorf("Unexpected saved session: %q", data)
+	}
 
-	prog, ok := p.ParseProgram()
-	if !ok {
-		// raiseParserErrors(t, prog.Statements, p.Errors)
-		t.Error("ParseProgram() returned nil")
-		return
+	session.Reset()
+	if len(session.Definitions()) != 0 || len(session.History()) != 0 {
+		t.Errorf("Expected an empty session after Reset")
 	}
+}
 
-	// test program statements
-	// num_nodes := len(expectedNodes)
-	// if len(prog.Statements) != num_nodes {
-	// 	raiseParserErrors(t, prog.Statements, p.Errors)
-	// 	raiseExpectedNotEqParsedError(t, prog, expectedNodes)
-	// 	return
-	// }
+func TestMetaCommands(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.gor")
+	testReplOutput(t, `
+		:tokens x + 1
+		:ast f(x)[1:];
+		let x = 2;
+		let s = "hi";
+		:env
+		:save `+path+`
+		:reset
+		:env
+		:load `+path+`
+		x;
+		:load
+		:nope
+		:cancel
+	`, []string{
+		"1:1\tIDENT\t\"x\"",
+		"1:3\t+\t\"+\"",
+		"1:5\tINT\t\"1\"",
+		"1:6\tEOF\t\"\"",
+		"ExpressionStatement 1:1",
+		"  SliceExpression 1:1",
+		"    FunctionCall 1:1",
+		"      function: Identifier f 1:1",
+		"      arg: Identifier x 1:3",
+		"    start: IntegerLiteral 1 1:6",
+		`s = "hi"`,
+		"x = 2",
+		"saved 2 definitions to " + path,
+		"2",
+		"usage: :load <file>",
+		"unknown command :nope, see :help",
+	})
 
-	for i, stmt := range prog.Statements {
-		testEvalStatement(t, stmt, expectedObjects[i])
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "let x = 2;\nlet s = \"hi\";\n" {
+		t.Errorf("Unexpected saved session: %q", data)
 	}
+}
 
+func TestTimeCommand(t *testing.T) {
+	var out bytes.Buffer
+	Start(strings.NewReader(":time 2 * 3\n"), &out)
+
+	output := strings.TrimPrefix(out.String(), PROMPT)
+	if !strings.HasPrefix(output, "6\ntime: ") {
+		t.Errorf("Unexpected :time output: %q", output)
+	}
 }
 
-func testEvalStatement(t *testing.T,
-	stmt ast.StatementNode,
-	expectedObj object.Object,
+func TestIsIncomplete(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"let x = 5;", false},
+		{"x", false},
+		{"let f = fn(a) {", true},
+		{"let f = fn(a) { return a; };", false},
+		{"f(1,", true},
+		{"[1, 2", true},
+		{`{"a": 1`, true},
+		{"1 +", true},
+		{"a ==", true},
+		{"let x =", true},
+		{"x +=", true},
+		{"x += 1;", false},
+		{"return", true},
+		{"if (x)", true},
+		{"if (x) { 1; } else", true},
+		{"if (x) { 1; }", false},
+		{"if (x) { 1; } elif (y)", true},
+		{"if (x) { 1; } elif", true},
+		{"match (x)", true},
+		{"match (x) { 1 =>", true},
+		{"match (x) { _ => 1 };", false},
+		{"while (x)", true},
+		{"for (x in", true},
+		{"for (x in xs)", true},
+		{"for (x in xs) { x; }", false},
+		{"let f = fn(a)", true},
+		{"f(a)", false},
+		{"1 if (x)", true},
+		{`"abc`, true},
+		{`"abc"`, false},
+		{"/* open", true},
+		{"let x = 1; /* a /* b */", true},
+		{"let x = 1; /* a /* b */ */", false},
+		{"let x = 1; // comment", false},
+		{"1 )", false},
+		{"}", false},
+	}
+
+	for _, tt := range tests {
+		if isIncomplete(tt.input) != tt.expected {
+			t.Errorf("isIncomplete(%q): expected %t", tt.input, tt.expected)
+		}
+	}
+}
+
+func TestEndsWithIfBlock(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"if (x) { 1; }", true},
+		{"if (x) { 1; } elif (y) { 2; }", true},
+		{"if (x) { 1; } else { 2; }", false},
+		{"if (x) { 1; } 2;", false},
+		{"if (f(x)) { if (y) { 1; } }", true},
+		{"while (x) { if (y) { 1; } }", false},
+		{"let f = fn() { if (x) { 1; } };", false},
+		{"1 if (x) else { 2; }", false},
+		{"{ 1; }", false},
+	}
+
+	for _, tt := range tests {
+		if endsWithIfBlock(tt.input) != tt.expected {
+			t.Errorf("endsWithIfBlock(%q): expected %t", tt.input, tt.expected)
+		}
+	}
+}
+
+func testReplOutput(t *testing.T,
+	testInput string, expectedLines []string,
 ) {
-	switch stmt := stmt.(type) {
-	case *ast.ReturnStatement:
-		if stmt.ReturnValue == nil {
-			t.Errorf("Invalid Return statement: ReturnValue is nil")
-			return
+	in := strings.NewReader(strings.TrimSpace(testInput) + "\n")
+	var out bytes.Buffer
+	Start(in, &out)
+
+	lines := []string{}
+	for _, line := range strings.Split(out.String(), "\n") {
+		// inputs without output leave their prompts on the same line
+		for strings.HasPrefix(line, PROMPT) || strings.HasPrefix(line, CONTINUATION_PROMPT) {
+			line = strings.TrimPrefix(line, PROMPT)
+			line = strings.TrimPrefix(line, CONTINUATION_PROMPT)
 		}
-		obj := evalExpression(stmt.ReturnValue)
-		if !objIsEqual(obj, expectedObj) {
-			t.Errorf("Return statement error: got=%s, expected=%s",
-				obj.Inspect(), expectedObj.Inspect(),
-			)
+		if line != "" {
+			lines = append(lines, line)
 		}
+	}
 
-	default:
-		return
+	if len(lines) != len(expectedLines) {
+		t.Fatalf("Expected %d lines of output. got %d: %q",
+			len(expectedLines), len(lines), out.String(),
+		)
 	}
 
+	for i, expectedLine := range expectedLines {
+		if lines[i] != expectedLine {
+			t.Errorf("Output line %d: got=%q, expected=%q",
+				i, lines[i], expectedLine,
+			)
+		}
+	}
 }
@@ -0,0 +1,201 @@
+package repl
+
+import (
+	"fmt"
+	"gorilla/ast"
+	"gorilla/eval"
+	"gorilla/lexer"
+	"gorilla/object"
+	"gorilla/parser"
+	"gorilla/token"
+	"math"
+	"os"
+	"strings"
+)
+
+// Definition is the source of a top-level let statement of the session.
+type Definition struct {
+	Name   string
+	Source string
+}
+
+// Session is the state a REPL keeps between inputs: the environment the
+// inputs are evaluated in, the definitions made so far and the history of
+// results. Result n is bound to `_n` and the latest one to `_`.
+type Session struct {
+	env         *object.Environment
+	fileSet     *token.FileSet
+	definitions []Definition
+	history     []object.Object
+	warnings    parser.ErrorList // of the latest input
+}
+
+func NewSession() *Session {
+	session := &Session{}
+	session.Reset()
+	return session
+}
+
+// Reset forgets every binding, definition and result.
+func (s *Session) Reset() {
+	s.env = object.NewEnvironment()
+	s.fileSet = token.NewFileSet()
+	s.definitions = nil
+	s.history = nil
+}
+
+// Eval parses and evaluates input in the session. It returns the parse
+// errors if input could not be parsed, otherwise its value, which is an
+// *object.Error if evaluation failed.
+func (s *Session) Eval(input string) (object.Object, parser.ErrorList) {
+	return s.evalFile("", input)
+}
+
+// Load evaluates the file at path in the session, as if it was typed in.
+func (s *Session) Load(path string) (object.Object, parser.ErrorList, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	result, errors := s.evalFile(path, string(data))
+	return result, errors, nil
+}
+
+// Save writes the definitions of the session to the file at path, so it
+// can be loaded again. A variable holding data is written with its current
+// value, so the assignments made to it since are kept. Any other, such as a
+// function, is written as the let statement that defined it, to be
+// evaluated again when loaded.
+func (s *Session) Save(path string) error {
+	var out strings.Builder
+	for _, definition := range s.definitions {
+		value, ok := s.env.Get(definition.Name)
+		if ok && isLiteral(value, map[object.Object]bool{}) {
+			fmt.Fprintf(&out, "let %s = %s;\n", definition.Name, value.Inspect())
+		} else {
+			out.WriteString(definition.Source + "\n")
+		}
+	}
+	return os.WriteFile(path, []byte(out.String()), 0o644)
+}
+
+// isLiteral reports whether the Inspect of obj is source evaluating to an
+// equal value. visiting holds the arrays and hashes obj is inside of, as
+// one that contains itself has no such source.
+func isLiteral(obj object.Object, visiting map[object.Object]bool) bool {
+	switch obj := obj.(type) {
+	case *object.None, *object.Bool, *object.Int, *object.BigInt, *object.String:
+		return true
+	case *object.Float:
+		return !math.IsNaN(obj.Value) && !math.IsInf(obj.Value, 0)
+
+	case *object.Array:
+		if visiting[obj] {
+			return false
+		}
+		visiting[obj] = true
+		defer delete(visiting, obj)
+		for _, element := range obj.Elements {
+			if !isLiteral(element, visiting) {
+				return false
+			}
+		}
+		return true
+
+	case *object.Hash:
+		if visiting[obj] {
+			return false
+		}
+		visiting[obj] = true
+		defer delete(visiting, obj)
+		for _, pair := range obj.GetPairs() {
+			if !isLiteral(pair.Key, visiting) || !isLiteral(pair.Value, visiting) {
+				return false
+			}
+		}
+		return true
+
+	default:
+		return false
+	}
+}
+
+func (s *Session) evalFile(name string, input string) (object.Object, parser.ErrorList) {
+	// every input is a file of its own, so positions stay unique and
+	// errors in functions defined by earlier inputs can still be located
+	file := s.fileSet.AddFile(name, len(input))
+	p := parser.NewParser(lexer.NewFileLexer(file, input))
+
+	prog, ok := p.ParseProgram()
+	s.warnings = p.Warnings
+	if !ok {
+		return nil, p.Errors
+	}
+
+	// the names bound before, to tell which let statements ran when the
+	// input fails part way
+	before := map[string]object.Object{}
+	for _, stmt := range prog.Statements {
+		if letStmt, ok := stmt.(*ast.LetStatement); ok {
+			name := letStmt.Identifier.GetName()
+			before[name], _ = s.env.Get(name)
+		}
+	}
+
+	result := eval.EvalProgram(prog, s.env)
+
+	for _, stmt := range prog.Statements {
+		letStmt, ok := stmt.(*ast.LetStatement)
+		if !ok {
+			continue
+		}
+		value, bound := s.env.Get(letStmt.Identifier.GetName())
+		if bound && value != before[letStmt.Identifier.GetName()] {
+			s.define(letStmt, file, input)
+		}
+	}
+	if result.GetType() == object.ERROR {
+		return result, nil
+	}
+
+	if result.GetType() != object.NONE {
+		s.history = append(s.history, result)
+		s.env.Set("_", result)
+		s.env.Set(fmt.Sprintf("_%d", len(s.history)), result)
+	}
+	return result, nil
+}
+
+// define records the source of letStmt, replacing an earlier definition of
+// the same name.
+func (s *Session) define(letStmt *ast.LetStatement, file *token.File, input string) {
+	name := letStmt.Identifier.GetName()
+	span := letStmt.GetSpan()
+	source := input[file.Offset(span.Start):file.Offset(span.End)] + ";"
+
+	for i, definition := range s.definitions {
+		if definition.Name == name {
+			s.definitions = append(s.definitions[:i], s.definitions[i+1:]...)
+			break
+		}
+	}
+	s.definitions = append(s.definitions, Definition{name, source})
+}
+
+// Position returns the position of pos in the inputs of the session.
+func (s *Session) Position(pos token.Pos) token.Position {
+	return s.fileSet.Position(pos)
+}
+
+func (s *Session) Definitions() []Definition {
+	return s.definitions
+}
+
+func (s *Session) History() []object.Object {
+	return s.history
+}
+
+// Warnings returns the parser warnings of the latest input.
+func (s *Session) Warnings() parser.ErrorList {
+	return s.warnings
+}
@@ -0,0 +1,143 @@
+package token
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Pos is a compact source position: the byte offset into a File plus the
+// File's base in its FileSet. The zero value NoPos means "no position".
+type Pos int
+
+const NoPos Pos = 0
+
+func (pos Pos) IsValid() bool {
+	return pos != NoPos
+}
+
+// Span is the half-open range [Start, End) covered by a token or node.
+type Span struct {
+	Start Pos
+	End   Pos
+}
+
+// Position is the human readable form of a Pos.
+type Position struct {
+	Filename string
+	Offset   int // byte offset, starting at 0
+	Line     int // starting at 1
+	Column   int // byte column, starting at 1
+}
+
+func (pos Position) IsValid() bool {
+	return pos.Line > 0
+}
+
+// String returns "file:line:col", "line:col" when there is no file name,
+// or "-" for an invalid position.
+func (pos Position) String() string {
+	if !pos.IsValid() {
+		if pos.Filename != "" {
+			return pos.Filename
+		}
+		return "-"
+	}
+
+	if pos.Filename == "" {
+		return fmt.Sprintf("%d:%d", pos.Line, pos.Column)
+	}
+	return fmt.Sprintf("%s:%d:%d", pos.Filename, pos.Line, pos.Column)
+}
+
+// File records the line offsets of a single source so that a Pos can be
+// turned back into a Position.
+type File struct {
+	name  string
+	base  int
+	size  int
+	lines []int // offset of the first byte of each line
+}
+
+func (f *File) GetName() string {
+	return f.name
+}
+
+func (f *File) GetBase() int {
+	return f.base
+}
+
+func (f *File) GetSize() int {
+	return f.size
+}
+
+// AddLine records the offset of a new line. Offsets that are not past the
+// last recorded line, or not inside the file, are ignored, so a lexer may
+// scan the same file more than once.
+func (f *File) AddLine(offset int) {
+	if offset >= f.size || offset <= f.lines[len(f.lines)-1] {
+		return
+	}
+	f.lines = append(f.lines, offset)
+}
+
+// Pos returns the Pos of the byte at offset. offset may equal the file size
+// to denote the end of the file.
+func (f *File) Pos(offset int) Pos {
+	offset = max(0, min(offset, f.size))
+	return Pos(f.base + offset)
+}
+
+func (f *File) Offset(pos Pos) int {
+	return int(pos) - f.base
+}
+
+func (f *File) Position(pos Pos) Position {
+	if !pos.IsValid() {
+		return Position{Filename: f.name}
+	}
+
+	offset := f.Offset(pos)
+	line := sort.Search(len(f.lines), func(i int) bool {
+		return f.lines[i] > offset
+	})
+	return Position{
+		Filename: f.name,
+		Offset:   offset,
+		Line:     line,
+		Column:   offset - f.lines[line-1] + 1,
+	}
+}
+
+// FileSet hands out non-overlapping Pos ranges to each added File, so a
+// single Pos identifies both the file and the offset within it.
+type FileSet struct {
+	base  int
+	files []*File
+}
+
+func NewFileSet() *FileSet {
+	return &FileSet{base: 1}
+}
+
+func (fs *FileSet) AddFile(filename string, size int) *File {
+	f := &File{name: filename, base: fs.base, size: size, lines: []int{0}}
+	fs.base += size + 1 // +1 so the end of file Pos is still unique
+	fs.files = append(fs.files, f)
+	return f
+}
+
+func (fs *FileSet) File(pos Pos) *File {
+	for _, f := range fs.files {
+		if f.base <= int(pos) && int(pos) <= f.base+f.size {
+			return f
+		}
+	}
+	return nil
+}
+
+func (fs *FileSet) Position(pos Pos) Position {
+	if f := fs.File(pos); f != nil {
+		return f.Position(pos)
+	}
+	return Position{}
+}
@@ -3,6 +3,20 @@ package token
 type Token struct {
 	Type    TokenType
 	Literal string
+	Span    Span
+
+	// Leading are the comments between the previous token and this one
+	// and Trailing the comments after this token on the same line. They
+	// are only filled in by a lexer that keeps comments.
+	Leading  []Comment
+	Trailing []Comment
+}
+
+// Comment is a `// line` or `/* block */` comment. Text includes the
+// comment markers.
+type Comment struct {
+	Text string
+	Span Span
 }
 
 func NewToken(inputType TokenType, inputChar byte) Token {
@@ -8,8 +8,10 @@ const (
 	EOF     TokenType = "EOF"
 
 	// Identifiers + literals
-	IDENT TokenType = "IDENT" // add, foobar, x, y, ...
-	INT   TokenType = "INT"   // 134345
+	IDENT  TokenType = "IDENT"  // add, foobar, x, y, ...
+	INT    TokenType = "INT"    // 134345, 0xFF, 1_000
+	FLOAT  TokenType = "FLOAT"  // 3.14, 1e9, .5
+	STRING TokenType = "STRING" // "foo bar"
 
 	// Operators
 	ASSIGN   TokenType = "="
@@ -18,6 +20,20 @@ const (
 	BANG     TokenType = "!"
 	ASTERISK TokenType = "*"
 	SLASH    TokenType = "/"
+	PERCENT  TokenType = "%"
+	POWER    TokenType = "**"
+
+	PLUS_ASSIGN     TokenType = "+="
+	MINUS_ASSIGN    TokenType = "-="
+	ASTERISK_ASSIGN TokenType = "*="
+	SLASH_ASSIGN    TokenType = "/="
+
+	BIT_AND     TokenType = "&"
+	BIT_OR      TokenType = "|"
+	BIT_XOR     TokenType = "^"
+	BIT_NOT     TokenType = "~"
+	SHIFT_LEFT  TokenType = "<<"
+	SHIFT_RIGHT TokenType = ">>"
 
 	LT TokenType = "<"
 	GT TokenType = ">"
@@ -34,6 +50,7 @@ const (
 	COMMA     TokenType = ","
 	SEMICOLON TokenType = ";"
 	COLON     TokenType = ":"
+	ARROW     TokenType = "=>"
 	LPAREN    TokenType = "("
 	RPAREN    TokenType = ")"
 	LBRACE    TokenType = "{"
@@ -48,19 +65,31 @@ const (
 	FALSE    TokenType = "FALSE"
 	IF       TokenType = "IF"
 	ELSE     TokenType = "ELSE"
-	// ELIF     TokenType = "ELIF"
-	RETURN TokenType = "RETURN"
+	ELIF     TokenType = "ELIF"
+	RETURN   TokenType = "RETURN"
+	WHILE    TokenType = "WHILE"
+	FOR      TokenType = "FOR"
+	IN       TokenType = "IN"
+	BREAK    TokenType = "BREAK"
+	CONTINUE TokenType = "CONTINUE"
+	MATCH    TokenType = "MATCH"
 )
 
 var keywords = map[string]TokenType{
-	"fn":    FUNCTION,
-	"let":   LET,
-	"True":  TRUE,
-	"False": FALSE,
-	"if":    IF,
-	"else":  ELSE,
-	// "elif":   ELIF,
-	"return": RETURN,
+	"fn":       FUNCTION,
+	"let":      LET,
+	"True":     TRUE,
+	"False":    FALSE,
+	"if":       IF,
+	"else":     ELSE,
+	"elif":     ELIF,
+	"return":   RETURN,
+	"while":    WHILE,
+	"for":      FOR,
+	"in":       IN,
+	"break":    BREAK,
+	"continue": CONTINUE,
+	"match":    MATCH,
 }
 
 func GetTokenType(identifier string) TokenType {
@@ -73,6 +102,17 @@ func GetTokenType(identifier string) TokenType {
 var PrefixOperatior = map[string]TokenType{
 	"!": BANG,
 	"-": MINUS,
+	"~": BIT_NOT,
+}
+
+// AssignOperators maps each assignment operator to the infix operator it
+// applies; plain '=' applies none.
+var AssignOperators = map[TokenType]TokenType{
+	ASSIGN:          ILLEGAL,
+	PLUS_ASSIGN:     PLUS,
+	MINUS_ASSIGN:    MINUS,
+	ASTERISK_ASSIGN: ASTERISK,
+	SLASH_ASSIGN:    SLASH,
 }
 
 // func isPrefixOperator(operator string) bool {
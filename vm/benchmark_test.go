@@ -0,0 +1,57 @@
+package vm
+
+import (
+	"gorilla/compiler"
+	"gorilla/eval"
+	"gorilla/object"
+	"testing"
+)
+
+// The benchmarks run the same programs on both engines:
+//
+//	go test ./vm -bench . -benchmem
+
+const fibonacci = `
+	let fib = fn(n) {
+		if (n < 2) {
+			return n;
+		}
+		return fib(n - 1) + fib(n - 2);
+	};
+	fib(20);
+`
+
+const closures = `
+	let compose = fn(f, g) { return fn(x) { return g(f(x)); }; };
+	let inc = fn(x) { return x + 1; };
+	let sum = fn(n, acc) { return acc if n == 0 else sum(n - 1, compose(inc, inc)(acc)); };
+	sum(500, 0);
+`
+
+func BenchmarkFibonacciEval(b *testing.B) { benchmarkEval(b, fibonacci) }
+func BenchmarkFibonacciVM(b *testing.B)   { benchmarkVM(b, fibonacci) }
+func BenchmarkClosuresEval(b *testing.B)  { benchmarkEval(b, closures) }
+func BenchmarkClosuresVM(b *testing.B)    { benchmarkVM(b, closures) }
+
+func benchmarkEval(b *testing.B, input string) {
+	prog := parse(b, input)
+	for b.Loop() {
+		if result := eval.EvalProgram(prog, object.NewEnvironment()); result.GetType() == object.ERROR {
+			b.Fatal(result.Inspect())
+		}
+	}
+}
+
+// benchmarkVM includes compilation, which is small next to the run.
+func benchmarkVM(b *testing.B, input string) {
+	prog := parse(b, input)
+	for b.Loop() {
+		comp := compiler.New()
+		if err := comp.Compile(prog); err != nil {
+			b.Fatal(err)
+		}
+		if result := New(comp.Bytecode()).Run(); result.GetType() == object.ERROR {
+			b.Fatal(result.Inspect())
+		}
+	}
+}
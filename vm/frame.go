@@ -0,0 +1,37 @@
+package vm
+
+import "gorilla/object"
+
+// Frame is the activation of a closure. locals holds the parameters and
+// local variables in a window on the stack, unless the function's locals
+// are captured by an inner closure: they are then in cells, which a loop
+// can replace for each iteration.
+type Frame struct {
+	closure     *object.Closure
+	ip          int
+	basePointer int
+	locals      []object.Object
+	cells       []*object.Object
+}
+
+// local returns the storage of the local slot index.
+func (frame *Frame) local(index uint8) *object.Object {
+	if frame.cells != nil {
+		return frame.cells[index]
+	}
+	return &frame.locals[index]
+}
+
+func (vm *VM) currentFrame() *Frame {
+	return &vm.frames[vm.framesIndex-1]
+}
+
+func (vm *VM) pushFrame(frame Frame) {
+	vm.frames[vm.framesIndex] = frame
+	vm.framesIndex++
+}
+
+func (vm *VM) popFrame() Frame {
+	vm.framesIndex--
+	return vm.frames[vm.framesIndex]
+}
@@ -0,0 +1,471 @@
+package vm
+
+import (
+	"fmt"
+	"gorilla/code"
+	"gorilla/compiler"
+	"gorilla/eval"
+	"gorilla/object"
+	"gorilla/token"
+)
+
+const (
+	MaxFrames = 1024
+	// StackSize leaves room for the locals and temporaries of MaxFrames
+	// ordinary calls, so deep recursion hits the frame limit first.
+	StackSize = 16 * MaxFrames
+)
+
+var infixOperators = map[code.Opcode]token.TokenType{
+	code.OpAdd:          token.PLUS,
+	code.OpSub:          token.MINUS,
+	code.OpMul:          token.ASTERISK,
+	code.OpDiv:          token.SLASH,
+	code.OpMod:          token.PERCENT,
+	code.OpPow:          token.POWER,
+	code.OpBitAnd:       token.BIT_AND,
+	code.OpBitOr:        token.BIT_OR,
+	code.OpBitXor:       token.BIT_XOR,
+	code.OpShiftLeft:    token.SHIFT_LEFT,
+	code.OpShiftRight:   token.SHIFT_RIGHT,
+	code.OpEqual:        token.EQ,
+	code.OpNotEqual:     token.NOT_EQ,
+	code.OpLess:         token.LT,
+	code.OpGreater:      token.GT,
+	code.OpLessEqual:    token.LE,
+	code.OpGreaterEqual: token.GE,
+}
+
+// VM runs Bytecode on an operand stack. Operators, indexing and builtins
+// are shared with the evaluator, so both engines produce the same values
+// and error messages.
+type VM struct {
+	constants   []object.Object
+	globals     []*object.Object // cells, which a loop can replace for each iteration
+	globalNames []string
+
+	stack []object.Object
+	sp    int // the next free slot; the top of the stack is stack[sp-1]
+
+	frames      []Frame
+	framesIndex int
+}
+
+func New(bytecode *compiler.Bytecode) *VM {
+	return NewWithGlobals(bytecode, nil)
+}
+
+// NewWithGlobals creates a VM whose global slots start with the values of
+// globals, which are set by index as defined in the compiler's symbol table.
+func NewWithGlobals(bytecode *compiler.Bytecode, globals []object.Object) *VM {
+	mainFn := &object.CompiledFunction{
+		Instructions: bytecode.Instructions,
+		SourceMap:    bytecode.SourceMap,
+	}
+
+	vm := &VM{
+		constants:   bytecode.Constants,
+		globals:     make([]*object.Object, max(len(bytecode.Globals), len(globals))),
+		globalNames: bytecode.Globals,
+		stack:       make([]object.Object, StackSize),
+		frames:      make([]Frame, MaxFrames),
+	}
+	values := make([]object.Object, len(vm.globals))
+	copy(values, globals)
+	for i := range values {
+		vm.globals[i] = &values[i]
+	}
+	vm.pushFrame(Frame{closure: &object.Closure{Fn: mainFn}})
+	return vm
+}
+
+// Run executes the program and returns its value, which is an
+// *object.Error if it failed, as eval.EvalProgram does.
+func (vm *VM) Run() object.Object {
+	for {
+		frame := vm.currentFrame()
+		ins := frame.closure.Fn.Instructions
+		ip := frame.ip
+		op := code.Opcode(ins[ip])
+		frame.ip++
+
+		var errObj *object.Error
+		switch op {
+		case code.OpConstant:
+			index := code.ReadUint16(ins[ip+1:])
+			frame.ip += 2
+			errObj = vm.push(vm.constants[index])
+
+		case code.OpPop:
+			vm.pop()
+		case code.OpDup:
+			errObj = vm.push(vm.stack[vm.sp-1])
+		case code.OpDupTwo:
+			if errObj = vm.push(vm.stack[vm.sp-2]); errObj == nil {
+				errObj = vm.push(vm.stack[vm.sp-2])
+			}
+		case code.OpRotThree:
+			top := vm.stack[vm.sp-1]
+			copy(vm.stack[vm.sp-2:vm.sp], vm.stack[vm.sp-3:vm.sp-1])
+			vm.stack[vm.sp-3] = top
+
+		case code.OpNone:
+			errObj = vm.push(eval.NONE)
+		case code.OpTrue:
+			errObj = vm.push(eval.TRUE)
+		case code.OpFalse:
+			errObj = vm.push(eval.FALSE)
+
+		case code.OpAdd, code.OpSub, code.OpMul, code.OpDiv, code.OpMod, code.OpPow,
+			code.OpBitAnd, code.OpBitOr, code.OpBitXor, code.OpShiftLeft, code.OpShiftRight,
+			code.OpEqual, code.OpNotEqual, code.OpLess, code.OpGreater,
+			code.OpLessEqual, code.OpGreaterEqual:
+			right := vm.pop()
+			left := vm.pop()
+			errObj = vm.pushResult(eval.Infix(infixOperators[op], left, right))
+
+		case code.OpMinus:
+			errObj = vm.pushResult(eval.Prefix(token.MINUS, vm.pop()))
+		case code.OpBang:
+			errObj = vm.pushResult(eval.Prefix(token.BANG, vm.pop()))
+		case code.OpBitNot:
+			errObj = vm.pushResult(eval.Prefix(token.BIT_NOT, vm.pop()))
+
+		case code.OpJump:
+			frame.ip = int(code.ReadUint16(ins[ip+1:]))
+
+		case code.OpJumpNotTruthy:
+			frame.ip += 2
+			if !eval.IsTruthy(vm.pop()) {
+				frame.ip = int(code.ReadUint16(ins[ip+1:]))
+			}
+
+		case code.OpIter:
+			var elements []object.Object
+			elements, errObj = eval.Iterate(vm.pop())
+			if errObj == nil {
+				errObj = vm.push(&iterator{elements: elements})
+			}
+
+		case code.OpIterNext:
+			frame.ip += 2
+			iter := vm.stack[vm.sp-1].(*iterator)
+			if iter.next == len(iter.elements) {
+				frame.ip = int(code.ReadUint16(ins[ip+1:]))
+				break
+			}
+			errObj = vm.push(iter.elements[iter.next])
+			iter.next++
+
+		case code.OpMatch:
+			index := code.ReadUint16(ins[ip+1:])
+			frame.ip += 2
+			errObj = vm.match(vm.constants[index].(*object.Pattern), vm.pop())
+
+		case code.OpNoMatch:
+			errObj = newError("no match arm for value %s", vm.pop().Inspect())
+
+		case code.OpGetGlobal:
+			index := code.ReadUint16(ins[ip+1:])
+			frame.ip += 2
+			value := *vm.globals[index]
+			if value == nil {
+				errObj = newError("identifier not found: %s", vm.globalNames[index])
+				break
+			}
+			errObj = vm.push(value)
+
+		case code.OpSetGlobal:
+			index := code.ReadUint16(ins[ip+1:])
+			frame.ip += 2
+			*vm.globals[index] = vm.pop()
+
+		case code.OpGetLocal:
+			index := code.ReadUint8(ins[ip+1:])
+			frame.ip++
+			value := *frame.local(index)
+			if value == nil {
+				errObj = newError("identifier not found: %s", frame.closure.Fn.LocalNames[index])
+				break
+			}
+			errObj = vm.push(value)
+
+		case code.OpSetLocal:
+			index := code.ReadUint8(ins[ip+1:])
+			frame.ip++
+			*frame.local(index) = vm.pop()
+
+		case code.OpFreshGlobals:
+			first := code.ReadUint16(ins[ip+1:])
+			last := code.ReadUint16(ins[ip+3:])
+			frame.ip += 4
+			for i := int(first); i <= int(last); i++ {
+				vm.globals[i] = new(object.Object)
+			}
+
+		case code.OpFreshLocals:
+			first := code.ReadUint8(ins[ip+1:])
+			last := code.ReadUint8(ins[ip+2:])
+			frame.ip += 2
+			for i := int(first); i <= int(last); i++ {
+				frame.cells[i] = new(object.Object)
+			}
+
+		case code.OpGetFree:
+			index := code.ReadUint8(ins[ip+1:])
+			frame.ip++
+			value := *frame.closure.Free[index]
+			if value == nil {
+				errObj = newError("identifier not found: %s", frame.closure.Fn.Captures[index].Name)
+				break
+			}
+			errObj = vm.push(value)
+
+		case code.OpAssignGlobal:
+			index := code.ReadUint16(ins[ip+1:])
+			frame.ip += 2
+			errObj = vm.assign(vm.globals[index], vm.globalNames[index])
+
+		case code.OpAssignLocal:
+			index := code.ReadUint8(ins[ip+1:])
+			frame.ip++
+			errObj = vm.assign(frame.local(index), frame.closure.Fn.LocalNames[index])
+
+		case code.OpAssignFree:
+			index := code.ReadUint8(ins[ip+1:])
+			frame.ip++
+			errObj = vm.assign(frame.closure.Free[index], frame.closure.Fn.Captures[index].Name)
+
+		case code.OpArray:
+			length := int(code.ReadUint16(ins[ip+1:]))
+			frame.ip += 2
+			elements := make([]object.Object, length)
+			copy(elements, vm.stack[vm.sp-length:vm.sp])
+			vm.sp -= length
+			errObj = vm.push(&object.Array{Elements: elements})
+
+		case code.OpHashKey:
+			if _, ok := vm.stack[vm.sp-1].(object.Hashable); !ok {
+				errObj = newError("unusable as hash key: %s", vm.stack[vm.sp-1].GetType())
+			}
+
+		case code.OpHash:
+			numPairs := int(code.ReadUint16(ins[ip+1:]))
+			frame.ip += 2
+			errObj = vm.buildHash(numPairs)
+
+		case code.OpIndex:
+			index := vm.pop()
+			left := vm.pop()
+			errObj = vm.pushResult(eval.Index(left, index))
+
+		case code.OpSetIndex:
+			value := vm.pop()
+			index := vm.pop()
+			left := vm.pop()
+			errObj = eval.SetIndex(left, index, value)
+
+		case code.OpSlice:
+			flags := code.ReadUint8(ins[ip+1:])
+			frame.ip++
+			var start, end object.Object
+			if flags&code.SliceEnd != 0 {
+				end = vm.pop()
+			}
+			if flags&code.SliceStart != 0 {
+				start = vm.pop()
+			}
+			left := vm.pop()
+			errObj = vm.pushResult(eval.Slice(left, start, end))
+
+		case code.OpCall:
+			numArgs := int(code.ReadUint8(ins[ip+1:]))
+			frame.ip++
+			errObj = vm.callFunction(numArgs)
+
+		case code.OpReturnValue, code.OpReturn:
+			var value object.Object = eval.NONE
+			if op == code.OpReturnValue {
+				value = vm.pop()
+			}
+
+			returned := vm.popFrame()
+			if vm.framesIndex == 0 {
+				return value
+			}
+			vm.sp = returned.basePointer - 1
+			errObj = vm.push(value)
+
+		case code.OpClosure:
+			index := code.ReadUint16(ins[ip+1:])
+			frame.ip += 2
+			errObj = vm.push(vm.newClosure(frame, vm.constants[index].(*object.CompiledFunction)))
+
+		default:
+			errObj = newError("unknown opcode %d", op)
+		}
+
+		if errObj != nil {
+			// the frame of a failed call is still the caller's
+			if !errObj.Span.Start.IsValid() {
+				errObj.Span = frame.closure.Fn.SourceMap.Lookup(ip)
+			}
+			return errObj
+		}
+	}
+}
+
+// iterator is the state of a for loop, kept on the stack under the values
+// of its body. It is never visible to the program.
+type iterator struct {
+	elements []object.Object
+	next     int
+}
+
+func (iter *iterator) GetType() object.ObjectType {
+	return "ITERATOR"
+}
+
+func (iter *iterator) Inspect() string {
+	return "<iterator>"
+}
+
+// newError creates a runtime error; Run locates it at the failing
+// instruction.
+func newError(format string, args ...any) *object.Error {
+	return &object.Error{Message: fmt.Sprintf(format, args...)}
+}
+
+func (vm *VM) push(obj object.Object) *object.Error {
+	if vm.sp >= StackSize {
+		return newError("stack overflow")
+	}
+	vm.stack[vm.sp] = obj
+	vm.sp++
+	return nil
+}
+
+// pushResult pushes the result of an operator, or returns it if it failed.
+func (vm *VM) pushResult(result object.Object) *object.Error {
+	if errObj, ok := result.(*object.Error); ok {
+		return errObj
+	}
+	return vm.push(result)
+}
+
+func (vm *VM) pop() object.Object {
+	vm.sp--
+	return vm.stack[vm.sp]
+}
+
+// assign pops the top of the stack into slot, which must already be set.
+func (vm *VM) assign(slot *object.Object, name string) *object.Error {
+	value := vm.pop()
+	if *slot == nil {
+		return newError("assignment to undeclared identifier: %s", name)
+	}
+	*slot = value
+	return nil
+}
+
+// match tests value against pattern. On a match it pushes the bound values,
+// in the order the pattern names them, followed by True; otherwise False.
+func (vm *VM) match(pattern *object.Pattern, value object.Object) *object.Error {
+	var bound []object.Object
+	bind := func(name string, value object.Object) {
+		bound = append(bound, value)
+	}
+	if !eval.MatchPattern(pattern.Node, value, bind) {
+		return vm.push(eval.FALSE)
+	}
+
+	for _, value := range bound {
+		if errObj := vm.push(value); errObj != nil {
+			return errObj
+		}
+	}
+	return vm.push(eval.TRUE)
+}
+
+func (vm *VM) buildHash(numPairs int) *object.Error {
+	hashObj := object.NewHash()
+	start := vm.sp - 2*numPairs
+	for i := start; i < vm.sp; i += 2 {
+		// OpHashKey checked every key already
+		hashObj.Set(vm.stack[i].(object.Hashable), vm.stack[i+1])
+	}
+	vm.sp = start
+	return vm.push(hashObj)
+}
+
+// newClosure captures the free variables of fn from the frame creating it.
+// Locals are captured by reference, which is why a function whose locals
+// are captured keeps them off the stack (see callFunction).
+func (vm *VM) newClosure(frame *Frame, fn *object.CompiledFunction) *object.Closure {
+	free := make([]*object.Object, len(fn.Captures))
+	for i, capture := range fn.Captures {
+		if capture.Local {
+			free[i] = frame.cells[capture.Index]
+		} else if capture.Global {
+			free[i] = vm.globals[capture.Index]
+		} else {
+			free[i] = frame.closure.Free[capture.Index]
+		}
+	}
+	return &object.Closure{Fn: fn, Free: free}
+}
+
+// callFunction calls the function below the numArgs arguments on top of
+// the stack.
+func (vm *VM) callFunction(numArgs int) *object.Error {
+	switch callee := vm.stack[vm.sp-1-numArgs].(type) {
+	case *object.Closure:
+		return vm.callClosure(callee, numArgs)
+
+	case *object.Builtin:
+		args := make([]object.Object, numArgs)
+		copy(args, vm.stack[vm.sp-numArgs:vm.sp])
+		vm.sp -= numArgs + 1
+		return vm.pushResult(callee.Fn(args...))
+
+	default:
+		return newError("not a function: %s", callee.GetType())
+	}
+}
+
+func (vm *VM) callClosure(closure *object.Closure, numArgs int) *object.Error {
+	fn := closure.Fn
+	if numArgs != fn.NumParameters {
+		return newError("wrong number of arguments: expected %d, got %d",
+			fn.NumParameters, numArgs,
+		)
+	}
+	if vm.framesIndex >= MaxFrames {
+		return newError("maximum recursion depth exceeded")
+	}
+
+	// the arguments are the first locals
+	basePointer := vm.sp - numArgs
+	var locals []object.Object
+	var cells []*object.Object
+	if fn.LocalsCaptured {
+		locals = make([]object.Object, fn.NumLocals)
+		copy(locals, vm.stack[basePointer:vm.sp])
+		vm.sp = basePointer
+		cells = make([]*object.Object, fn.NumLocals)
+		for i := range locals {
+			cells[i] = &locals[i]
+		}
+	} else {
+		if basePointer+fn.NumLocals >= StackSize {
+			return newError("stack overflow")
+		}
+		locals = vm.stack[basePointer : basePointer+fn.NumLocals]
+		vm.sp = basePointer + fn.NumLocals
+	}
+	// nil marks a local that is not set yet
+	clear(locals[numArgs:])
+
+	vm.pushFrame(Frame{closure: closure, basePointer: basePointer, locals: locals, cells: cells})
+	return nil
+}
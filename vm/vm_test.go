@@ -0,0 +1,293 @@
+package vm
+
+import (
+	"gorilla/ast"
+	"gorilla/compiler"
+	"gorilla/eval"
+	"gorilla/lexer"
+	"gorilla/object"
+	"gorilla/parser"
+	"testing"
+)
+
+// Every program must give the same result in both engines.
+var parityTests = []string{
+	"5;",
+	"",
+	"let x = 1;",
+	"return --5;",
+	"(5 + 10 * 2 + 15 / 3) * 2 + -10;",
+	"0xFF + 0b11 * 1_000 - 0o7;",
+	"(1 + .5) * 2e1 / 4;",
+	"-1.5 < 1 == (1 == 1.0);",
+	"let a = [1]; a[0] = a; [a, a == [a]];",
+	"let h = {[1]: 1 / 0};",
+	"9223372036854775807 + 1;",
+	"99999999999999999999 * 3 / 3 - 99999999999999999998;",
+	"-2 ** 2 + 2 ** 3 ** 2 % 7;",
+	"[-7 % 3, 7 % -3, -7.5 % 2, -99999999999999999999 % 10];",
+	"(6 & 3 | 8 ^ 1) << 2 >> 1 == ~-5;",
+	"1 == 1 && 2 <= 3 || 1 / 0;",
+	"let fail = fn() { return 1 / 0; }; [False && fail(), 1 || fail(), 0 || \"\", 1 && [1]];",
+	"True && 1 / 0;",
+	"[0 <= 1 < 2, 0 <= 2 < 2, 3 > 2 > 1 > 1, 1 == 1 != 2, 2 < 1 < missing];",
+	"let f = fn(n) { return 0 < n <= 10 == True; }; [f(0), f(5), f(11)];",
+	`1 < 2 < "a";`,
+	"1 < 2 == True;",
+	`"a" >= "b" || !"" && [1];`,
+	`"Hello" + " " + "World!";`,
+	"[1, 2 * 2, 3 + 3][1:];",
+	`"h\u{e9}llo"[1:3];`,
+	"[1, 2, 3, 4][-10:10];",
+	`{"one": 10 - 9, 2: True, False: [1]};`,
+	`{"a": 1, 2: [3]} == {2: [3], "a": 1};`,
+	`let key = "foo"; {"foo": 5}[key];`,
+	"return 1 if 1 > 2 else 2;",
+	"if (1 > 2) { 10; }",
+	"if (1 < 2) { 10; } else { 20; }",
+	"if (False) { 1; } else if (True) { 2; } else { 3; }",
+	"{ let a = 1; a + 1; }",
+	"let x = 1; { let x = 2; } x;",
+	"let x = 1; let x = x + 1; x;",
+	"if (True) { if (True) { return 10; } return 1; }",
+	"let f = fn(x) { x; }; f(1);",
+	"let f = fn() { return; }; f();",
+	"let add = fn(a, b) { return a + b; }; add(1, add(2, 3));",
+	"fn(x) { return x; }(5);",
+	"let makeAdder = fn(a) { return fn(b) { return a + b; }; }; makeAdder(1)(2);",
+	"let add = fn(a) { return fn(b) { return fn(c) { return a + b + c; }; }; }; add(1)(2)(3);",
+	"let x = 1; let getX = fn() { return x; }; let callWithX = fn(x) { return getX(); }; callWithX(100);",
+	"let f = fn() { return g(); }; let g = fn() { return 7; }; f();",
+	`let fib = fn(n) { if (n < 2) { return n; } return fib(n - 1) + fib(n - 2); }; fib(15);`,
+	`let outer = fn() {
+		let countdown = fn(n) { return 0 if n == 0 else countdown(n - 1); };
+		return countdown(5);
+	};
+	outer();`,
+	`let outer = fn(a) {
+		let b = a * 2;
+		{ let c = b + 1; return fn() { return a + b + c; }; }
+	};
+	outer(1)();`,
+	"while (False) { 1 / 0; }",
+	"while (True) { break; }",
+	"for (x in [1, 2]) { x; }",
+	"for (x in []) { return 1; } 2;",
+	"for (x in [1, 2, 3]) { if (x == 2) { break; } } 5;",
+	"let f = fn() { while (True) { return 3; } }; f();",
+	"let find = fn(xs, t) { for (x in xs) { if (x == t) { return True; } } return False; }; [find([1, 2], 2), find([1, 2], 5)];",
+	"let firstOdd = fn(xs) { for (x in xs) { if (x % 2 == 0) { continue; } return x; } }; firstOdd([2, 4, 5, 7]);",
+	"let f = fn() { for (x in [1, 2]) { for (y in [10, 20]) { if (x == 1) { break; } return x + y; } } }; f();",
+	"for (x in [1, 2]) { let g = fn() { return x; }; if (x == 2) { return g(); } }",
+	`for (c in "a\u{e9}") { if (c != "a") { return c; } }`,
+	`for (k in {"b": 1, "a": 2}) { return k; }`,
+	"let x = 1; x = 2; x;",
+	"let x = 10; x += 5; x -= 3; x *= 2; x /= 4; x;",
+	"let i = 0; let total = 0; while (i < 5) { i += 1; total += i; } total;",
+	"let total = 0; for (x in [1, 2, 3]) { if (x == 2) { continue; } total += x; } total;",
+	"let x = 1; { let x = 2; x = 3; } x;",
+	"let x = 1; { x = 3; } x;",
+	"let f = fn() { let x = 1; { x = 3; } return x; }; f();",
+	"let makeCounter = fn() { let count = 0; return fn() { count += 1; return count; }; }; let c = makeCounter(); c(); c();",
+	"let outer = fn() { let n = 0; let inc = fn() { let add = fn() { n += 1; }; add(); }; inc(); inc(); return n; }; outer();",
+	"let f = fn() { g = 2; }; let g = 1; f(); g;",
+	"let xs = [1, 2, 3]; xs[0] = 10; xs[-1] *= 2; xs;",
+	`let h = {"a": 1}; h["a"] += 1; h["b"] = 3; h;`,
+	"let grid = [[0, 0], [0, 0]]; grid[1][0] = 5; grid;",
+	"let f = fn(xs) { for (i in [0, 1]) { xs[i] += 1; } return xs; }; f([1, 2]);",
+	"let f = fn(x) { return x; }; f;",
+	"if (False) { 1; } elif (False) { 2; } elif (True) { 3; }",
+	`match (2) { 1 => "one", 2 => "two", _ => "many" };`,
+	"match (-1.5) { -1.5 => True, _ => False };",
+	"match (2.0) { 2 => True, _ => False };",
+	"match ([1, [2, 3]]) { [_, [x, 3]] => x, _ => 0 };",
+	"match ([1, 2, 3]) { [a, b] => 0, _ => -1 };",
+	`match ({"name": "ada", "age": 36}) { {"name": n, "age": 36} => n, _ => "" };`,
+	`let f = fn(v) { return match (v) { n if n > 10 => "big", n if n > 1 => "medium", _ => "small" }; }; [f(20), f(5), f(0)];`,
+	"let f = fn(v) { return match (v) { [a, b] if a < b => b - a, [a, b] => a - b, n => n }; }; [f([1, 3]), f([3, 1]), f(7)];",
+	"let x = 1; match (2) { [x] => x, n => x + n };",
+	"let x = 1; match (2) { x => x }; x;",
+	"let f = fn(v) { return match (v) { 0 => 1, n => n * f(n - 1) }; }; f(5);",
+	"let f = fn(xs) { let total = 0; for (x in xs) { total += match (x) { [a, b] => a * b, n => n }; } return total; }; f([1, [2, 3], 4]);",
+	"let f = fn(a) { return match (a) { [x] => fn() { return x + a[0]; }, _ => fn() { return 0; } }; }; f([4])();",
+	"len([1, 2, 3]) + len(\"ab\");",
+	"let len = fn(x) { return 0; }; len([1]);",
+	`[type(1), type(1.5), type("a"), type([]), type({}), type(len), type(fn() {})];`,
+	`[str(12), str("ab"), str([1, "c"]), int(-2.7), int(" 42 "), int(True)];`,
+	"[first([1, 2]), last([1, 2]), rest([1, 2, 3]), first([]), rest([])];",
+	"let xs = [1]; let ys = push(xs, 2); [xs, ys];",
+	"let total = 0; for (i in range(1, 10, 2)) { total += i; } total;",
+	`let h = {"b": 1, "a": [2]}; [keys(h), values(h)];`,
+	"let sum = fn(xs) { return 0 if len(xs) == 0 else first(xs) + sum(rest(xs)); }; sum(range(5));",
+
+	// errors
+	"return 5 + True;",
+	"-True;",
+	"1 / 0;",
+	"foobar;",
+	"[1, 2, 3][3];",
+	`{"foo": 5}["bar"];`,
+	"let h = {[1]: 5};",
+	"1[0:];",
+	"1(2);",
+	"let f = fn(a) { return a; }; f(1, 2);",
+	"len(1);",
+	"range(1, 5, 0);",
+	"push([1]);",
+	`int("x");`,
+	"keys([1]);",
+	"let f = fn() { return g(); }; f();",
+	"for (x in 5) { x; }",
+	"for (x in [1]) { x; } x;",
+	"let f = fn(xs) { for (x in xs) { x + 1; } }; f([1, True]);",
+	"x = 1;",
+	"{ let y = 1; } y = 2;",
+	"let f = fn() { z = 1; }; f();",
+	"x += 1;",
+	"let x = True; x += 1;",
+	"let xs = [1]; xs[1] = 2;",
+	`let h = {}; h["a"] += 1;`,
+	`let s = "ab"; s[0] = "c";`,
+	"let outer = fn() { let f = fn() { return g(); }; let x = f(); let g = fn() { return 1; }; return x; }; outer();",
+	"match (3) { 1 => 1, 2 => 2 };",
+	"match (1) { n if n / 0 => 1, _ => 2 };",
+	"match (1) { [x] => x, _ => x };",
+	"let f = fn(v) { return match (v) { [x] => x }; }; f(5);",
+	// every iteration gets its own variables, and shares the loop variable
+	"let fs = []; let j = 0; while (j < 3) { let k = j; fs = push(fs, fn() { return k; }); j += 1; } [fs[0](), fs[2]()];",
+	`let f = fn() {
+		let fs = [];
+		let total = 0;
+		for (x in [1, 2, 3]) {
+			if (x == 2) { let y = x * 10; fs = push(fs, fn() { return y; }); continue; }
+			let z = x;
+			fs = push(fs, fn() { total += z; return [total, x]; });
+		}
+		return [fs[0](), fs[1](), fs[2]()];
+	};
+	f();`,
+}
+
+func TestEngineParity(t *testing.T) {
+	for _, input := range parityTests {
+		prog := parse(t, input)
+		expected := eval.EvalProgram(prog, object.NewEnvironment())
+
+		result := run(t, prog)
+		if result.GetType() != expected.GetType() || result.Inspect() != expected.Inspect() {
+			t.Errorf("%s\n\texpected %s %s\n\tgot      %s %s",
+				input, expected.GetType(), expected.Inspect(), result.GetType(), result.Inspect(),
+			)
+		}
+	}
+}
+
+func TestClosuresCaptureByReference(t *testing.T) {
+	// even is created before odd is set, and still sees it
+	result := run(t, parse(t, `
+		let outer = fn() {
+			let even = fn(n) { return True if n == 0 else odd(n - 1); };
+			let odd = fn(n) { return False if n == 0 else even(n - 1); };
+			return even(10);
+		};
+		outer();
+	`))
+	if result != eval.TRUE {
+		t.Errorf("Expected True. got %s", result.Inspect())
+	}
+}
+
+func TestRuntimeErrorSpans(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let f = fn(x) {\n\treturn x / 0;\n};\nreturn f(1);", "2:9"},
+		// the key is checked before the value is evaluated
+		{"let h = {[1]: 1 / 0};", "1:10"},
+	}
+
+	for _, tt := range tests {
+		lx := lexer.NewLexer(tt.input)
+		prog, ok := parser.NewParser(lx).ParseProgram()
+		if !ok {
+			t.Fatalf("Could not parse %q", tt.input)
+		}
+
+		errObj, ok := run(t, prog).(*object.Error)
+		if !ok {
+			t.Fatalf("%q - expected Error object", tt.input)
+		}
+
+		position := lx.GetFile().Position(errObj.Span.Start)
+		if position.String() != tt.expected {
+			t.Errorf("%q - expected error at %s. got %s", tt.input, tt.expected, position)
+		}
+	}
+}
+
+func TestRecursionLimit(t *testing.T) {
+	result := run(t, parse(t, "let f = fn(n) { return f(n + 1); }; f(0);"))
+	errObj, ok := result.(*object.Error)
+	if !ok {
+		t.Fatalf("Expected Error object. got %s", result.Inspect())
+	}
+	if errObj.Message != "maximum recursion depth exceeded" {
+		t.Errorf("Wrong error message. got %q", errObj.Message)
+	}
+}
+
+func TestPredefinedGlobals(t *testing.T) {
+	symbolTable := compiler.NewSymbolTable()
+	answer := symbolTable.Define("answer")
+
+	comp := compiler.NewWithState(symbolTable, []object.Object{})
+	if err := comp.Compile(parse(t, "answer * 2;")); err != nil {
+		t.Fatalf("Could not compile: %s", err)
+	}
+
+	globals := make([]object.Object, answer.Index+1)
+	globals[answer.Index] = &object.Int{Value: 21}
+	result := NewWithGlobals(comp.Bytecode(), globals).Run()
+	if result.Inspect() != "42" {
+		t.Errorf("Expected 42. got %s", result.Inspect())
+	}
+}
+
+func TestChainedComparisonOperandsOnce(t *testing.T) {
+	symbolTable := compiler.NewSymbolTable()
+	middle := symbolTable.Define("middle")
+
+	comp := compiler.NewWithState(symbolTable, []object.Object{})
+	if err := comp.Compile(parse(t, "[0 < middle() < 10, 10 < middle() < 0];")); err != nil {
+		t.Fatalf("Could not compile: %s", err)
+	}
+
+	calls := 0
+	globals := make([]object.Object, middle.Index+1)
+	globals[middle.Index] = &object.Builtin{Name: "middle", Fn: func(args ...object.Object) object.Object {
+		calls++
+		return &object.Int{Value: 5}
+	}}
+	result := NewWithGlobals(comp.Bytecode(), globals).Run()
+	if result.Inspect() != "[True, False]" || calls != 2 {
+		t.Errorf("Expected [True, False] after 2 calls. got %s after %d", result.Inspect(), calls)
+	}
+}
+
+func parse(t testing.TB, input string) *ast.Program {
+	p := parser.NewParser(lexer.NewLexer(input))
+	prog, ok := p.ParseProgram()
+	if !ok {
+		t.Fatalf("Could not parse %q: %s", input, p.Err())
+	}
+	return prog
+}
+
+func run(t testing.TB, prog *ast.Program) object.Object {
+	comp := compiler.New()
+	if err := comp.Compile(prog); err != nil {
+		t.Fatalf("Could not compile: %s", err)
+	}
+	return New(comp.Bytecode()).Run()
+}